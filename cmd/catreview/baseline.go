@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/manu/catreview/pkg/baseline"
+)
+
+// baselineCycleLimit caps the cycles enumerated to record a baseline.
+const baselineCycleLimit = 10000
+
+// loadBaseline loads the --baseline file, if any. The second result reports
+// that the baseline is missing and is to be recorded (--update-baseline).
+func loadBaseline() (*baseline.Baseline, bool, error) {
+	if baselineFile == "" {
+		if updateBaseline {
+			return nil, false, fmt.Errorf("--update-baseline requires --baseline")
+		}
+		return nil, false, nil
+	}
+
+	base, err := baseline.Load(baselineFile)
+	switch {
+	case err == nil:
+		return base, false, nil
+	case os.IsNotExist(err) && updateBaseline:
+		return nil, true, nil
+	case os.IsNotExist(err):
+		return nil, false, fmt.Errorf("baseline %s not found (record it with --update-baseline)", baselineFile)
+	default:
+		return nil, false, fmt.Errorf("failed to load baseline: %v", err)
+	}
+}
+
+// recordBaseline saves the current findings as the new baseline.
+func recordBaseline(findings []*baseline.Entry, truncated bool) (*baseline.Baseline, error) {
+	base := baseline.New(findings)
+	if err := base.Save(baselineFile); err != nil {
+		return nil, fmt.Errorf("failed to save baseline: %v", err)
+	}
+	fmt.Printf("\nBaseline recorded: %d findings saved to %s\n", len(base.Findings), baselineFile)
+	if truncated {
+		fmt.Printf("⚠️  Only the first %d cycles were recorded\n", baselineCycleLimit)
+	}
+	return base, nil
+}
+
+// tightenBaseline removes the findings that were fixed from the baseline
+// file and lowers the counts of those that shrank, so they cannot come back
+// or grow unnoticed. New findings are never added.
+func tightenBaseline(base *baseline.Baseline, findings []*baseline.Entry, checked []string) error {
+	fixed := base.Fixed(findings, checked...)
+	lowered := base.Lowered(findings, checked...)
+	if len(fixed) == 0 && len(lowered) == 0 {
+		fmt.Printf("\nBaseline up to date: %d findings\n", len(base.Findings))
+		return nil
+	}
+
+	tightened := base.Without(fixed).With(lowered)
+	if err := tightened.Save(baselineFile); err != nil {
+		return fmt.Errorf("failed to save baseline: %v", err)
+	}
+	fmt.Printf("\nBaseline tightened: %d fixed findings removed, %d lowered, %d left\n", len(fixed), len(lowered), len(tightened.Findings))
+	for i, f := range lowered {
+		if i >= 5 {
+			fmt.Printf("  ... (%d more)\n", len(lowered)-i)
+			break
+		}
+		fmt.Printf("  Lowered %s: %s\n", f.Kind, f.Message)
+	}
+	for i, f := range fixed {
+		if i >= 5 {
+			fmt.Printf("  ... (%d more)\n", len(fixed)-i)
+			break
+		}
+		fmt.Printf("  Fixed %s: %s\n", f.Kind, f.Message)
+	}
+	return nil
+}
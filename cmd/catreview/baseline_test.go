@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/manu/catreview/internal/modeltest"
+	"github.com/manu/catreview/pkg/baseline"
+	"github.com/manu/catreview/pkg/category"
+)
+
+// writeModel writes a model of files importing each other to dir/model.json
+// and returns its path.
+func writeModel(t *testing.T, dir string, imports [][2]string) string {
+	t.Helper()
+	var files []*category.Object
+	for _, id := range []string{"a.go", "b.go", "c.go"} {
+		files = append(files, category.NewObject(id, "file", id, map[string]interface{}{}))
+	}
+	var morphisms []*category.Morphism
+	for _, imp := range imports {
+		morphisms = append(morphisms, category.NewMorphism("import:"+imp[0]+"->"+imp[1], imp[0], imp[1], "import", map[string]interface{}{}))
+	}
+	cat := modeltest.Build(t, "verify", files, morphisms)
+	data, err := json.Marshal(cat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "model.json")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// resetVerifyFlags restores the verify flags to their defaults after the
+// test.
+func resetVerifyFlags(t *testing.T) {
+	t.Cleanup(func() {
+		maxCycles, failOnViolation, rulesFile = -1, false, ""
+		baselineFile, updateBaseline, sarifFile = "", false, ""
+	})
+}
+
+func TestVerifyBaselineFlow(t *testing.T) {
+	resetVerifyFlags(t)
+	dir := t.TempDir()
+	baselineFile = filepath.Join(dir, "baseline.json")
+
+	// Cycles are recorded even without --max-cycles
+	model := writeModel(t, dir, [][2]string{{"a.go", "b.go"}, {"b.go", "a.go"}, {"b.go", "c.go"}, {"c.go", "b.go"}})
+	updateBaseline = true
+	if err := runVerify(nil, []string{model}); err != nil {
+		t.Fatalf("Recording the baseline failed: %v", err)
+	}
+	base, err := baseline.Load(baselineFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := base.Count(baseline.KindCycle); got != 2 {
+		t.Fatalf("Expected 2 recorded cycles, got %d", got)
+	}
+
+	// Known cycles do not fail
+	updateBaseline = false
+	maxCycles, failOnViolation = 0, true
+	if err := runVerify(nil, []string{model}); err != nil {
+		t.Errorf("Expected known cycles to pass, got %v", err)
+	}
+
+	// A fixed cycle is removed from the baseline
+	model = writeModel(t, dir, [][2]string{{"a.go", "b.go"}, {"b.go", "a.go"}, {"b.go", "c.go"}})
+	updateBaseline = true
+	if err := runVerify(nil, []string{model}); err != nil {
+		t.Fatalf("Tightening the baseline failed: %v", err)
+	}
+	if base, err = baseline.Load(baselineFile); err != nil {
+		t.Fatal(err)
+	}
+	if got := base.Count(baseline.KindCycle); got != 1 {
+		t.Errorf("Expected 1 cycle left in the baseline, got %d", got)
+	}
+
+	// A new cycle fails and is not added to the baseline
+	model = writeModel(t, dir, [][2]string{{"a.go", "b.go"}, {"b.go", "a.go"}, {"a.go", "c.go"}, {"c.go", "a.go"}})
+	if err := runVerify(nil, []string{model}); err == nil {
+		t.Error("Expected a new cycle to fail")
+	}
+	if base, err = baseline.Load(baselineFile); err != nil {
+		t.Fatal(err)
+	}
+	if got := base.Count(baseline.KindCycle); got != 1 {
+		t.Errorf("Expected the baseline to keep 1 cycle, got %d", got)
+	}
+
+	// A missing baseline is an error unless it is to be recorded
+	updateBaseline = false
+	baselineFile = filepath.Join(dir, "missing.json")
+	if err := runVerify(nil, []string{model}); err == nil {
+		t.Error("Expected an error for a missing baseline")
+	}
+}
+
+// writePackageModel writes a model in which package a calls the packages
+// deps to dir/model.json and returns its path.
+func writePackageModel(t *testing.T, dir string, deps ...string) string {
+	t.Helper()
+	var files []*category.Object
+	for _, pkg := range []string{"a", "b", "c", "d"} {
+		id := pkg + "/" + pkg + ".go"
+		files = append(files, category.NewObject(id, "file", pkg+".go", map[string]interface{}{"package": pkg}))
+	}
+	var morphisms []*category.Morphism
+	for _, dep := range deps {
+		morphisms = append(morphisms, category.NewMorphism("call:a->"+dep, "a/a.go", dep+"/"+dep+".go", "function_call", map[string]interface{}{}))
+	}
+	cat := modeltest.Build(t, "coupling", files, morphisms)
+	data, err := json.Marshal(cat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "model.json")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestVerifyBaselineCoupling(t *testing.T) {
+	resetVerifyFlags(t)
+	dir := t.TempDir()
+	baselineFile = filepath.Join(dir, "baseline.json")
+	rulesFile = filepath.Join(dir, "rules.yaml")
+	spec := "rules:\n  - name: small\n    max_efferent:\n      packages: [\"a/**\"]\n      limit: 0\n"
+	if err := os.WriteFile(rulesFile, []byte(spec), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	efferent := func() int {
+		t.Helper()
+		base, err := baseline.Load(baselineFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(base.Findings) != 1 {
+			t.Fatalf("Expected 1 finding in the baseline, got %v", base.Findings)
+		}
+		return base.Findings[0].Count
+	}
+
+	// a depends on two packages, over its limit of 0
+	updateBaseline = true
+	if err := runVerify(nil, []string{writePackageModel(t, dir, "b", "c")}); err != nil {
+		t.Fatalf("Recording the baseline failed: %v", err)
+	}
+	if got := efferent(); got != 2 {
+		t.Fatalf("Expected the baselined efferent coupling 2, got %d", got)
+	}
+
+	// Its known coupling passes, a third dependency fails
+	updateBaseline, failOnViolation = false, true
+	if err := runVerify(nil, []string{writePackageModel(t, dir, "b", "c")}); err != nil {
+		t.Errorf("Expected the known coupling to pass, got %v", err)
+	}
+	if err := runVerify(nil, []string{writePackageModel(t, dir, "b", "c", "d")}); err == nil {
+		t.Error("Expected grown coupling to fail")
+	}
+
+	// Dropping a dependency lowers the baseline, so it cannot come back
+	updateBaseline = true
+	if err := runVerify(nil, []string{writePackageModel(t, dir, "b")}); err != nil {
+		t.Fatalf("Tightening the baseline failed: %v", err)
+	}
+	if got := efferent(); got != 1 {
+		t.Errorf("Expected the baseline lowered to 1, got %d", got)
+	}
+	updateBaseline = false
+	if err := runVerify(nil, []string{writePackageModel(t, dir, "b", "c")}); err == nil {
+		t.Error("Expected coupling above the lowered baseline to fail")
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/manu/catreview/pkg/analysis"
+	"github.com/manu/catreview/pkg/category"
+	"github.com/spf13/cobra"
+)
+
+var (
+	deadcodeCmd = &cobra.Command{
+		Use:   "deadcode [model.json]",
+		Short: "Find functions, types and files nothing uses",
+		Long: `List the functions and types unreachable from the roots of a codebase, and
+the files declaring nothing else. The roots are main functions, exported API,
+init functions and tests (extracted with extract --tests); names used only through reflection or assembly can
+be kept alive with an allowlist:
+
+  catreview deadcode model.json
+  catreview deadcode model.json --allow 'handlers.*' --allow-file .deadcode-allow
+  catreview deadcode model.json --roots main,init --format json
+
+Method calls are not resolved, so a type in use keeps all its methods alive.`,
+		Args: cobra.ExactArgs(1),
+		RunE: runDeadcode,
+	}
+
+	deadcodeRoots     string
+	deadcodeAllow     []string
+	deadcodeAllowFile string
+	deadcodeFormat    string
+	deadcodeOutput    string
+)
+
+func init() {
+	deadcodeCmd.Flags().StringVar(&deadcodeRoots, "roots", strings.Join(analysis.DeadCodeRoots, ","), "Root kinds to keep alive")
+	deadcodeCmd.Flags().StringSliceVar(&deadcodeAllow, "allow", nil, "Object IDs or names to keep alive (globs, or regex:<pattern>)")
+	deadcodeCmd.Flags().StringVar(&deadcodeAllowFile, "allow-file", "", "File of allowlist patterns, one per line (# comments)")
+	deadcodeCmd.Flags().StringVar(&deadcodeFormat, "format", "text", "Output format: text, json")
+	deadcodeCmd.Flags().StringVarP(&deadcodeOutput, "output", "o", "", "Write the result as JSON to this file")
+	deadcodeCmd.Flags().StringVar(&edgeTypes, "edges", "", "Morphism types to follow, e.g. function_call,reference (default: all structural)")
+	deadcodeCmd.Flags().StringVar(&edgeExclude, "exclude-edges", "", "Morphism types not to follow")
+
+	rootCmd.AddCommand(deadcodeCmd)
+}
+
+func runDeadcode(cmd *cobra.Command, args []string) error {
+	if deadcodeFormat != "text" && deadcodeFormat != "json" {
+		return fmt.Errorf("invalid --format %q (want text or json)", deadcodeFormat)
+	}
+
+	cat, err := loadCategory(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to load model: %v", err)
+	}
+	edges, err := edgeFilter()
+	if err != nil {
+		return err
+	}
+
+	allow := deadcodeAllow
+	if deadcodeAllowFile != "" {
+		patterns, err := readAllowlist(deadcodeAllowFile)
+		if err != nil {
+			return fmt.Errorf("failed to read allowlist: %v", err)
+		}
+		allow = append(allow, patterns...)
+	}
+
+	deadCode, err := analysis.FindDeadCode(cat, analysis.DeadCodeOptions{
+		Roots: splitList(deadcodeRoots),
+		Allow: allow,
+		Edges: edges,
+	})
+	if err != nil {
+		return err
+	}
+
+	if deadcodeOutput != "" {
+		if err := saveJSON(deadCode, deadcodeOutput); err != nil {
+			return fmt.Errorf("failed to save dead code: %v", err)
+		}
+	}
+
+	if deadcodeFormat == "json" {
+		data, err := category.CanonicalJSONIndent(deadCode)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	printDeadCode(deadCode)
+	if deadcodeOutput != "" {
+		fmt.Printf("\nDead code saved to: %s\n", deadcodeOutput)
+	}
+	return nil
+}
+
+// readAllowlist reads allowlist patterns, one per line, skipping blank
+// lines and # comments.
+func readAllowlist(filename string) ([]string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var patterns []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		if line = strings.TrimSpace(line); line != "" {
+			patterns = append(patterns, line)
+		}
+	}
+	return patterns, scanner.Err()
+}
+
+// splitList splits a comma-separated flag value, dropping empty entries.
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// printDeadCode lists the dead functions and types by file, and the
+// orphaned files.
+func printDeadCode(d *analysis.DeadCode) {
+	fmt.Printf("Roots: %d, reachable objects: %d\n", d.Roots, d.Reachable)
+
+	for _, section := range []struct {
+		title   string
+		objects []*analysis.DeadObject
+	}{
+		{"Unused Functions", d.Functions},
+		{"Unused Types", d.Types},
+	} {
+		fmt.Printf("\n%s: %d\n", section.title, len(section.objects))
+		for _, obj := range section.objects {
+			note := ""
+			if obj.Referenced {
+				note = " (used only by dead code)"
+			}
+			fmt.Printf("  %s  %s%s\n", obj.File, obj.ObjectID, note)
+		}
+	}
+
+	fmt.Printf("\nOrphaned Files: %d\n", len(d.Files))
+	for _, file := range d.Files {
+		fmt.Printf("  %s\n", file)
+	}
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/manu/catreview/pkg/analysis"
+	"github.com/manu/catreview/pkg/category"
+	"github.com/manu/catreview/pkg/viz"
+	"github.com/spf13/cobra"
+)
+
+var (
+	diffCmd = &cobra.Command{
+		Use:   "diff [old.json] [new.json]",
+		Short: "Report architectural changes between two models",
+		Long: `Compare two categorical models, e.g. extracted from the base and head of a
+pull request, and report what the change did to the architecture:
+
+  - added, removed and changed objects and morphisms
+  - new and resolved dependency cycles
+  - new cross-layer edges
+  - coupling deltas per object
+  - complexity deltas
+
+Output formats:
+  text     - Plain text summary (default)
+  markdown - Markdown suitable for a PR comment
+  json     - Full change report`,
+		Args: cobra.ExactArgs(2),
+		RunE: runDiff,
+	}
+
+	diffFormat string
+	diffOutput string
+)
+
+// diffListLimit caps the entries per section in text and Markdown output.
+const diffListLimit = 20
+
+// diffReport is the complete result of the diff command.
+type diffReport struct {
+	Before             string                 `json:"before"`
+	After              string                 `json:"after"`
+	Delta              *category.Delta        `json:"delta"`
+	Changes            *analysis.ChangeReport `json:"changes"`
+	NewCrossLayerEdges []*viz.Edge            `json:"new_cross_layer_edges"`
+}
+
+func init() {
+	diffCmd.Flags().StringVarP(&diffFormat, "format", "f", "text", "Output format: text, markdown, json")
+	diffCmd.Flags().StringVarP(&diffOutput, "output", "o", "", "Output file (default: stdout)")
+
+	rootCmd.AddCommand(diffCmd)
+}
+
+func runDiff(cmd *cobra.Command, args []string) error {
+	before, err := loadCategory(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to load model %s: %v", args[0], err)
+	}
+	after, err := loadCategory(args[1])
+	if err != nil {
+		return fmt.Errorf("failed to load model %s: %v", args[1], err)
+	}
+
+	// List as many cycles as a baseline records, so that cycles beyond the
+	// report limit are not taken for new or resolved ones
+	opts := analysis.ReportOptions{CycleLimit: baselineCycleLimit}
+	beforeReport, err := analysis.GenerateReportWithOptions(before, opts)
+	if err != nil {
+		return fmt.Errorf("analysis of %s failed: %v", args[0], err)
+	}
+	afterReport, err := analysis.GenerateReportWithOptions(after, opts)
+	if err != nil {
+		return fmt.Errorf("analysis of %s failed: %v", args[1], err)
+	}
+
+	report := &diffReport{
+		Before:  args[0],
+		After:   args[1],
+		Delta:   category.Diff(before, after),
+		Changes: analysis.CompareReports(beforeReport, afterReport),
+		NewCrossLayerEdges: viz.NewCrossLayerEdges(
+			viz.NewGraphBuilder(before).Build(),
+			viz.NewGraphBuilder(after).Build(),
+		),
+	}
+
+	var output string
+	switch diffFormat {
+	case "text":
+		output = formatDiffText(report)
+	case "markdown", "md":
+		output = formatDiffMarkdown(report)
+	case "json":
+		data, err := category.CanonicalJSONIndent(report)
+		if err != nil {
+			return fmt.Errorf("failed to encode report: %v", err)
+		}
+		output = string(data) + "\n"
+	default:
+		return fmt.Errorf("unknown format: %s", diffFormat)
+	}
+
+	if diffOutput != "" {
+		if err := os.WriteFile(diffOutput, []byte(output), 0644); err != nil {
+			return fmt.Errorf("failed to write output: %v", err)
+		}
+		fmt.Fprintf(os.Stderr, "Output written to: %s\n", diffOutput)
+	} else {
+		fmt.Print(output)
+	}
+
+	return nil
+}
+
+// formatDiffText renders a diff report as plain text.
+func formatDiffText(r *diffReport) string {
+	var sb strings.Builder
+	d, c := r.Delta, r.Changes
+
+	sb.WriteString("Architectural Change Report\n")
+	sb.WriteString("===========================\n\n")
+	fmt.Fprintf(&sb, "Before: %s (%s)\n", r.Before, c.BeforeHash)
+	fmt.Fprintf(&sb, "After:  %s (%s)\n\n", r.After, c.AfterHash)
+
+	if d.IsEmpty() {
+		sb.WriteString("No changes.\n")
+		return sb.String()
+	}
+
+	sb.WriteString("Model Changes:\n")
+	fmt.Fprintf(&sb, "  Objects:   +%d -%d ~%d\n", len(d.AddedObjects), len(d.RemovedObjects), len(d.ChangedObjects))
+	fmt.Fprintf(&sb, "  Morphisms: +%d -%d ~%d\n", len(d.AddedMorphisms), len(d.RemovedMorphisms), len(d.ChangedMorphisms))
+
+	sb.WriteString("\nComplexity:\n")
+	fmt.Fprintf(&sb, "  Diagram Complexity:    %.2f -> %.2f (%+.2f)\n",
+		c.Complexity.DiagramBefore, c.Complexity.DiagramAfter,
+		c.Complexity.DiagramAfter-c.Complexity.DiagramBefore)
+	fmt.Fprintf(&sb, "  Kolmogorov Complexity: %d -> %d bytes (%+d)\n",
+		c.Complexity.KolmogorovBefore, c.Complexity.KolmogorovAfter,
+		c.Complexity.KolmogorovAfter-c.Complexity.KolmogorovBefore)
+
+	fmt.Fprintf(&sb, "\nNew Cycles: %d\n", len(c.NewCycles))
+	for i, cycle := range c.NewCycles {
+		if i >= diffListLimit {
+			fmt.Fprintf(&sb, "  ... and %d more\n", len(c.NewCycles)-i)
+			break
+		}
+		fmt.Fprintf(&sb, "  %s\n", strings.Join(cycleLoop(cycle), " -> "))
+	}
+	fmt.Fprintf(&sb, "Resolved Cycles: %d\n", len(c.ResolvedCycles))
+	if c.CyclesIncomplete {
+		fmt.Fprintf(&sb, "  (more than %d cycles: new and resolved cycles are approximate)\n", baselineCycleLimit)
+	}
+
+	fmt.Fprintf(&sb, "\nNew Cross-Layer Edges: %d\n", len(r.NewCrossLayerEdges))
+	for i, edge := range r.NewCrossLayerEdges {
+		if i >= diffListLimit {
+			fmt.Fprintf(&sb, "  ... and %d more\n", len(r.NewCrossLayerEdges)-i)
+			break
+		}
+		fmt.Fprintf(&sb, "  %s -> %s\n", edge.Source, edge.Target)
+	}
+
+	fmt.Fprintf(&sb, "\nCoupling Changes: %d\n", len(c.CouplingDeltas))
+	for i, delta := range c.CouplingDeltas {
+		if i >= diffListLimit {
+			fmt.Fprintf(&sb, "  ... and %d more\n", len(c.CouplingDeltas)-i)
+			break
+		}
+		fmt.Fprintf(&sb, "  %s: Ca %d -> %d, Ce %d -> %d, I %.2f -> %.2f\n",
+			delta.ObjectID, delta.AfferentBefore, delta.AfferentAfter,
+			delta.EfferentBefore, delta.EfferentAfter,
+			delta.InstabilityBefore, delta.InstabilityAfter)
+	}
+
+	return sb.String()
+}
+
+// formatDiffMarkdown renders a diff report as Markdown for PR comments.
+func formatDiffMarkdown(r *diffReport) string {
+	var sb strings.Builder
+	d, c := r.Delta, r.Changes
+
+	sb.WriteString("## Architectural Change Report\n\n")
+	fmt.Fprintf(&sb, "`%s` → `%s`\n\n", r.Before, r.After)
+
+	if d.IsEmpty() {
+		sb.WriteString("No architectural changes.\n")
+		return sb.String()
+	}
+
+	sb.WriteString("| | Added | Removed | Changed |\n")
+	sb.WriteString("|---|---:|---:|---:|\n")
+	fmt.Fprintf(&sb, "| Objects | %d | %d | %d |\n", len(d.AddedObjects), len(d.RemovedObjects), len(d.ChangedObjects))
+	fmt.Fprintf(&sb, "| Morphisms | %d | %d | %d |\n\n", len(d.AddedMorphisms), len(d.RemovedMorphisms), len(d.ChangedMorphisms))
+
+	sb.WriteString("### Complexity\n\n")
+	sb.WriteString("| Metric | Before | After | Delta |\n")
+	sb.WriteString("|---|---:|---:|---:|\n")
+	fmt.Fprintf(&sb, "| Diagram complexity | %.2f | %.2f | %+.2f |\n",
+		c.Complexity.DiagramBefore, c.Complexity.DiagramAfter,
+		c.Complexity.DiagramAfter-c.Complexity.DiagramBefore)
+	fmt.Fprintf(&sb, "| Kolmogorov complexity (bytes) | %d | %d | %+d |\n\n",
+		c.Complexity.KolmogorovBefore, c.Complexity.KolmogorovAfter,
+		c.Complexity.KolmogorovAfter-c.Complexity.KolmogorovBefore)
+
+	fmt.Fprintf(&sb, "### New Cycles (%d)\n\n", len(c.NewCycles))
+	if len(c.NewCycles) == 0 {
+		sb.WriteString("_None._\n")
+	}
+	for i, cycle := range c.NewCycles {
+		if i >= diffListLimit {
+			fmt.Fprintf(&sb, "- … and %d more\n", len(c.NewCycles)-i)
+			break
+		}
+		fmt.Fprintf(&sb, "- `%s`\n", strings.Join(cycleLoop(cycle), " → "))
+	}
+	if len(c.ResolvedCycles) > 0 {
+		fmt.Fprintf(&sb, "\n%d cycle(s) resolved.\n", len(c.ResolvedCycles))
+	}
+	if c.CyclesIncomplete {
+		fmt.Fprintf(&sb, "\n> More than %d cycles: new and resolved cycles are approximate.\n", baselineCycleLimit)
+	}
+
+	fmt.Fprintf(&sb, "\n### New Cross-Layer Edges (%d)\n\n", len(r.NewCrossLayerEdges))
+	if len(r.NewCrossLayerEdges) == 0 {
+		sb.WriteString("_None._\n")
+	}
+	for i, edge := range r.NewCrossLayerEdges {
+		if i >= diffListLimit {
+			fmt.Fprintf(&sb, "- … and %d more\n", len(r.NewCrossLayerEdges)-i)
+			break
+		}
+		fmt.Fprintf(&sb, "- `%s` → `%s`\n", edge.Source, edge.Target)
+	}
+
+	fmt.Fprintf(&sb, "\n### Coupling Changes (%d)\n\n", len(c.CouplingDeltas))
+	if len(c.CouplingDeltas) > 0 {
+		sb.WriteString("| Object | Ca | Ce | Instability |\n")
+		sb.WriteString("|---|---|---|---|\n")
+		for i, delta := range c.CouplingDeltas {
+			if i >= diffListLimit {
+				fmt.Fprintf(&sb, "\n… and %d more\n", len(c.CouplingDeltas)-i)
+				break
+			}
+			fmt.Fprintf(&sb, "| `%s` | %d → %d | %d → %d | %.2f → %.2f |\n",
+				delta.ObjectID, delta.AfferentBefore, delta.AfferentAfter,
+				delta.EfferentBefore, delta.EfferentAfter,
+				delta.InstabilityBefore, delta.InstabilityAfter)
+		}
+	}
+
+	return sb.String()
+}
+
+// cycleLoop returns the objects of a cycle with the first repeated at the end.
+func cycleLoop(cycle *analysis.Cycle) []string {
+	if len(cycle.Objects) == 0 {
+		return nil
+	}
+	return append(append([]string{}, cycle.Objects...), cycle.Objects[0])
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/manu/catreview/pkg/category"
+	"github.com/manu/catreview/pkg/functor"
+	"github.com/spf13/cobra"
+)
+
+var (
+	expandCmd = &cobra.Command{
+		Use:   "expand [abstract.json] [model.json]",
+		Short: "Drill down from an abstraction to the model behind it",
+		Long: `Show the part of a model that an abstracted object or dependency stands for.
+
+The abstraction is recomputed from the model with the same --by or --functor
+used to create abstract.json, which must contain the requested object or
+edge. For an edge the file-level dependencies mapped onto it are listed, for
+an object its members and the dependencies between them.
+
+  catreview expand abstract.json model.json --edge 'pkg:api->pkg:store'
+  catreview expand abstract.json model.json --object pkg:store`,
+		Args: cobra.ExactArgs(2),
+		RunE: runExpand,
+	}
+
+	expandEdge   string
+	expandObject string
+	expandBy     string
+	expandSpec   string
+	expandOutput string
+)
+
+func init() {
+	expandCmd.Flags().StringVar(&expandEdge, "edge", "", "Abstract edge to expand, as <source>-><target>")
+	expandCmd.Flags().StringVar(&expandObject, "object", "", "Abstract object to expand")
+	expandCmd.Flags().StringVar(&expandBy, "by", "package", "Abstraction level abstract.json was created with (see abstract --by)")
+	expandCmd.Flags().StringVar(&expandSpec, "functor", "", "Functor spec abstract.json was created with (overrides --by)")
+	expandCmd.Flags().StringVarP(&expandOutput, "output", "o", "", "Write the expanded sub-model to this JSON file")
+
+	rootCmd.AddCommand(expandCmd)
+}
+
+func runExpand(cmd *cobra.Command, args []string) error {
+	if (expandEdge == "") == (expandObject == "") {
+		return fmt.Errorf("exactly one of --edge and --object is required")
+	}
+
+	abstract, err := loadCategory(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to load abstraction: %v", err)
+	}
+	model, err := loadCategory(args[1])
+	if err != nil {
+		return fmt.Errorf("failed to load model: %v", err)
+	}
+
+	var f functor.Functor
+	if expandSpec != "" {
+		f, err = newRuleFunctor(expandSpec, model)
+	} else {
+		f, err = newAbstractionFunctor(expandBy, model)
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := functor.Apply(f); err != nil {
+		return err
+	}
+
+	// Collect the requested parts of the abstraction
+	var parts []*category.Category
+	if expandObject != "" {
+		if _, exists := abstract.GetObject(expandObject); !exists {
+			return fmt.Errorf("object %s not found in %s", expandObject, args[0])
+		}
+		sub, err := functor.Expand(f, expandObject)
+		if err != nil {
+			return fmt.Errorf("%v (was %s created with a different --by?)", err, args[0])
+		}
+		parts = append(parts, sub)
+	} else {
+		source, target, ok := strings.Cut(expandEdge, "->")
+		if !ok {
+			return fmt.Errorf("invalid edge %q: expected <source>-><target>", expandEdge)
+		}
+		for _, m := range abstract.Morphisms() {
+			if m.Source != source || m.Target != target || m.Type == "identity" {
+				continue
+			}
+			sub, err := functor.ExpandMorphism(f, m.ID)
+			if err != nil {
+				return fmt.Errorf("%v (was %s created with a different --by?)", err, args[0])
+			}
+			parts = append(parts, sub)
+		}
+		if len(parts) == 0 {
+			return fmt.Errorf("no edge %s -> %s in %s", source, target, args[0])
+		}
+	}
+
+	for _, sub := range parts {
+		printExpansion(sub, f)
+	}
+
+	// Several edges between the same objects expand into one sub-model
+	expanded := parts[0]
+	if len(parts) > 1 {
+		if expanded, _, err = category.Merge(category.MergeOptions{Name: "expand"}, parts...); err != nil {
+			return err
+		}
+	}
+
+	if expandOutput != "" {
+		if err := saveCategory(expanded, expandOutput); err != nil {
+			return fmt.Errorf("failed to save expansion: %v", err)
+		}
+		fmt.Printf("\nExpanded model saved to: %s\n", expandOutput)
+	}
+	return nil
+}
+
+// printExpansion lists the objects and morphisms of an expansion.
+func printExpansion(sub *category.Category, f functor.Functor) {
+	stats := sub.Stats()
+	fmt.Printf("\n%s: %d objects, %d morphisms\n", sub.Name, stats["objects"], stats["morphisms"])
+
+	fmt.Printf("  Objects:\n")
+	for _, obj := range sub.Objects() {
+		mapped, _ := f.MapObject(obj)
+		fmt.Printf("    %s (%s) ↦ %s\n", obj.ID, obj.Type, mapped.ID)
+	}
+
+	fmt.Printf("  Morphisms:\n")
+	for _, m := range sub.Morphisms() {
+		if m.Type == "identity" {
+			continue
+		}
+		fmt.Printf("    %s → %s (%s)\n", m.Source, m.Target, m.Type)
+	}
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/manu/catreview/pkg/analysis"
+	"github.com/manu/catreview/pkg/category"
+	"github.com/manu/catreview/pkg/extractor"
+	"github.com/spf13/cobra"
+)
+
+var (
+	impactCmd = &cobra.Command{
+		Use:   "impact [model.json]",
+		Short: "List what a change could break",
+		Long: `Compute the reverse transitive closure of a change: every object depending,
+directly or transitively, on a changed file, nearest first, and the affected
+entry points (main packages and exported API).
+
+The changed files come from a list, a unified diff or a git revision range:
+
+  catreview impact model.json --changed pkg/foo/bar.go,pkg/foo/baz.go
+  git diff main | catreview impact model.json --diff -
+  catreview impact model.json --rev main..HEAD --list packages
+
+With --list, only the affected files or package directories are printed,
+one per line, e.g. to select the tests to run in CI.`,
+		Args: cobra.ExactArgs(1),
+		RunE: runImpact,
+	}
+
+	impactChanged  string
+	impactDiff     string
+	impactRev      string
+	impactRepo     string
+	impactMaxDepth int
+	impactList     string
+	impactOutput   string
+)
+
+func init() {
+	impactCmd.Flags().StringVar(&impactChanged, "changed", "", "Comma-separated changed files")
+	impactCmd.Flags().StringVar(&impactDiff, "diff", "", "Unified diff to read changed files from (- for stdin)")
+	impactCmd.Flags().StringVar(&impactRev, "rev", "", "Git revision range to read changed files from, e.g. main..HEAD")
+	impactCmd.Flags().StringVar(&impactRepo, "repo", ".", "Git repository for --rev and for resolving repository paths")
+	impactCmd.Flags().IntVar(&impactMaxDepth, "max-depth", 0, "Maximum dependency distance to follow (0 = unlimited)")
+	impactCmd.Flags().StringVar(&impactList, "list", "", "Print only the affected files or packages: files, packages")
+	impactCmd.Flags().StringVarP(&impactOutput, "output", "o", "", "Write the impact as JSON to this file")
+	impactCmd.Flags().StringVar(&edgeTypes, "edges", "", "Morphism types to follow, e.g. import,function_call (default: all structural)")
+	impactCmd.Flags().StringVar(&edgeExclude, "exclude-edges", "", "Morphism types not to follow")
+
+	rootCmd.AddCommand(impactCmd)
+}
+
+func runImpact(cmd *cobra.Command, args []string) error {
+	sources := 0
+	for _, s := range []string{impactChanged, impactDiff, impactRev} {
+		if s != "" {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return fmt.Errorf("exactly one of --changed, --diff and --rev is required")
+	}
+	if impactList != "" && impactList != "files" && impactList != "packages" {
+		return fmt.Errorf("invalid --list %q (want files or packages)", impactList)
+	}
+
+	cat, err := loadCategory(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to load model: %v", err)
+	}
+	edges, err := edgeFilter()
+	if err != nil {
+		return err
+	}
+
+	// Collect the changed paths
+	history := extractor.NewHistoryExtractor(impactRepo)
+	var paths []string
+	switch {
+	case impactChanged != "":
+		for _, p := range strings.Split(impactChanged, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				paths = append(paths, p)
+			}
+		}
+	case impactDiff != "":
+		in := os.Stdin
+		if impactDiff != "-" {
+			if in, err = os.Open(impactDiff); err != nil {
+				return fmt.Errorf("failed to read diff: %v", err)
+			}
+			defer in.Close()
+		}
+		if paths, err = extractor.ParseUnifiedDiff(in); err != nil {
+			return err
+		}
+	default:
+		if paths, err = history.ChangedFiles(impactRev); err != nil {
+			return err
+		}
+	}
+
+	files, unknown := resolveChangedFiles(cat, paths, history)
+	impact := analysis.ComputeImpact(cat, files, edges, impactMaxDepth)
+
+	if impactOutput != "" {
+		if err := saveJSON(impact, impactOutput); err != nil {
+			return fmt.Errorf("failed to save impact: %v", err)
+		}
+	}
+
+	switch impactList {
+	case "files":
+		for _, file := range impact.Files {
+			fmt.Println(file)
+		}
+		return nil
+	case "packages":
+		dirs := make(map[string]bool)
+		for _, file := range impact.Files {
+			dirs[path.Dir(file)] = true
+		}
+		list := make([]string, 0, len(dirs))
+		for dir := range dirs {
+			list = append(list, dir)
+		}
+		sort.Strings(list)
+		for _, dir := range list {
+			fmt.Println(dir)
+		}
+		return nil
+	}
+
+	printImpact(impact, unknown)
+	if impactOutput != "" {
+		fmt.Printf("\nImpact saved to: %s\n", impactOutput)
+	}
+	return nil
+}
+
+// resolveChangedFiles maps changed paths to file objects of cat: directly by
+// ID, or as repository-relative paths. Non-Go files and files outside the
+// model are returned as unknown.
+func resolveChangedFiles(cat *category.Category, paths []string, history *extractor.HistoryExtractor) ([]string, []string) {
+	var files, unknown []string
+	var repoFiles map[string]string
+	for _, p := range paths {
+		if obj, ok := cat.GetObject(p); ok && obj.Type == "file" {
+			files = append(files, p)
+			continue
+		}
+		if repoFiles == nil {
+			// Resolve repository paths only when needed; outside a
+			// repository nothing resolves
+			if repoFiles, _ = history.ModelFiles(cat); repoFiles == nil {
+				repoFiles = map[string]string{}
+			}
+		}
+		if id, ok := repoFiles[p]; ok {
+			files = append(files, id)
+		} else {
+			unknown = append(unknown, p)
+		}
+	}
+	return files, unknown
+}
+
+// printImpact prints the changed files, the affected entry points and the
+// affected objects nearest first.
+func printImpact(impact *analysis.Impact, unknown []string) {
+	fmt.Printf("Changed Files: %d\n", len(impact.Changed))
+	for _, file := range impact.Changed {
+		fmt.Printf("  %s\n", file)
+	}
+	if len(unknown) > 0 {
+		fmt.Printf("  Not in model: %s\n", summarizeIDs(unknown, 5))
+	}
+
+	fmt.Printf("\nAffected: %d objects in %d files\n", len(impact.Affected), len(impact.Files))
+
+	fmt.Printf("\nAffected Entry Points: %d\n", len(impact.EntryPoints))
+	for i, a := range impact.EntryPoints {
+		if i >= 20 {
+			fmt.Printf("  ... (%d more)\n", len(impact.EntryPoints)-i)
+			break
+		}
+		fmt.Printf("  [%s] %s (distance %d)\n", a.EntryPoint, a.ObjectID, a.Distance)
+	}
+
+	// Objects at distance 0 are the change itself
+	var dependents []*analysis.AffectedObject
+	for _, a := range impact.Affected {
+		if a.Distance > 0 {
+			dependents = append(dependents, a)
+		}
+	}
+	fmt.Printf("\nAffected Objects (by distance):\n")
+	for i, a := range dependents {
+		if i >= 30 {
+			fmt.Printf("  ... (%d more)\n", len(dependents)-i)
+			break
+		}
+		fmt.Printf("  %d  %s (%s) via %s\n", a.Distance, a.ObjectID, a.Type, a.Via)
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/manu/catreview/internal/modeltest"
+	"github.com/manu/catreview/pkg/category"
+	"github.com/manu/catreview/pkg/extractor"
+)
+
+func TestResolveChangedFiles(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	dir := t.TempDir()
+	if out, err := exec.Command("git", "-C", dir, "init", "-q").CombinedOutput(); err != nil {
+		t.Fatalf("git init failed: %v: %s", err, out)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "pkg"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	bPath := filepath.Join(dir, "pkg", "b.go")
+	if err := os.WriteFile(bPath, []byte("package pkg\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// a.go is changed by its ID, b.go by its path in the repository
+	cat := modeltest.Build(t, "impact", []*category.Object{
+		category.NewObject("a.go", "file", "a.go", map[string]interface{}{}),
+		category.NewObject("model/b.go", "file", "b.go", map[string]interface{}{"path": bPath}),
+		category.NewObject("pkg.Func", "function", "Func", map[string]interface{}{}),
+	}, nil)
+	paths := []string{"a.go", "pkg/b.go", "pkg.Func", "README.md"}
+
+	files, unknown := resolveChangedFiles(cat, paths, extractor.NewHistoryExtractor(dir))
+	if got := strings.Join(files, ","); got != "a.go,model/b.go" {
+		t.Errorf("Expected a.go,model/b.go, got %s", got)
+	}
+	if got := strings.Join(unknown, ","); got != "pkg.Func,README.md" {
+		t.Errorf("Expected pkg.Func and README.md to be unknown, got %s", got)
+	}
+
+	// Outside a repository only object IDs resolve
+	files, unknown = resolveChangedFiles(cat, paths, extractor.NewHistoryExtractor(t.TempDir()))
+	if strings.Join(files, ",") != "a.go" || len(unknown) != 3 {
+		t.Errorf("Expected only a.go to resolve outside a repository, got %v and %v", files, unknown)
+	}
+}
@@ -7,11 +7,18 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/manu/catreview/pkg/analysis"
+	"github.com/manu/catreview/pkg/baseline"
 	"github.com/manu/catreview/pkg/category"
 	"github.com/manu/catreview/pkg/extractor"
 	"github.com/manu/catreview/pkg/functor"
+	"github.com/manu/catreview/pkg/ownership"
+	"github.com/manu/catreview/pkg/rules"
+	"github.com/manu/catreview/pkg/sarif"
 	"github.com/manu/catreview/pkg/viz"
 	"github.com/spf13/cobra"
 )
@@ -45,14 +52,14 @@ It extracts categorical models from codebases and provides insights into:
 
 	verifyCmd = &cobra.Command{
 		Use:   "verify [model.json]",
-		Short: "Verify category axioms",
+		Short: "Verify category axioms, cycle limits and architecture rules",
 		Args:  cobra.ExactArgs(1),
 		RunE:  runVerify,
 	}
 
 	abstractCmd = &cobra.Command{
 		Use:   "abstract [model.json]",
-		Short: "Create package-level abstraction via functor",
+		Short: "Create package- or team-level abstraction via functor",
 		Args:  cobra.ExactArgs(1),
 		RunE:  runAbstract,
 	}
@@ -68,7 +75,10 @@ Outputs the graph in various formats:
   dot     - Graphviz DOT format
   json    - JSON representation
 
-Supports filtering by layer and coupling thresholds for cleaner views.`,
+Supports filtering by layer and coupling thresholds for cleaner views.
+
+With --main-sequence, plots the packages of the model on the
+abstractness-instability plane instead, as ascii or svg.`,
 		Args: cobra.ExactArgs(1),
 		RunE: runViz,
 	}
@@ -78,6 +88,40 @@ Supports filtering by layer and coupling thresholds for cleaner views.`,
 	formatJSON      bool
 	maxCycles       int
 	failOnViolation bool
+	rulesFile       string
+	baselineFile    string
+	updateBaseline  bool
+	sarifFile       string
+
+	// Extraction flags
+	withTests bool
+
+	// History flags
+	withHistory       bool
+	historyMaxCommits int
+	historySince      string
+	minCoChanges      int
+
+	// Ownership flags
+	codeownersFile string
+	ownershipMap   string
+
+	// Edge selection flags (analyze, verify, viz)
+	edgeTypes   string
+	edgeExclude string
+	edgeWeights map[string]string
+
+	// Analyze flags
+	analyzeOpposite   bool
+	analyzeCycleLimit int
+	analyzeRank       string
+
+	// Abstract flags
+	abstractBy       string
+	abstractTemplate string
+	abstractSpec     string
+	abstractVia      string
+	abstractLevels   map[string]string
 
 	// Viz flags
 	vizFormat      string
@@ -86,33 +130,64 @@ Supports filtering by layer and coupling thresholds for cleaner views.`,
 	vizMaxNodes    int
 	vizHeatmap     bool
 	vizLayered     bool
+	vizBy          string
+	vizMainSeq     bool
 )
 
 func init() {
 	// Extract command flags
 	extractCmd.Flags().StringVarP(&outputFile, "output", "o", "model.json", "Output file for categorical model")
 	extractCmd.Flags().BoolVar(&formatJSON, "pretty", false, "Pretty-print JSON output")
+	extractCmd.Flags().BoolVar(&withTests, "tests", false, "Include _test.go files, so that deadcode keeps what tests use alive")
+	extractCmd.Flags().BoolVar(&withHistory, "history", false, "Attach git churn and co-change morphisms")
+	extractCmd.Flags().IntVar(&historyMaxCommits, "history-max-commits", 0, "Maximum commits to read (0 = all)")
+	extractCmd.Flags().StringVar(&historySince, "history-since", "", "Only read commits newer than this date (git --since syntax)")
+	extractCmd.Flags().IntVar(&minCoChanges, "min-co-changes", analysis.DefaultMinCoChanges, "Minimum shared commits for a co-change morphism")
+	extractCmd.Flags().StringVar(&codeownersFile, "codeowners", "", "CODEOWNERS file to attach owners from (\"auto\" searches the extraction root)")
+	extractCmd.Flags().StringVar(&ownershipMap, "ownership-map", "", "JSON map of CODEOWNERS owners to team names")
 
 	// Analyze command flags
 	analyzeCmd.Flags().StringVarP(&outputFile, "output", "o", "report.json", "Output file for analysis report")
 	analyzeCmd.Flags().BoolVar(&formatJSON, "pretty", true, "Pretty-print JSON output")
+	analyzeCmd.Flags().BoolVar(&analyzeOpposite, "opposite", false, "Analyze the opposite category (dependents instead of dependencies)")
+	analyzeCmd.Flags().IntVar(&analyzeCycleLimit, "max-cycles", analysis.DefaultCycleLimit, "Maximum elementary cycles to enumerate (0 = components only)")
+	analyzeCmd.Flags().StringVar(&analyzeRank, "rank", "", "Rank components by centrality: "+strings.Join(analysis.RankMetrics, ", "))
+	analyzeCmd.Flags().StringVar(&sarifFile, "sarif", "", "Also write cycles, dead code and package zone findings as SARIF 2.1.0")
 
 	// Verify command flags
 	verifyCmd.Flags().IntVar(&maxCycles, "max-cycles", -1, "Maximum allowed cycles (-1 = no limit)")
 	verifyCmd.Flags().BoolVar(&failOnViolation, "fail-on-violation", false, "Exit with error on axiom violation")
+	verifyCmd.Flags().StringVar(&rulesFile, "rules", "", "Architecture rules to check (YAML or JSON); error violations fail")
+	verifyCmd.Flags().StringVar(&baselineFile, "baseline", "", "Known cycles and rule violations to tolerate (JSON)")
+	verifyCmd.Flags().BoolVar(&updateBaseline, "update-baseline", false, "Record the baseline if missing, or remove fixed findings from it and lower recorded coupling")
+	verifyCmd.Flags().StringVar(&sarifFile, "sarif", "", "Also write cycles and rule violations as SARIF 2.1.0")
 
 	// Abstract command flags
 	abstractCmd.Flags().StringVarP(&outputFile, "output", "o", "abstract.json", "Output file for abstracted model")
 	abstractCmd.Flags().BoolVar(&formatJSON, "pretty", true, "Pretty-print JSON output")
+	abstractCmd.Flags().StringVar(&abstractBy, "by", "package", "Abstraction level: package, owner, directory[:<depth>], layers:<spec>, metadata:<key>, regex:<pattern>")
+	abstractCmd.Flags().StringVar(&abstractSpec, "functor", "", "YAML or JSON functor spec to abstract with (overrides --by)")
+	abstractCmd.Flags().StringVar(&abstractVia, "via", "", "Functor pipeline, e.g. file->package->team; saves every level")
+	abstractCmd.Flags().StringToStringVar(&abstractLevels, "level", nil, "Name a pipeline level defined by a functor spec, e.g. module=module.yaml")
+	abstractCmd.Flags().StringVar(&abstractTemplate, "key-template", "", "Class ID template for --by regex:<pattern> (default: first capture group)")
 
 	// Viz command flags
-	vizCmd.Flags().StringVarP(&vizFormat, "format", "f", "ascii", "Output format: ascii, mermaid, dot, json")
+	vizCmd.Flags().StringVarP(&vizFormat, "format", "f", "ascii", "Output format: ascii, mermaid, dot, json (svg with --main-sequence)")
 	vizCmd.Flags().IntVarP(&vizLayer, "layer", "l", -1, "Extract specific layer (0-3), -1 for all")
 	vizCmd.Flags().IntVar(&vizMinCoupling, "min-coupling", 0, "Minimum total coupling to include node")
 	vizCmd.Flags().IntVar(&vizMaxNodes, "max-nodes", 0, "Maximum nodes to display (0 = unlimited)")
 	vizCmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output file (default: stdout)")
 	vizCmd.Flags().BoolVar(&vizHeatmap, "heatmap", false, "Generate coupling heatmap instead of graph")
 	vizCmd.Flags().BoolVar(&vizLayered, "layered", false, "Generate detailed layered ASCII view")
+	vizCmd.Flags().StringVar(&vizBy, "by", "", "Abstract the model before drawing it (same levels as abstract --by)")
+	vizCmd.Flags().BoolVar(&vizMainSeq, "main-sequence", false, "Plot packages on the abstractness-instability plane (format ascii or svg)")
+
+	// Edge selection flags
+	for _, cmd := range []*cobra.Command{analyzeCmd, verifyCmd, vizCmd} {
+		cmd.Flags().StringVar(&edgeTypes, "edges", "", "Morphism types to analyze as dependencies, e.g. import,type_dependency (default: all structural)")
+		cmd.Flags().StringVar(&edgeExclude, "exclude-edges", "", "Morphism types to ignore, e.g. defines")
+		cmd.Flags().StringToStringVar(&edgeWeights, "edge-weight", nil, "Weight per morphism type, e.g. function_call=2")
+	}
 
 	rootCmd.AddCommand(extractCmd, analyzeCmd, verifyCmd, abstractCmd, vizCmd)
 }
@@ -131,6 +206,7 @@ func runExtract(cmd *cobra.Command, args []string) error {
 
 	// Create extractor
 	ext := extractor.NewGoExtractor()
+	ext.Tests = withTests
 
 	// Extract from path
 	cat, err := ext.ExtractFromPath(path)
@@ -138,12 +214,32 @@ func runExtract(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("extraction failed: %v", err)
 	}
 
+	// Enrich with version-control history
+	if withHistory {
+		fmt.Printf("Reading git history...\n")
+		hist := extractor.NewHistoryExtractor(path)
+		hist.MaxCommits = historyMaxCommits
+		hist.Since = historySince
+		hist.MinCoChanges = minCoChanges
+		if err := hist.Enrich(cat); err != nil {
+			return fmt.Errorf("history extraction failed: %v", err)
+		}
+	}
+
+	// Attach team ownership
+	if codeownersFile != "" {
+		if err := annotateOwners(cat, path); err != nil {
+			return fmt.Errorf("ownership annotation failed: %v", err)
+		}
+	}
+
 	// Print statistics
 	stats := cat.Stats()
 	fmt.Printf("Extracted:\n")
 	fmt.Printf("  Objects:   %d\n", stats["objects"])
 	fmt.Printf("  Morphisms: %d\n", stats["morphisms"])
 	fmt.Printf("  Identities: %d\n", stats["identities"])
+	fmt.Printf("  Hash:      %s\n", cat.Hash())
 
 	// Save to file
 	if err := saveCategory(cat, outputFile); err != nil {
@@ -157,6 +253,16 @@ func runExtract(cmd *cobra.Command, args []string) error {
 func runAnalyze(cmd *cobra.Command, args []string) error {
 	modelFile := args[0]
 
+	if analyzeRank != "" {
+		known := false
+		for _, metric := range analysis.RankMetrics {
+			known = known || metric == analyzeRank
+		}
+		if !known {
+			return fmt.Errorf("unknown --rank %q (want one of %s)", analyzeRank, strings.Join(analysis.RankMetrics, ", "))
+		}
+	}
+
 	fmt.Printf("Analyzing categorical model: %s\n", modelFile)
 
 	// Load model
@@ -165,8 +271,26 @@ func runAnalyze(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to load model: %v", err)
 	}
 
+	// Reverse all morphisms so the analyzers answer "who depends on me"
+	if analyzeOpposite {
+		if cat, err = cat.Op(); err != nil {
+			return fmt.Errorf("failed to build opposite category: %v", err)
+		}
+		fmt.Printf("Using opposite category: %s\n", cat.Name)
+	}
+
 	// Generate report
-	report, err := analysis.GenerateReport(cat)
+	edges, err := edgeFilter()
+	if err != nil {
+		return err
+	}
+
+	report, err := analysis.GenerateReportWithOptions(cat, analysis.ReportOptions{
+		CycleLimit: analyzeCycleLimit,
+		Edges:      edges,
+		// Shortest-path metrics are quadratic; compute them only to rank by them
+		PathCentrality: slices.Contains(analysis.PathMetrics, analyzeRank),
+	})
 	if err != nil {
 		return fmt.Errorf("analysis failed: %v", err)
 	}
@@ -175,16 +299,32 @@ func runAnalyze(cmd *cobra.Command, args []string) error {
 	fmt.Printf("\nCategorical Analysis Report\n")
 	fmt.Printf("===========================\n\n")
 	fmt.Printf("Category Statistics:\n")
+	fmt.Printf("  Model Hash: %s\n", report.ModelHash)
 	fmt.Printf("  Objects:    %d\n", report.CategoryStats["objects"])
 	fmt.Printf("  Morphisms:  %d\n", report.CategoryStats["morphisms"])
 	fmt.Printf("\nComplexity Metrics:\n")
 	fmt.Printf("  Diagram Complexity:    %.2f\n", report.DiagramComplexity)
 	fmt.Printf("  Kolmogorov Complexity: %d bytes\n", report.KolmogorovComplexity)
 	fmt.Printf("\nDependency Analysis:\n")
-	fmt.Printf("  Cycles Found: %d\n", len(report.Cycles))
+	fmt.Printf("  Cyclic Components: %d\n", len(report.StronglyConnected))
+
+	if len(report.StronglyConnected) > 0 {
+		fmt.Printf("\n  Largest Strongly Connected Components:\n")
+		for i, comp := range report.StronglyConnected {
+			if i >= 5 {
+				break
+			}
+			fmt.Printf("    Component %d (%d objects, %d internal edges): %s\n",
+				comp.ID, comp.Size, len(comp.Edges), summarizeIDs(comp.Objects, 6))
+		}
+	}
 
-	if len(report.Cycles) > 0 {
-		fmt.Printf("\n  Top 5 Cycles:\n")
+	if analyzeCycleLimit > 0 {
+		truncated := ""
+		if report.CyclesTruncated {
+			truncated = " (limit reached)"
+		}
+		fmt.Printf("\n  Elementary Cycles: %d%s\n", len(report.Cycles), truncated)
 		for i, cycle := range report.Cycles {
 			if i >= 5 {
 				break
@@ -193,6 +333,24 @@ func runAnalyze(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	if len(report.HiddenDependencies) > 0 {
+		fmt.Printf("\nLogical Coupling:\n")
+		fmt.Printf("  Hidden Dependencies: %d\n", len(report.HiddenDependencies))
+		for i, h := range report.HiddenDependencies {
+			if i >= 5 {
+				break
+			}
+			fmt.Printf("    %s <-> %s (%d co-changes, confidence %.2f)\n",
+				h.FileA, h.FileB, h.CoChanges, h.Confidence)
+		}
+	}
+
+	if report.TeamCoupling != nil {
+		fmt.Printf("\nCross-Team Coupling (rows depend on columns):\n")
+		printTeamMatrix(report.TeamCoupling)
+		fmt.Printf("  Cross-team dependencies: %d\n", report.TeamCoupling.CrossTeam())
+	}
+
 	fmt.Printf("\nTop 5 Most Unstable Components:\n")
 	for i, m := range report.TopUnstable {
 		if i >= 5 {
@@ -212,12 +370,33 @@ func runAnalyze(cmd *cobra.Command, args []string) error {
 			m.ObjectID, total, m.EfferentCoupling, m.AfferentCoupling)
 	}
 
+	printPackageMetrics(report.Packages)
+	if err := printCentrality(report.Centrality, analyzeRank); err != nil {
+		return err
+	}
+	printCommunities(report.Communities, 5)
+
+	fmt.Printf("\nDead Code (see the deadcode command):\n")
+	fmt.Printf("  Unused Functions: %d\n", len(report.DeadCode.Functions))
+	fmt.Printf("  Unused Types:     %d\n", len(report.DeadCode.Types))
+	fmt.Printf("  Orphaned Files:   %d\n", len(report.DeadCode.Files))
+
 	// Save full report
 	if err := saveJSON(report, outputFile); err != nil {
 		return fmt.Errorf("failed to save report: %v", err)
 	}
 
 	fmt.Printf("\nFull report saved to: %s\n", outputFile)
+
+	if sarifFile != "" {
+		log := sarif.NewBuilder()
+		if analyzeCycleLimit > 0 {
+			log.AddCycles(cat, report.Cycles, edges, func(*analysis.Cycle) string { return sarif.LevelWarning })
+		}
+		log.AddDeadCode(cat, report.DeadCode)
+		log.AddPackageZones(cat, report.Packages)
+		return saveSARIF(log)
+	}
 	return nil
 }
 
@@ -243,31 +422,125 @@ func runVerify(cmd *cobra.Command, args []string) error {
 	}
 	fmt.Printf("✅ Category axioms verified successfully\n")
 
+	edges, err := edgeFilter()
+	if err != nil {
+		return err
+	}
+	base, record, err := loadBaseline()
+	if err != nil {
+		return err
+	}
+
+	// Collect the findings first, so a new baseline can record them
+	var components []*analysis.Component
+	var cycles []*analysis.Cycle
+	var truncated bool
+	var checked []string // Kinds of findings checked completely
+	// Baselines cover cycles even when their number is not limited
+	if maxCycles >= 0 || updateBaseline {
+		cycleAnalyzer := analysis.NewCycleAnalyzer(cat)
+		cycleAnalyzer.Edges = edges
+		components = cycleAnalyzer.StronglyConnectedComponents()
+		// One cycle beyond the limit is enough to fail, not counting
+		// known cycles
+		limit := maxCycles + 1
+		if updateBaseline || sarifFile != "" {
+			limit = baselineCycleLimit
+		} else if base != nil {
+			limit += base.Count(baseline.KindCycle)
+		}
+		cycles, truncated = cycleAnalyzer.EnumerateCycles(limit)
+		if !truncated {
+			checked = append(checked, baseline.KindCycle)
+		}
+	}
+
+	var engine *rules.Engine
+	var violations []*rules.Violation
+	if rulesFile != "" {
+		if engine, err = loadRules(rulesFile); err != nil {
+			return err
+		}
+		violations = engine.Check(cat, edges)
+		checked = append(checked, baseline.KindRule, baseline.KindCoupling)
+	}
+
+	findings := make([]*baseline.Entry, 0, len(cycles)+len(violations))
+	for _, c := range cycles {
+		findings = append(findings, baseline.CycleEntry(c))
+	}
+	for _, v := range violations {
+		findings = append(findings, baseline.ViolationEntry(v))
+	}
+	if record {
+		if base, err = recordBaseline(findings, truncated); err != nil {
+			return err
+		}
+	}
+
 	// Check cycles if limit specified
+	var failure error
 	if maxCycles >= 0 {
 		fmt.Printf("\nChecking for dependency cycles (max allowed: %d)...\n", maxCycles)
-		cycleAnalyzer := analysis.NewCycleAnalyzer(cat)
-		cycles := cycleAnalyzer.FindCycles()
-
-		fmt.Printf("Found %d cycles\n", len(cycles))
+		if err := reportCycles(cycles, truncated, components, base); err != nil && failOnViolation {
+			failure = err
+		}
+	}
 
-		if len(cycles) > maxCycles {
-			fmt.Printf("❌ Cycle limit exceeded: %d > %d\n", len(cycles), maxCycles)
-			if failOnViolation {
-				return fmt.Errorf("too many cycles: %d > %d", len(cycles), maxCycles)
+	if engine != nil {
+		fmt.Printf("\nChecking architecture rules: %s (%d rules)...\n", rulesFile, len(engine.Rules()))
+		if unmatched := engine.Unmatched(cat); len(unmatched) > 0 {
+			fmt.Printf("⚠️  Selectors matching no object (paths are relative to the extraction root):\n")
+			for _, sel := range unmatched {
+				fmt.Printf("    %s\n", sel)
 			}
-		} else {
-			fmt.Printf("✅ Cycle count within limit\n")
+		}
+		if err := reportViolations(violations, base); err != nil && failure == nil {
+			failure = err
 		}
 	}
 
-	return nil
+	if updateBaseline && !record {
+		if err := tightenBaseline(base, findings, checked); err != nil {
+			return err
+		}
+	}
+
+	if sarifFile != "" {
+		log := sarif.NewBuilder()
+		if base != nil {
+			current := make(map[string]*baseline.Entry, len(findings))
+			for _, f := range findings {
+				current[f.Fingerprint] = f
+			}
+			log.Known = func(fingerprint string) bool {
+				return current[fingerprint] != nil && base.Known(current[fingerprint])
+			}
+		}
+		if maxCycles >= 0 {
+			log.AddCycles(cat, cycles, edges, cycleLevel(cycles, base))
+		}
+		if engine != nil {
+			log.AddViolations(engine.Rules(), violations)
+		}
+		if err := saveSARIF(log); err != nil {
+			return err
+		}
+	}
+	return failure
 }
 
 func runAbstract(cmd *cobra.Command, args []string) error {
 	modelFile := args[0]
 
-	fmt.Printf("Creating package-level abstraction from: %s\n", modelFile)
+	switch {
+	case abstractVia != "":
+		fmt.Printf("Creating abstraction pipeline %s from: %s\n", abstractVia, modelFile)
+	case abstractSpec != "":
+		fmt.Printf("Creating abstraction from %s using functor spec: %s\n", modelFile, abstractSpec)
+	default:
+		fmt.Printf("Creating %s-level abstraction from: %s\n", abstractBy, modelFile)
+	}
 
 	// Load file-level model
 	fileCat, err := loadCategory(modelFile)
@@ -275,45 +548,34 @@ func runAbstract(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to load model: %v", err)
 	}
 
-	// Create target category for packages
-	pkgCat := category.NewCategory("package_level")
-
-	// Create abstraction functor
-	f := functor.NewPackageAbstractionFunctor(fileCat, pkgCat)
-
-	// Apply functor to all objects
-	fmt.Printf("Mapping files to packages...\n")
-	for _, obj := range fileCat.Objects() {
-		if _, err := f.MapObject(obj); err != nil {
-			fmt.Printf("Warning: failed to map %s: %v\n", obj.ID, err)
-		}
+	if abstractVia != "" {
+		return runAbstractPipeline(fileCat, abstractVia)
 	}
 
-	// Apply functor to all morphisms
-	fmt.Printf("Mapping dependencies...\n")
-	for _, morph := range fileCat.Morphisms() {
-		if _, err := f.MapMorphism(morph); err != nil {
-			// Silently skip errors (many will fail due to missing objects)
-			continue
-		}
+	// Create abstraction functor and its target category
+	var f functor.Functor
+	if abstractSpec != "" {
+		f, err = newRuleFunctor(abstractSpec, fileCat)
+	} else {
+		f, err = newAbstractionFunctor(abstractBy, fileCat)
 	}
+	if err != nil {
+		return err
+	}
+	absCat := f.TargetCategory()
 
-	// Verify functor laws
-	fmt.Printf("Verifying functor laws...\n")
-	if err := f.VerifyLaws(); err != nil {
-		fmt.Printf("Warning: functor law verification failed: %v\n", err)
-	} else {
-		fmt.Printf("✅ Functor laws verified\n")
+	if err := applyFunctor(f); err != nil {
+		return err
 	}
 
 	// Print statistics
-	stats := pkgCat.Stats()
+	stats := absCat.Stats()
 	fmt.Printf("\nAbstracted Category:\n")
-	fmt.Printf("  Packages:           %d\n", stats["objects"])
-	fmt.Printf("  Package Dependencies: %d\n", stats["morphisms"])
+	fmt.Printf("  Objects:      %d\n", stats["objects"])
+	fmt.Printf("  Dependencies: %d\n", stats["morphisms"])
 
 	// Save abstracted model
-	if err := saveCategory(pkgCat, outputFile); err != nil {
+	if err := saveCategory(absCat, outputFile); err != nil {
 		return fmt.Errorf("failed to save abstracted model: %v", err)
 	}
 
@@ -332,8 +594,33 @@ func runViz(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to load model: %v", err)
 	}
 
+	if vizMainSeq {
+		return runMainSequencePlot(cat)
+	}
+
+	// Abstract the model first if requested
+	if vizBy != "" {
+		f, err := newAbstractionFunctor(vizBy, cat)
+		if err != nil {
+			return err
+		}
+		app, err := functor.Apply(f)
+		if err != nil {
+			return err
+		}
+		if app.LawError != nil {
+			fmt.Fprintf(os.Stderr, "Warning: functor law verification failed: %v\n", app.LawError)
+		}
+		cat = app.Target
+		stats := cat.Stats()
+		fmt.Fprintf(os.Stderr, "Abstracted by %s: %d objects, %d morphisms\n", vizBy, stats["objects"], stats["morphisms"])
+	}
+
 	// Build visualization graph
 	builder := viz.NewGraphBuilder(cat)
+	if builder.Edges, err = edgeFilter(); err != nil {
+		return err
+	}
 	graph := builder.Build()
 
 	fmt.Fprintf(os.Stderr, "Built graph: %d nodes, %d edges, DAG: %v\n",
@@ -376,7 +663,31 @@ func runViz(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	// Output to file or stdout
+	return writeVizOutput(output)
+}
+
+// runMainSequencePlot plots the packages of a model on the A-I plane. The
+// plot needs the declared types, so it ignores --by.
+func runMainSequencePlot(cat *category.Category) error {
+	edges, err := edgeFilter()
+	if err != nil {
+		return err
+	}
+	packages := analysis.ComputePackageMetrics(cat, edges)
+	fmt.Fprintf(os.Stderr, "Computed metrics for %d packages\n", len(packages))
+
+	switch vizFormat {
+	case "ascii":
+		return writeVizOutput(viz.GenerateMainSequenceASCII(packages))
+	case "svg":
+		return writeVizOutput(viz.GenerateMainSequenceSVG(packages))
+	default:
+		return fmt.Errorf("unsupported format for --main-sequence: %s (use ascii or svg)", vizFormat)
+	}
+}
+
+// writeVizOutput writes viz output to the output file or stdout.
+func writeVizOutput(output string) error {
 	if outputFile != "" {
 		if err := os.WriteFile(outputFile, []byte(output), 0644); err != nil {
 			return fmt.Errorf("failed to write output: %v", err)
@@ -385,12 +696,286 @@ func runViz(cmd *cobra.Command, args []string) error {
 	} else {
 		fmt.Print(output)
 	}
-
 	return nil
 }
 
 // Helper functions
 
+// edgeFilter builds the edge filter selected by --edges, --exclude-edges
+// and --edge-weight, printing it to stderr when set.
+func edgeFilter() (*analysis.EdgeFilter, error) {
+	edges, err := analysis.ParseEdgeFilter(edgeTypes, edgeExclude, edgeWeights)
+	if err != nil {
+		return nil, err
+	}
+	if edges != nil {
+		fmt.Fprintf(os.Stderr, "Edges: %s\n", edges)
+	}
+	return edges, nil
+}
+
+// summarizeIDs joins up to n IDs, noting how many were left out.
+func summarizeIDs(ids []string, n int) string {
+	if len(ids) <= n {
+		return strings.Join(ids, ", ")
+	}
+	return fmt.Sprintf("%s, ... (+%d more)", strings.Join(ids[:n], ", "), len(ids)-n)
+}
+
+// printCentrality lists the structural chokepoints of a report and, if
+// metric is set, the components ranked highest by it.
+func printCentrality(c *analysis.Centrality, metric string) error {
+	fmt.Printf("\nStructural Chokepoints:\n")
+	fmt.Printf("  Articulation Points: %d\n", len(c.ArticulationPoints))
+	if len(c.ArticulationPoints) > 0 {
+		fmt.Printf("    %s\n", summarizeIDs(c.ArticulationPoints, 5))
+	}
+	fmt.Printf("  Bridges: %d\n", len(c.Bridges))
+	for i, b := range c.Bridges {
+		if i >= 5 {
+			fmt.Printf("    ... (%d more)\n", len(c.Bridges)-i)
+			break
+		}
+		fmt.Printf("    %s -> %s\n", b.Source, b.Target)
+	}
+
+	if metric == "" {
+		return nil
+	}
+	ranked, err := c.Rank(metric, 10)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("\nTop 10 Components by %s:\n", metric)
+	for _, s := range ranked {
+		fmt.Printf("  %s: pagerank=%.4f betweenness=%.4f closeness=%.3f core=%d degree=%d\n",
+			s.ObjectID, s.PageRank, s.Betweenness, s.Closeness, s.Core, s.Degree)
+	}
+	return nil
+}
+
+// printCommunities summarizes the detected modules and up to n files
+// clustered with another package.
+func printCommunities(c *analysis.Communities, n int) {
+	fmt.Printf("\nModule Structure:\n")
+	fmt.Printf("  Communities: %d (modularity %.3f, declared packages %.3f)\n",
+		len(c.Clusters), c.Modularity, c.PackageModularity)
+	fmt.Printf("  Misplaced Files: %d\n", len(c.Misplaced))
+	for i, m := range c.Misplaced {
+		if i >= n {
+			fmt.Printf("    ... (%d more)\n", len(c.Misplaced)-i)
+			break
+		}
+		fmt.Printf("    %s (package %s) clusters with %s\n", m.ObjectID, m.Package, m.Suggested)
+	}
+}
+
+// printPackageMetrics lists the packages farthest from the main sequence and
+// the packages in the zones of pain and uselessness.
+func printPackageMetrics(packages []*analysis.PackageMetrics) {
+	if len(packages) == 0 {
+		return
+	}
+
+	fmt.Printf("\nPackage Metrics (distance from the main sequence):\n")
+	fmt.Printf("  %-24s %5s %5s %5s %5s %5s %5s  %s\n", "Package", "A", "I", "D", "Ca", "Ce", "Ext", "Zone")
+	for i, p := range packages {
+		if i >= 10 {
+			fmt.Printf("  ... (%d more)\n", len(packages)-i)
+			break
+		}
+		fmt.Printf("  %-24s %5.2f %5.2f %5.2f %5d %5d %5d  %s\n",
+			p.Package, p.Abstractness, p.Instability, p.Distance, p.Afferent, p.Efferent, p.External, p.Zone)
+	}
+
+	zones := make(map[string][]string)
+	for _, p := range packages {
+		zones[p.Zone] = append(zones[p.Zone], p.Package)
+	}
+	for _, zone := range []struct{ label, name string }{
+		{"Zone of Pain:       ", analysis.ZonePain},
+		{"Zone of Uselessness:", analysis.ZoneUselessness},
+	} {
+		fmt.Printf("  %s %d", zone.label, len(zones[zone.name]))
+		if len(zones[zone.name]) > 0 {
+			fmt.Printf(" (%s)", summarizeIDs(zones[zone.name], 5))
+		}
+		fmt.Println()
+	}
+}
+
+// newAbstractionFunctor creates the functor for an abstraction level.
+func newAbstractionFunctor(level string, source *category.Category) (functor.Functor, error) {
+	switch level {
+	case "package":
+		return functor.NewPackageAbstractionFunctor(source, category.NewCategory("package_level")), nil
+	case "owner", "team":
+		return functor.NewOwnershipFunctor(source, category.NewCategory("team_level")), nil
+	}
+
+	// Quotients by a configurable equivalence
+	switch {
+	case strings.HasPrefix(level, "metadata:"):
+		return functor.NewQuotient(source, category.ByMetadata(strings.TrimPrefix(level, "metadata:"))), nil
+	case strings.HasPrefix(level, "regex:"):
+		key, err := category.ByRegex(strings.TrimPrefix(level, "regex:"), abstractTemplate)
+		if err != nil {
+			return nil, err
+		}
+		return functor.NewQuotient(source, key), nil
+	case level == "directory" || strings.HasPrefix(level, "directory:"):
+		depth := 0
+		if d := strings.TrimPrefix(level, "directory"); d != "" {
+			n, err := strconv.Atoi(strings.TrimPrefix(d, ":"))
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid directory depth in %s", level)
+			}
+			depth = n
+		}
+		return functor.NewDirectoryFunctor(source, category.NewCategory("directory_level"), depth), nil
+	case strings.HasPrefix(level, "layers:"):
+		spec, err := functor.LoadLayerSpec(strings.TrimPrefix(level, "layers:"))
+		if err != nil {
+			return nil, err
+		}
+		return functor.NewLayerFunctor(spec, source, category.NewCategory("layer_level"))
+	default:
+		return nil, fmt.Errorf("unknown abstraction level: %s", level)
+	}
+}
+
+// newRuleFunctor creates a functor from a declarative spec file.
+func newRuleFunctor(specFile string, source *category.Category) (functor.Functor, error) {
+	spec, err := functor.LoadRuleSpec(specFile)
+	if err != nil {
+		return nil, err
+	}
+	name := spec.Name
+	if name == "" {
+		name = "rule_level"
+	}
+	return functor.NewRuleFunctor(spec, source, category.NewCategory(name))
+}
+
+// applyFunctor maps every object and morphism of the functor's source
+// category in one pass, reports failed mappings, verifies the functor laws
+// and propagates owners to the target objects.
+func applyFunctor(f functor.Functor) error {
+	fmt.Printf("Mapping objects and dependencies...\n")
+	app, err := functor.Apply(f)
+	if err != nil {
+		return err
+	}
+	for i, failure := range app.Failures {
+		if i >= 20 {
+			fmt.Printf("Warning: ... and %d more failed mappings\n", len(app.Failures)-i)
+			break
+		}
+		fmt.Printf("Warning: failed to map %s: %v\n", failure.ID, failure.Err)
+	}
+
+	fmt.Printf("Verifying functor laws...\n")
+	if app.LawError != nil {
+		fmt.Printf("Warning: functor law verification failed: %v\n", app.LawError)
+	} else {
+		fmt.Printf("✅ Functor laws verified\n")
+	}
+
+	printInformationLoss(functor.AnalyzeProperties(f))
+
+	// Later levels, such as team after package, need the owners
+	functor.PropagateOwners(f)
+	return nil
+}
+
+// printInformationLoss reports how much structure a functor discards.
+func printInformationLoss(props *functor.Properties) {
+	yesNo := func(b bool) string {
+		if b {
+			return "yes"
+		}
+		return "no"
+	}
+
+	fmt.Printf("\nInformation Loss:\n")
+	fmt.Printf("  Objects:              %d → %d\n", props.SourceObjects, props.TargetObjects)
+	fmt.Printf("  Morphisms:            %d → %d\n", props.SourceMorphisms, props.TargetMorphisms)
+	if props.Unmapped > 0 {
+		fmt.Printf("  Unmapped:             %d objects and morphisms\n", props.Unmapped)
+	}
+	fmt.Printf("  Injective on objects: %s", yesNo(props.InjectiveOnObjects))
+	if id, size := props.LargestFibre(); size > 1 {
+		fmt.Printf(" (largest fibre: %s with %d objects)", id, size)
+	}
+	fmt.Printf("\n")
+	fmt.Printf("  Faithful:             %s\n", yesNo(props.Faithful))
+	fmt.Printf("  Full:                 %s\n", yesNo(props.Full))
+	fmt.Printf("  Collapsed morphisms:  %d onto %d target morphisms (%d into identities)\n",
+		props.CollapsedMorphisms(), len(props.Collapses), props.ToIdentity)
+
+	for i, c := range props.Collapses {
+		if i >= 5 {
+			fmt.Printf("    ... and %d more\n", len(props.Collapses)-i)
+			break
+		}
+		fmt.Printf("    %s ← %d morphisms\n", c.Target, len(c.Sources))
+	}
+}
+
+// annotateOwners attaches CODEOWNERS ownership to the objects of cat.
+func annotateOwners(cat *category.Category, root string) error {
+	var co *ownership.Codeowners
+	var err error
+	if codeownersFile == "auto" {
+		co, err = ownership.Find(root)
+		if err == nil && co == nil {
+			return fmt.Errorf("no CODEOWNERS file found under %s", root)
+		}
+	} else {
+		co, err = ownership.LoadFile(codeownersFile)
+	}
+	if err != nil {
+		return err
+	}
+
+	teams := ownership.TeamMap{}
+	if ownershipMap != "" {
+		if teams, err = ownership.LoadTeamMap(ownershipMap); err != nil {
+			return err
+		}
+	}
+
+	annotated, err := ownership.Annotate(cat, co, teams)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Attached owners to %d objects (%d CODEOWNERS rules)\n", annotated, len(co.Rules))
+	return nil
+}
+
+// printTeamMatrix prints a team coupling matrix as a text table.
+func printTeamMatrix(m *analysis.TeamCouplingMatrix) {
+	width := 8
+	for _, team := range m.Teams {
+		if len(team) > width {
+			width = len(team)
+		}
+	}
+
+	fmt.Printf("  %-*s", width, "")
+	for _, team := range m.Teams {
+		fmt.Printf(" %*s", width, team)
+	}
+	fmt.Println()
+	for i, team := range m.Teams {
+		fmt.Printf("  %-*s", width, team)
+		for j := range m.Teams {
+			fmt.Printf(" %*d", width, m.Counts[i][j])
+		}
+		fmt.Println()
+	}
+}
+
 func saveCategory(cat *category.Category, filename string) error {
 	return saveJSON(cat, filename)
 }
@@ -414,9 +999,9 @@ func saveJSON(v interface{}, filename string) error {
 	var err error
 
 	if formatJSON {
-		data, err = json.MarshalIndent(v, "", "  ")
+		data, err = category.CanonicalJSONIndent(v)
 	} else {
-		data, err = json.Marshal(v)
+		data, err = category.CanonicalJSON(v)
 	}
 
 	if err != nil {
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/manu/catreview/pkg/analysis"
+	"github.com/manu/catreview/pkg/category"
+	"github.com/spf13/cobra"
+)
+
+var (
+	mergeCmd = &cobra.Command{
+		Use:   "merge [model.json...]",
+		Short: "Merge several models into one architecture model",
+		Long: `Merge models extracted per service or per repository into one category.
+
+Objects and morphisms are matched by ID. Colliding entries with different
+content are resolved by a conflict policy:
+  prefer-left      - keep the entry from the earliest model (default)
+  union            - combine entries and their metadata
+  namespace-prefix - keep both, prefixing the later one with its model's
+                     namespace ("<namespace>::<id>")
+
+After merging, imported_package objects are linked to the files of the
+package they resolve to with "resolves_to" morphisms, connecting the
+imports of one model to the concrete packages of another.`,
+		Args: cobra.MinimumNArgs(2),
+		RunE: runMerge,
+	}
+
+	// Merge flags
+	mergeOutput      string
+	mergeName        string
+	mergeObjects     string
+	mergeMorphisms   string
+	mergeMetadata    string
+	mergeNamespaces  []string
+	mergeLinkImports bool
+	mergeReportFile  string
+)
+
+func init() {
+	mergeCmd.Flags().StringVarP(&mergeOutput, "output", "o", "merged.json", "Output file for merged model")
+	mergeCmd.Flags().StringVar(&mergeName, "name", "merged", "Name of the merged category")
+	mergeCmd.Flags().StringVar(&mergeObjects, "objects", "prefer-left", "Object ID conflict policy: prefer-left, union, namespace-prefix")
+	mergeCmd.Flags().StringVar(&mergeMorphisms, "morphisms", "prefer-left", "Morphism ID conflict policy: prefer-left, union, namespace-prefix")
+	mergeCmd.Flags().StringVar(&mergeMetadata, "metadata", "prefer-left", "Metadata key conflict policy: prefer-left, union, namespace-prefix")
+	mergeCmd.Flags().StringSliceVar(&mergeNamespaces, "namespaces", nil, "Namespace per model, in argument order (default: model names)")
+	mergeCmd.Flags().BoolVar(&mergeLinkImports, "link-imports", true, "Link imported packages to the package files of other models")
+	mergeCmd.Flags().StringVar(&mergeReportFile, "report", "", "Write the merge report (conflicts) to this JSON file")
+
+	rootCmd.AddCommand(mergeCmd)
+}
+
+func runMerge(cmd *cobra.Command, args []string) error {
+	models := make([]*category.Category, 0, len(args))
+	for _, file := range args {
+		cat, err := loadCategory(file)
+		if err != nil {
+			return fmt.Errorf("failed to load model %s: %v", file, err)
+		}
+		fmt.Printf("Loaded %s: %d objects, %d morphisms\n",
+			file, cat.Stats()["objects"], cat.Stats()["morphisms"])
+		models = append(models, cat)
+	}
+
+	opts := category.MergeOptions{
+		Name:       mergeName,
+		Objects:    category.ConflictPolicy(mergeObjects),
+		Morphisms:  category.ConflictPolicy(mergeMorphisms),
+		Metadata:   category.ConflictPolicy(mergeMetadata),
+		Namespaces: mergeNamespaces,
+	}
+	merged, report, err := category.Merge(opts, models...)
+	if err != nil {
+		return fmt.Errorf("merge failed: %v", err)
+	}
+
+	linked := 0
+	if mergeLinkImports {
+		if linked, err = analysis.LinkImportedPackages(merged); err != nil {
+			return fmt.Errorf("failed to link imports: %v", err)
+		}
+	}
+
+	fmt.Printf("\nMerged Category:\n")
+	fmt.Printf("  Objects:      %d\n", merged.Stats()["objects"])
+	fmt.Printf("  Morphisms:    %d\n", merged.Stats()["morphisms"])
+	fmt.Printf("  Import Links: %d\n", linked)
+	fmt.Printf("  Conflicts:    %d\n", len(report.Conflicts))
+	for i, c := range report.Conflicts {
+		if i >= 10 {
+			fmt.Printf("    ... and %d more\n", len(report.Conflicts)-i)
+			break
+		}
+		target := c.ID
+		if c.Key != "" {
+			target = fmt.Sprintf("%s [%s]", c.ID, c.Key)
+		}
+		fmt.Printf("    %s %s from %s: %s\n", c.Kind, target, c.Model, c.Resolution)
+	}
+
+	if mergeReportFile != "" {
+		if err := saveJSON(report, mergeReportFile); err != nil {
+			return fmt.Errorf("failed to save merge report: %v", err)
+		}
+		fmt.Printf("\nMerge report saved to: %s\n", mergeReportFile)
+	}
+
+	if err := saveCategory(merged, mergeOutput); err != nil {
+		return fmt.Errorf("failed to save merged model: %v", err)
+	}
+
+	fmt.Printf("Merged model saved to: %s\n", mergeOutput)
+	return nil
+}
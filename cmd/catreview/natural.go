@@ -0,0 +1,165 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/manu/catreview/pkg/category"
+	"github.com/manu/catreview/pkg/functor"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	naturalCmd = &cobra.Command{
+		Use:   "natural [model.json]",
+		Short: "Check a natural transformation between two functor specs",
+		Long: `Check whether two mappings of a model commute, for example the mapping from
+code to the declared architecture (--from) and the mapping to the actual
+runtime layering (--to).
+
+Both functor specs map the model into one shared category D. The components
+file maps objects of the --from level to objects of the --to level, e.g.
+
+  context:billing: layer:domain
+  context:shared:  layer:infrastructure
+
+Every dependency f: A → B of the model must then satisfy
+G(f) ∘ η_A = η_B ∘ F(f). Failing squares are listed with the morphisms
+involved. By default D is read as a preorder ("may depend on"), so a square
+fails when a component does not lead to the layer of the file; --strict
+also requires both paths of a square to compose to the same morphism of D.`,
+		Args: cobra.ExactArgs(1),
+		RunE: runNatural,
+	}
+
+	// Natural flags
+	naturalFrom       string
+	naturalTo         string
+	naturalComponents string
+	naturalStrict     bool
+	naturalFail       bool
+	naturalOutput     string
+)
+
+func init() {
+	naturalCmd.Flags().StringVar(&naturalFrom, "from", "", "Functor spec F (required)")
+	naturalCmd.Flags().StringVar(&naturalTo, "to", "", "Functor spec G (required)")
+	naturalCmd.Flags().StringVar(&naturalComponents, "components", "", "YAML or JSON map from F objects to G objects")
+	naturalCmd.Flags().BoolVar(&naturalStrict, "strict", false, "Require both paths of a square to compose to the same morphism of D")
+	naturalCmd.Flags().BoolVar(&naturalFail, "fail-on-violation", false, "Exit with error if a square does not commute")
+	naturalCmd.Flags().StringVarP(&naturalOutput, "output", "o", "", "Write the naturality report to this JSON file")
+	naturalCmd.MarkFlagRequired("from")
+	naturalCmd.MarkFlagRequired("to")
+
+	rootCmd.AddCommand(naturalCmd)
+}
+
+func runNatural(cmd *cobra.Command, args []string) error {
+	modelFile := args[0]
+
+	fmt.Printf("Checking natural transformation %s ⇒ %s on: %s\n", naturalFrom, naturalTo, modelFile)
+
+	source, err := loadCategory(modelFile)
+	if err != nil {
+		return fmt.Errorf("failed to load model: %v", err)
+	}
+
+	// Both functors map into one shared target category
+	target := category.NewCategory("natural_target")
+	f, err := loadSpecFunctor(naturalFrom, source, target)
+	if err != nil {
+		return err
+	}
+	g, err := loadSpecFunctor(naturalTo, source, target)
+	if err != nil {
+		return err
+	}
+	// Build D from both images; the squares are checked against it
+	for _, fn := range []functor.Functor{f, g} {
+		for _, obj := range source.Objects() {
+			if _, err := fn.MapObject(obj); err != nil && !errors.Is(err, functor.ErrUnmapped) {
+				return fmt.Errorf("%s: failed to map %s: %v", fn.Name(), obj.ID, err)
+			}
+		}
+		for _, m := range source.Morphisms() {
+			if _, err := fn.MapMorphism(m); err != nil && !errors.Is(err, functor.ErrUnmapped) {
+				return fmt.Errorf("%s: failed to map %s: %v", fn.Name(), m.ID, err)
+			}
+		}
+	}
+
+	eta, err := functor.NewNaturalTransformation("η", f, g)
+	if err != nil {
+		return err
+	}
+	eta.Thin = !naturalStrict
+
+	if naturalComponents != "" {
+		mapping, err := loadComponentMap(naturalComponents)
+		if err != nil {
+			return err
+		}
+		if err := eta.SetComponentsFromMap(mapping); err != nil {
+			return fmt.Errorf("invalid components: %v", err)
+		}
+	}
+
+	report := eta.VerifyNaturality()
+
+	fmt.Printf("\nNaturality Squares:\n")
+	fmt.Printf("  Checked: %d\n", report.Checked)
+	fmt.Printf("  Skipped: %d (unmapped by F or G)\n", report.Skipped)
+	fmt.Printf("  Failing: %d\n", len(report.Failures))
+	for i, sq := range report.Failures {
+		if i >= 20 {
+			fmt.Printf("  ... and %d more\n", len(report.Failures)-i)
+			break
+		}
+		fmt.Printf("\n  %s: %s → %s\n", sq.Morphism, sq.Source, sq.Target)
+		if sq.FMorphism != "" {
+			fmt.Printf("    F(f) = %s\n    G(f) = %s\n", sq.FMorphism, sq.GMorphism)
+		}
+		fmt.Printf("    %s\n", sq.Reason)
+	}
+
+	if naturalOutput != "" {
+		if err := saveJSON(report, naturalOutput); err != nil {
+			return fmt.Errorf("failed to save report: %v", err)
+		}
+		fmt.Printf("\nNaturality report saved to: %s\n", naturalOutput)
+	}
+
+	if report.Natural() {
+		fmt.Printf("\n✅ Transformation is natural\n")
+		return nil
+	}
+	fmt.Printf("\n❌ Transformation is not natural\n")
+	if naturalFail {
+		return fmt.Errorf("%d naturality squares do not commute", len(report.Failures))
+	}
+	return nil
+}
+
+// loadSpecFunctor creates a rule functor from a spec file into target.
+func loadSpecFunctor(specFile string, source, target *category.Category) (*functor.RuleFunctor, error) {
+	spec, err := functor.LoadRuleSpec(specFile)
+	if err != nil {
+		return nil, err
+	}
+	return functor.NewRuleFunctor(spec, source, target)
+}
+
+// loadComponentMap reads a YAML or JSON map of component endpoints.
+func loadComponentMap(filename string) (map[string]string, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var mapping map[string]string
+	if err := yaml.Unmarshal(data, &mapping); err != nil {
+		return nil, fmt.Errorf("failed to parse components %s: %v", filename, err)
+	}
+	return mapping, nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/manu/catreview/pkg/category"
+	"github.com/manu/catreview/pkg/functor"
+)
+
+// runAbstractPipeline applies a chain of functors such as
+// "file->package->team" to source. The first level names the input model;
+// every later level is a built-in abstraction (see newAbstractionFunctor),
+// a level defined with --level, or a functor spec file. Each level is saved
+// next to the output file, and the final level to the output file itself.
+func runAbstractPipeline(source *category.Category, via string) error {
+	levels := strings.Split(via, "->")
+	for i := range levels {
+		levels[i] = strings.TrimSpace(levels[i])
+	}
+	if len(levels) < 2 {
+		return fmt.Errorf("pipeline %q needs at least two levels", via)
+	}
+
+	stats := source.Stats()
+	fmt.Printf("\nLevel %s: %d objects, %d morphisms\n", levels[0], stats["objects"], stats["morphisms"])
+
+	current := source
+	var composite functor.Functor
+	for _, level := range levels[1:] {
+		fmt.Printf("\nLevel %s:\n", level)
+
+		f, err := newLevelFunctor(level, current)
+		if err != nil {
+			return fmt.Errorf("level %s: %v", level, err)
+		}
+		if err := applyFunctor(f); err != nil {
+			return fmt.Errorf("level %s: %v", level, err)
+		}
+
+		if composite == nil {
+			composite = f
+		} else if composite, err = functor.Compose(composite, f); err != nil {
+			return err
+		}
+		current = f.TargetCategory()
+
+		levelFile := levelOutputFile(outputFile, level)
+		if err := saveCategory(current, levelFile); err != nil {
+			return fmt.Errorf("failed to save level %s: %v", level, err)
+		}
+		stats := current.Stats()
+		fmt.Printf("  %d objects, %d morphisms saved to: %s\n", stats["objects"], stats["morphisms"], levelFile)
+	}
+
+	fmt.Printf("\nVerifying composite functor %s...\n", composite.Name())
+	if err := composite.VerifyLaws(); err != nil {
+		fmt.Printf("Warning: composite functor law verification failed: %v\n", err)
+	} else {
+		fmt.Printf("✅ Composite functor laws verified\n")
+	}
+	printInformationLoss(functor.AnalyzeProperties(composite))
+
+	if err := saveCategory(current, outputFile); err != nil {
+		return fmt.Errorf("failed to save abstracted model: %v", err)
+	}
+	fmt.Printf("\nAbstracted model saved to: %s\n", outputFile)
+	return nil
+}
+
+// newLevelFunctor creates the functor for one pipeline level.
+func newLevelFunctor(level string, source *category.Category) (functor.Functor, error) {
+	if spec, ok := abstractLevels[level]; ok {
+		return newRuleFunctor(spec, source)
+	}
+	if strings.HasPrefix(level, "layers:") {
+		return newAbstractionFunctor(level, source)
+	}
+	switch filepath.Ext(level) {
+	case ".yaml", ".yml", ".json":
+		return newRuleFunctor(level, source)
+	}
+	return newAbstractionFunctor(level, source)
+}
+
+// unsafeFileChars matches characters not used in level file names.
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// levelOutputFile returns the file a pipeline level is saved to, e.g.
+// "abstract.package.json" for output "abstract.json" and level "package".
+func levelOutputFile(output, level string) string {
+	ext := filepath.Ext(output)
+	stem := strings.TrimSuffix(output, ext)
+
+	name := level
+	switch filepath.Ext(level) {
+	case ".yaml", ".yml", ".json":
+		name = strings.TrimSuffix(filepath.Base(level), filepath.Ext(level))
+	}
+	name = strings.Trim(unsafeFileChars.ReplaceAllString(name, "_"), "_")
+
+	return fmt.Sprintf("%s.%s%s", stem, name, ext)
+}
@@ -0,0 +1,16 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/manu/catreview/pkg/sarif"
+)
+
+// saveSARIF writes the findings collected by b to the --sarif file.
+func saveSARIF(b *sarif.Builder) error {
+	if err := b.Save(sarifFile); err != nil {
+		return fmt.Errorf("failed to save SARIF log: %v", err)
+	}
+	fmt.Printf("\nSARIF log saved to: %s (%d results)\n", sarifFile, b.Len())
+	return nil
+}
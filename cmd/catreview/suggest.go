@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/manu/catreview/pkg/analysis"
+	"github.com/manu/catreview/pkg/functor"
+	"github.com/spf13/cobra"
+)
+
+var (
+	suggestModulesCmd = &cobra.Command{
+		Use:   "suggest-modules [model.json]",
+		Short: "Propose module boundaries from how the code clusters",
+		Long: `Cluster the files of a model by their dependencies with the Louvain method
+and compare the clusters with the declared packages.
+
+The clusters are written as a functor spec mapping every file, and its types
+and functions, to its module, so the proposal can be inspected like any
+other abstraction:
+
+  catreview suggest-modules model.json -o modules.yaml
+  catreview abstract model.json --functor modules.yaml -o modules.json`,
+		Args: cobra.ExactArgs(1),
+		RunE: runSuggestModules,
+	}
+
+	suggestOutput     string
+	suggestResolution float64
+)
+
+func init() {
+	suggestModulesCmd.Flags().StringVarP(&suggestOutput, "output", "o", "modules.yaml", "Output file for the functor spec (.yaml or .json)")
+	suggestModulesCmd.Flags().Float64Var(&suggestResolution, "resolution", 1, "Modularity resolution; higher values give smaller modules")
+	suggestModulesCmd.Flags().StringVar(&edgeTypes, "edges", "", "Morphism types to cluster by, e.g. import,function_call (default: all structural)")
+	suggestModulesCmd.Flags().StringVar(&edgeExclude, "exclude-edges", "", "Morphism types to ignore, e.g. defines")
+	suggestModulesCmd.Flags().StringToStringVar(&edgeWeights, "edge-weight", nil, "Weight per morphism type, e.g. function_call=2")
+
+	rootCmd.AddCommand(suggestModulesCmd)
+}
+
+func runSuggestModules(cmd *cobra.Command, args []string) error {
+	if suggestResolution <= 0 {
+		return fmt.Errorf("--resolution must be positive")
+	}
+
+	cat, err := loadCategory(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to load model: %v", err)
+	}
+	edges, err := edgeFilter()
+	if err != nil {
+		return err
+	}
+
+	communities := analysis.DetectCommunities(cat, edges, suggestResolution)
+
+	fmt.Printf("Detected %d modules (modularity %.3f, declared packages %.3f)\n\n",
+		len(communities.Clusters), communities.Modularity, communities.PackageModularity)
+	for _, c := range communities.Clusters {
+		fmt.Printf("  %s: %d files\n", c.Name, c.Size)
+	}
+
+	fmt.Printf("\nMisplaced Files: %d\n", len(communities.Misplaced))
+	for i, m := range communities.Misplaced {
+		if i >= 20 {
+			fmt.Printf("  ... (%d more)\n", len(communities.Misplaced)-i)
+			break
+		}
+		fmt.Printf("  %s: package %s, clusters with %s (%s)\n", m.ObjectID, m.Package, m.Suggested, m.Community)
+	}
+
+	if err := functor.SaveRuleSpec(moduleSpec(communities), suggestOutput); err != nil {
+		return fmt.Errorf("failed to save functor spec: %v", err)
+	}
+	fmt.Printf("\nFunctor spec saved to: %s\n", suggestOutput)
+	return nil
+}
+
+// moduleSpec turns communities into a functor spec. Each module gets one
+// rule for its files and one for the objects declared in them; imported
+// packages map to the external object, as in package abstraction.
+func moduleSpec(communities *analysis.Communities) *functor.RuleSpec {
+	spec := &functor.RuleSpec{Name: "FileToModule"}
+	for _, c := range communities.Clusters {
+		quoted := make([]string, len(c.Objects))
+		for i, id := range c.Objects {
+			quoted[i] = regexp.QuoteMeta(id)
+		}
+		pattern := "regex:^(" + strings.Join(quoted, "|") + ")$"
+		target := functor.ObjectTemplate{
+			ID:       c.Name,
+			Type:     "module",
+			Metadata: map[string]interface{}{"package": c.Package},
+		}
+
+		spec.Objects = append(spec.Objects,
+			functor.ObjectRule{Match: functor.ObjectMatch{ID: pattern}, Target: target},
+			functor.ObjectRule{Match: functor.ObjectMatch{Metadata: map[string]string{"file": pattern}}, Target: target},
+		)
+	}
+	spec.Objects = append(spec.Objects, functor.ObjectRule{
+		Match:  functor.ObjectMatch{Type: "imported_package"},
+		Target: functor.ObjectTemplate{ID: functor.ExternalObjectID, Type: "external"},
+	})
+	return spec
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/manu/catreview/pkg/analysis"
+	"github.com/manu/catreview/pkg/baseline"
+	"github.com/manu/catreview/pkg/rules"
+	"github.com/manu/catreview/pkg/sarif"
+)
+
+// loadRules loads and compiles the architecture rules of a spec file.
+func loadRules(specFile string) (*rules.Engine, error) {
+	spec, err := rules.LoadSpec(specFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load rules: %v", err)
+	}
+	engine, err := rules.NewEngine(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rules %s: %v", specFile, err)
+	}
+	return engine, nil
+}
+
+// reportCycles prints the outcome of the cycle check. Cycles in base, if
+// any, do not count towards the limit. It returns an error if the limit is
+// exceeded.
+func reportCycles(cycles []*analysis.Cycle, truncated bool, components []*analysis.Component, base *baseline.Baseline) error {
+	count := fmt.Sprintf("%d", len(cycles))
+	if truncated {
+		count = fmt.Sprintf("at least %d", len(cycles))
+	}
+	if base == nil {
+		fmt.Printf("Found %s cycles in %d strongly connected components\n", count, len(components))
+		if len(cycles) > maxCycles {
+			fmt.Printf("❌ Cycle limit exceeded: %s > %d\n", count, maxCycles)
+			return fmt.Errorf("too many cycles: %s > %d", count, maxCycles)
+		}
+		fmt.Printf("✅ Cycle count within limit\n")
+		return nil
+	}
+
+	var added []*analysis.Cycle
+	for _, c := range cycles {
+		if !base.Contains(c.Fingerprint()) {
+			added = append(added, c)
+		}
+	}
+	fmt.Printf("Found %s cycles in %d strongly connected components (%d in baseline)\n",
+		count, len(components), len(cycles)-len(added))
+	if len(added) > maxCycles {
+		for i, c := range added {
+			if i >= 5 {
+				fmt.Printf("    ... (%d more)\n", len(added)-i)
+				break
+			}
+			fmt.Printf("    New cycle: %s\n", baseline.CycleEntry(c).Message)
+		}
+		fmt.Printf("❌ Cycle limit exceeded: %d new > %d\n", len(added), maxCycles)
+		return fmt.Errorf("too many new cycles: %d > %d", len(added), maxCycles)
+	}
+	fmt.Printf("✅ New cycle count within limit\n")
+	return nil
+}
+
+// cycleLevel returns the SARIF level of each cycle: note for cycles in
+// base, error for the others when there are more than --max-cycles of them,
+// and warning otherwise.
+func cycleLevel(cycles []*analysis.Cycle, base *baseline.Baseline) func(*analysis.Cycle) string {
+	known := func(c *analysis.Cycle) bool {
+		return base != nil && base.Contains(c.Fingerprint())
+	}
+	added := 0
+	for _, c := range cycles {
+		if !known(c) {
+			added++
+		}
+	}
+	return func(c *analysis.Cycle) string {
+		switch {
+		case known(c):
+			return sarif.LevelNote
+		case added > maxCycles:
+			return sarif.LevelError
+		default:
+			return sarif.LevelWarning
+		}
+	}
+}
+
+// reportViolations prints the rule violations not in base, if any, with the
+// morphisms causing them. It returns an error if any of them has error
+// severity.
+func reportViolations(violations []*rules.Violation, base *baseline.Baseline) error {
+	var added []*rules.Violation
+	for _, v := range violations {
+		// A coupling violation is new too when its package's coupling grew
+		if base == nil || !base.Known(baseline.ViolationEntry(v)) {
+			added = append(added, v)
+		}
+	}
+	printViolations(added)
+
+	errors := rules.Errors(added)
+	switch {
+	case base == nil && len(added) == 0:
+		fmt.Printf("✅ All rules satisfied\n")
+	case base == nil:
+		fmt.Printf("Found %d rule violations (%d errors, %d warnings)\n",
+			len(added), errors, len(added)-errors)
+	case len(added) == 0:
+		fmt.Printf("✅ No new rule violations (%d in baseline)\n", len(violations))
+	default:
+		fmt.Printf("Found %d new rule violations (%d errors, %d warnings; %d more in baseline)\n",
+			len(added), errors, len(added)-errors, len(violations)-len(added))
+	}
+	if errors > 0 {
+		return fmt.Errorf("%d architecture rule violations", errors)
+	}
+	return nil
+}
+
+// printViolations lists rule violations with up to five morphisms each.
+func printViolations(violations []*rules.Violation) {
+	for _, v := range violations {
+		mark := "❌"
+		if v.Severity == rules.SeverityWarning {
+			mark = "⚠️"
+		}
+		fmt.Printf("%s [%s] %s\n", mark, v.Rule, v.Message)
+		for i, ev := range v.Morphisms {
+			if i >= 5 {
+				fmt.Printf("      ... (%d more)\n", len(v.Morphisms)-i)
+				break
+			}
+			position := "?"
+			if ev.Location != nil {
+				position = ev.Location.String()
+			}
+			fmt.Printf("      %s  %s %s -> %s\n", position, ev.Type, ev.Source, ev.Target)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/manu/catreview/pkg/sarif"
+)
+
+// sarifResults reads the rule ID and level of each result of a SARIF log,
+// as "rule:level", sorted.
+func sarifResults(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var log sarif.Log
+	if err := json.Unmarshal(data, &log); err != nil {
+		t.Fatal(err)
+	}
+	var results []string
+	for _, r := range log.Runs[0].Results {
+		results = append(results, r.RuleID+":"+r.Level)
+	}
+	sort.Strings(results)
+	return strings.Join(results, ",")
+}
+
+func TestVerifySARIF(t *testing.T) {
+	resetVerifyFlags(t)
+	dir := t.TempDir()
+	rulesFile = filepath.Join(dir, "rules.yaml")
+	spec := "rules:\n" +
+		"  - name: no-c\n" +
+		"    severity: warning\n" +
+		"    forbid:\n" +
+		"      from: [\"b.go\"]\n" +
+		"      to: [\"c.go\"]\n"
+	if err := os.WriteFile(rulesFile, []byte(spec), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sarifFile = filepath.Join(dir, "verify.sarif")
+	cycles := [][2]string{{"a.go", "b.go"}, {"b.go", "a.go"}, {"b.go", "c.go"}, {"c.go", "b.go"}}
+	model := writeModel(t, dir, cycles)
+
+	// The level of a cycle does not depend on --fail-on-violation
+	maxCycles = 0
+	for _, fail := range []bool{false, true} {
+		failOnViolation = fail
+		err := runVerify(nil, []string{model})
+		if fail != (err != nil) {
+			t.Errorf("With --fail-on-violation=%v, got error %v", fail, err)
+		}
+		want := "architecture/no-c:warning,dependency-cycle:error,dependency-cycle:error"
+		if got := sarifResults(t, sarifFile); got != want {
+			t.Errorf("With --fail-on-violation=%v, expected %s, got %s", fail, want, got)
+		}
+	}
+
+	// Within the limit, cycles are warnings
+	maxCycles, failOnViolation = 2, true
+	if err := runVerify(nil, []string{model}); err != nil {
+		t.Errorf("Expected 2 cycles within the limit, got %v", err)
+	}
+	if got, want := sarifResults(t, sarifFile), "architecture/no-c:warning,dependency-cycle:warning,dependency-cycle:warning"; got != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+
+	// Known cycles are notes, and only the new one is an error
+	baselineFile = filepath.Join(dir, "baseline.json")
+	updateBaseline = true
+	if err := runVerify(nil, []string{writeModel(t, dir, cycles[:2])}); err != nil {
+		t.Fatalf("Recording the baseline failed: %v", err)
+	}
+	updateBaseline = false
+	maxCycles = 0
+	if err := runVerify(nil, []string{writeModel(t, dir, cycles)}); err == nil {
+		t.Error("Expected the new cycle to fail")
+	}
+	if got, want := sarifResults(t, sarifFile), "architecture/no-c:warning,dependency-cycle:error,dependency-cycle:note"; got != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+}
@@ -0,0 +1,51 @@
+// Package glob translates the path globs of specs and CODEOWNERS files into
+// regular expressions.
+package glob
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Expr translates a glob into an unanchored regular expression. "**/"
+// matches any number of leading directories, "**" anything, and "*" and "?"
+// any characters and one character within a path segment.
+func Expr(glob string) string {
+	var sb strings.Builder
+	for i := 0; i < len(glob); i++ {
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			sb.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			sb.WriteString(".*")
+			i++
+		case glob[i] == '*':
+			sb.WriteString("[^/]*")
+		case glob[i] == '?':
+			sb.WriteString("[^/]")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(glob[i])))
+		}
+	}
+	return sb.String()
+}
+
+// Compile compiles a pattern as written in functor, layer and rule specs: a
+// glob matching whole paths, or a regular expression when prefixed with
+// "regex:", which it reports. An empty pattern matches everything (nil).
+func Compile(pattern string) (re *regexp.Regexp, isRegex bool, err error) {
+	if pattern == "" {
+		return nil, false, nil
+	}
+	if strings.HasPrefix(pattern, "regex:") {
+		re, err := regexp.Compile(strings.TrimPrefix(pattern, "regex:"))
+		if err != nil {
+			return nil, false, fmt.Errorf("invalid regex %q: %v", pattern, err)
+		}
+		return re, true, nil
+	}
+	re, err = regexp.Compile("^" + Expr(pattern) + "$")
+	return re, false, err
+}
@@ -0,0 +1,43 @@
+package glob
+
+import "testing"
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		glob, path string
+		want       bool
+	}{
+		{"services/*/api.go", "services/billing/api.go", true},
+		{"services/*/api.go", "services/billing/v2/api.go", false},
+		{"services/**/api.go", "services/billing/v2/api.go", true},
+		{"**/*.go", "main.go", true},
+		{"*.go", "cmd/main.go", false},
+		{"cmd/main?.go", "cmd/main2.go", true},
+		{"cmd/main.go", "cmd/mainXgo", false},
+	}
+	for _, tt := range tests {
+		re, isRegex, err := Compile(tt.glob)
+		if err != nil {
+			t.Fatalf("Compile(%q) failed: %v", tt.glob, err)
+		}
+		if isRegex {
+			t.Errorf("Expected %q compiled as a glob", tt.glob)
+		}
+		if got := re.MatchString(tt.path); got != tt.want {
+			t.Errorf("Glob %q on %q: expected %v, got %v", tt.glob, tt.path, tt.want, got)
+		}
+	}
+}
+
+func TestCompileRegex(t *testing.T) {
+	re, isRegex, err := Compile("regex:^services/([^/]+)/")
+	if err != nil || !isRegex || !re.MatchString("services/billing/a.go") {
+		t.Errorf("Expected a regex matching services/billing/a.go, got %v %v (%v)", re, isRegex, err)
+	}
+	if _, _, err := Compile("regex:("); err == nil {
+		t.Error("Expected an invalid regex to fail")
+	}
+	if re, _, err := Compile(""); re != nil || err != nil {
+		t.Errorf("Expected an empty pattern to match everything, got %v (%v)", re, err)
+	}
+}
@@ -0,0 +1,57 @@
+// Package modeltest builds categorical models for tests: by hand from
+// objects and morphisms, or by extracting shop, a small Go module kept in
+// testdata.
+package modeltest
+
+import (
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/manu/catreview/pkg/category"
+)
+
+// Build creates a category of objects and morphisms, failing t if any of
+// them cannot be added.
+func Build(t testing.TB, name string, objects []*category.Object, morphisms []*category.Morphism) *category.Category {
+	t.Helper()
+	cat := category.NewCategory(name)
+	Add(t, cat, objects, morphisms)
+	return cat
+}
+
+// Add adds objects and then morphisms to cat, failing t if any of them
+// cannot be added.
+func Add(t testing.TB, cat *category.Category, objects []*category.Object, morphisms []*category.Morphism) {
+	t.Helper()
+	for _, obj := range objects {
+		if err := cat.AddObject(obj); err != nil {
+			t.Fatalf("fixture %s: %v", cat.Name, err)
+		}
+	}
+	for _, m := range morphisms {
+		if err := cat.AddMorphism(m); err != nil {
+			t.Fatalf("fixture %s: %v", cat.Name, err)
+		}
+	}
+}
+
+// Shop returns the absolute path of the shop module:
+//
+//	cmd/shop        main: places an order with domain and infra
+//	tools/gen       main: validates an order with domain
+//	internal/domain domain.Place, domain.Validate, domain.Order; imports infra and audit;
+//	                tested by TestValidate with the sampleOrder helper
+//	internal/infra  infra.Store and infra.Open; imports github.com/acme/audit
+//	audit           audit.Record
+//
+// Extracting it from its absolute path exercises models whose files are
+// not named relative to the working directory.
+func Shop(t testing.TB) string {
+	t.Helper()
+	_, file, _, ok := runtime.Caller(0)
+	if !ok {
+		t.Fatal("cannot locate the shop module")
+	}
+	return filepath.Join(filepath.Dir(file), "testdata", "shop")
+}
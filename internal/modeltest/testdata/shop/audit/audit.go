@@ -0,0 +1,7 @@
+// Package audit records events.
+package audit
+
+// Record records an event.
+func Record(event string) {
+	_ = event
+}
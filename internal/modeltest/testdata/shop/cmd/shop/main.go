@@ -0,0 +1,12 @@
+// Command shop places an order.
+package main
+
+import (
+	"example.com/shop/internal/domain"
+	"example.com/shop/internal/infra"
+)
+
+func main() {
+	store := infra.Open()
+	domain.Place(store, domain.Order{ID: 1})
+}
@@ -0,0 +1,30 @@
+// Package domain holds the order logic.
+package domain
+
+import (
+	"example.com/shop/audit"
+	"example.com/shop/internal/infra"
+)
+
+// Order is placed by customers.
+type Order struct {
+	ID int
+}
+
+// Place validates and stores an order.
+func Place(store *infra.Store, o Order) bool {
+	if !Validate(o) {
+		return false
+	}
+	audit.Record("place")
+	return store.Save(o.ID)
+}
+
+// Validate reports whether an order can be placed.
+func Validate(o Order) bool {
+	return o.ID > 0
+}
+
+func legacy() bool {
+	return false
+}
@@ -0,0 +1,13 @@
+package domain
+
+import "testing"
+
+func TestValidate(t *testing.T) {
+	if !Validate(sampleOrder()) {
+		t.Error("Expected the sample order to be valid")
+	}
+}
+
+func sampleOrder() Order {
+	return Order{ID: 1}
+}
@@ -0,0 +1,20 @@
+// Package infra stores orders.
+package infra
+
+import _ "github.com/acme/audit"
+
+// Store saves orders.
+type Store struct {
+	saved []int
+}
+
+// Open connects to the store.
+func Open() *Store {
+	return &Store{}
+}
+
+// Save stores an order.
+func (s *Store) Save(id int) bool {
+	s.saved = append(s.saved, id)
+	return true
+}
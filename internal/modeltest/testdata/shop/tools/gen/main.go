@@ -0,0 +1,8 @@
+// Command gen checks generated orders.
+package main
+
+import "example.com/shop/internal/domain"
+
+func main() {
+	domain.Validate(domain.Order{})
+}
@@ -0,0 +1,28 @@
+// Package specfile reads the YAML and JSON spec files of functors, layers
+// and rules.
+package specfile
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Load decodes the YAML or JSON file at path into spec, naming the kind of
+// spec in errors. Unknown fields are rejected, so that a misspelt key fails
+// instead of being ignored.
+func Load(path, kind string, spec interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(spec); err != nil {
+		return fmt.Errorf("failed to parse %s spec %s: %v", kind, path, err)
+	}
+	return nil
+}
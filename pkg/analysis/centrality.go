@@ -0,0 +1,473 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/manu/catreview/pkg/category"
+)
+
+// RankMetrics are the metrics objects can be ranked by (see Centrality.Rank).
+var RankMetrics = []string{"pagerank", "betweenness", "closeness", "core", "degree"}
+
+// PageRank parameters.
+const (
+	pageRankDamping    = 0.85
+	pageRankIterations = 100
+	pageRankTolerance  = 1e-10
+)
+
+// CentralityScores holds the centrality of one object in the dependency
+// graph.
+type CentralityScores struct {
+	ObjectID     string  `json:"object_id"`
+	Degree       int     `json:"degree"`       // Distinct objects it depends on or is depended on by
+	PageRank     float64 `json:"pagerank"`     // Share of importance flowing along dependencies
+	Betweenness  float64 `json:"betweenness"`  // Share of shortest dependency paths through it (normalized)
+	Closeness    float64 `json:"closeness"`    // Harmonic closeness, ignoring direction (normalized)
+	Core         int     `json:"core"`         // k-core number, ignoring direction
+	Articulation bool    `json:"articulation"` // Removing it disconnects the graph
+}
+
+// Bridge is a dependency whose removal disconnects the graph. Dependencies
+// of or on objects without other neighbours disconnect only those objects
+// and are not reported as bridges.
+type Bridge struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+}
+
+// Centrality ranks objects by their structural position in the dependency
+// graph. PageRank and betweenness follow dependencies; closeness, core
+// numbers, articulation points and bridges treat them as undirected.
+type Centrality struct {
+	Objects            map[string]*CentralityScores `json:"objects"`
+	ArticulationPoints []string                     `json:"articulation_points"`
+	Bridges            []*Bridge                    `json:"bridges"`
+	PathMetrics        bool                         `json:"path_metrics"` // Betweenness and closeness were computed
+}
+
+// PathMetrics are the rank metrics computed from all shortest paths, which
+// take O(n·m) time on a graph of n objects and m dependencies.
+var PathMetrics = []string{"betweenness", "closeness"}
+
+// ComputeCentrality computes the centrality of every object of cat in the
+// graph of morphisms selected by edges.
+func ComputeCentrality(cat *category.Category, edges *EdgeFilter) *Centrality {
+	return computeCentrality(cat, edges, true)
+}
+
+// ComputeLinearCentrality computes the centrality metrics that scale to
+// large models: degree, PageRank, core numbers, articulation points and
+// bridges. Betweenness and closeness are left at zero.
+func ComputeLinearCentrality(cat *category.Category, edges *EdgeFilter) *Centrality {
+	return computeCentrality(cat, edges, false)
+}
+
+func computeCentrality(cat *category.Category, edges *EdgeFilter, paths bool) *Centrality {
+	nodes, adjacency := dependencyGraph(cat, edges)
+	undirected := undirectedGraph(nodes, adjacency)
+
+	c := &Centrality{
+		Objects:            make(map[string]*CentralityScores, len(nodes)),
+		ArticulationPoints: []string{},
+		Bridges:            []*Bridge{},
+		PathMetrics:        paths,
+	}
+	for _, id := range nodes {
+		c.Objects[id] = &CentralityScores{ObjectID: id, Degree: len(undirected[id])}
+	}
+
+	for id, score := range pageRank(nodes, adjacency) {
+		c.Objects[id].PageRank = score
+	}
+	if paths {
+		for id, score := range betweenness(nodes, adjacency) {
+			c.Objects[id].Betweenness = score
+		}
+		for id, score := range harmonicCloseness(nodes, undirected) {
+			c.Objects[id].Closeness = score
+		}
+	}
+	for id, core := range coreNumbers(nodes, undirected) {
+		c.Objects[id].Core = core
+	}
+
+	points, bridges := articulation(nodes, undirected)
+	for _, id := range points {
+		c.Objects[id].Articulation = true
+	}
+	c.ArticulationPoints = points
+	for _, b := range bridges {
+		if len(undirected[b[0]]) == 1 || len(undirected[b[1]]) == 1 {
+			continue
+		}
+		// Report bridges in the direction of the dependency
+		if containsType(adjacency[b[0]], b[1]) {
+			c.Bridges = append(c.Bridges, &Bridge{Source: b[0], Target: b[1]})
+		} else {
+			c.Bridges = append(c.Bridges, &Bridge{Source: b[1], Target: b[0]})
+		}
+	}
+	sort.Slice(c.Bridges, func(i, j int) bool {
+		if c.Bridges[i].Source != c.Bridges[j].Source {
+			return c.Bridges[i].Source < c.Bridges[j].Source
+		}
+		return c.Bridges[i].Target < c.Bridges[j].Target
+	})
+
+	return c
+}
+
+// Rank returns the n objects scoring highest on a metric (n <= 0 returns
+// all), ties broken by object ID. Ranking by a path metric fails unless
+// path metrics were computed.
+func (c *Centrality) Rank(metric string, n int) ([]*CentralityScores, error) {
+	if !c.PathMetrics && containsType(PathMetrics, metric) {
+		return nil, fmt.Errorf("%s was not computed", metric)
+	}
+	var score func(*CentralityScores) float64
+	switch metric {
+	case "pagerank":
+		score = func(s *CentralityScores) float64 { return s.PageRank }
+	case "betweenness":
+		score = func(s *CentralityScores) float64 { return s.Betweenness }
+	case "closeness":
+		score = func(s *CentralityScores) float64 { return s.Closeness }
+	case "core":
+		score = func(s *CentralityScores) float64 { return float64(s.Core) }
+	case "degree":
+		score = func(s *CentralityScores) float64 { return float64(s.Degree) }
+	default:
+		return nil, fmt.Errorf("unknown rank metric %q (want one of %s)", metric, strings.Join(RankMetrics, ", "))
+	}
+
+	ranked := make([]*CentralityScores, 0, len(c.Objects))
+	for _, s := range c.Objects {
+		ranked = append(ranked, s)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		si, sj := score(ranked[i]), score(ranked[j])
+		if si != sj {
+			return si > sj
+		}
+		return ranked[i].ObjectID < ranked[j].ObjectID
+	})
+	if n > 0 && len(ranked) > n {
+		ranked = ranked[:n]
+	}
+	return ranked, nil
+}
+
+// undirectedGraph returns the sorted distinct neighbours of every node,
+// ignoring direction and self-loops.
+func undirectedGraph(nodes []string, adjacency map[string][]string) map[string][]string {
+	neighbours := make(map[string]map[string]bool, len(nodes))
+	for _, v := range nodes {
+		neighbours[v] = make(map[string]bool)
+	}
+	for v, targets := range adjacency {
+		for _, w := range targets {
+			if v != w && neighbours[v] != nil && neighbours[w] != nil {
+				neighbours[v][w] = true
+				neighbours[w][v] = true
+			}
+		}
+	}
+
+	undirected := make(map[string][]string, len(nodes))
+	for v, set := range neighbours {
+		list := make([]string, 0, len(set))
+		for w := range set {
+			list = append(list, w)
+		}
+		sort.Strings(list)
+		undirected[v] = list
+	}
+	return undirected
+}
+
+// pageRank computes PageRank by power iteration. Objects without
+// dependencies spread their rank evenly over all objects.
+func pageRank(nodes []string, adjacency map[string][]string) map[string]float64 {
+	n := float64(len(nodes))
+	rank := make(map[string]float64, len(nodes))
+	for _, v := range nodes {
+		rank[v] = 1 / n
+	}
+
+	for iter := 0; iter < pageRankIterations; iter++ {
+		dangling := 0.0
+		for _, v := range nodes {
+			if len(adjacency[v]) == 0 {
+				dangling += rank[v]
+			}
+		}
+
+		next := make(map[string]float64, len(nodes))
+		base := (1-pageRankDamping)/n + pageRankDamping*dangling/n
+		for _, v := range nodes {
+			next[v] += base
+			if targets := adjacency[v]; len(targets) > 0 {
+				share := pageRankDamping * rank[v] / float64(len(targets))
+				for _, w := range targets {
+					next[w] += share
+				}
+			}
+		}
+
+		delta := 0.0
+		for _, v := range nodes {
+			delta += math.Abs(next[v] - rank[v])
+		}
+		rank = next
+		if delta < pageRankTolerance {
+			break
+		}
+	}
+	return rank
+}
+
+// indexGraph numbers the nodes by position and translates adjacency lists
+// to indices, so that the all-pairs searches below work on slices.
+func indexGraph(nodes []string, adjacency map[string][]string) [][]int {
+	position := make(map[string]int, len(nodes))
+	for i, v := range nodes {
+		position[v] = i
+	}
+	graph := make([][]int, len(nodes))
+	for i, v := range nodes {
+		for _, w := range adjacency[v] {
+			if j, ok := position[w]; ok {
+				graph[i] = append(graph[i], j)
+			}
+		}
+	}
+	return graph
+}
+
+// betweenness computes normalized betweenness centrality with Brandes'
+// algorithm on the directed, unweighted graph.
+func betweenness(nodes []string, adjacency map[string][]string) map[string]float64 {
+	graph := indexGraph(nodes, adjacency)
+	n := len(nodes)
+	centrality := make([]float64, n)
+
+	distance := make([]int, n)
+	paths := make([]float64, n)
+	dependency := make([]float64, n)
+	predecessors := make([][]int, n)
+	for i := range distance {
+		distance[i] = -1
+	}
+	for s := 0; s < n; s++ {
+		// Breadth-first search counting shortest paths
+		distance[s], paths[s] = 0, 1
+		order := []int{s}
+		for head := 0; head < len(order); head++ {
+			v := order[head]
+			for _, w := range graph[v] {
+				if distance[w] < 0 {
+					distance[w] = distance[v] + 1
+					order = append(order, w)
+				}
+				if distance[w] == distance[v]+1 {
+					paths[w] += paths[v]
+					predecessors[w] = append(predecessors[w], v)
+				}
+			}
+		}
+
+		// Accumulate dependencies in reverse order of distance
+		for i := len(order) - 1; i > 0; i-- {
+			w := order[i]
+			for _, v := range predecessors[w] {
+				dependency[v] += paths[v] / paths[w] * (1 + dependency[w])
+			}
+			centrality[w] += dependency[w]
+		}
+
+		// Reset what the search touched
+		for _, v := range order {
+			distance[v], paths[v], dependency[v], predecessors[v] = -1, 0, 0, predecessors[v][:0]
+		}
+	}
+
+	scores := make(map[string]float64, n)
+	for i, v := range nodes {
+		if n > 2 {
+			scores[v] = centrality[i] / float64((n-1)*(n-2))
+		}
+	}
+	return scores
+}
+
+// harmonicCloseness computes the normalized harmonic closeness of every
+// node: the mean inverse distance to all other nodes, which stays defined
+// on disconnected graphs.
+func harmonicCloseness(nodes []string, undirected map[string][]string) map[string]float64 {
+	graph := indexGraph(nodes, undirected)
+	n := len(nodes)
+	scores := make(map[string]float64, n)
+	if n < 2 {
+		return scores
+	}
+
+	distance := make([]int, n)
+	for i := range distance {
+		distance[i] = -1
+	}
+	for s := 0; s < n; s++ {
+		distance[s] = 0
+		sum := 0.0
+		queue := []int{s}
+		for head := 0; head < len(queue); head++ {
+			v := queue[head]
+			for _, w := range graph[v] {
+				if distance[w] < 0 {
+					distance[w] = distance[v] + 1
+					sum += 1 / float64(distance[w])
+					queue = append(queue, w)
+				}
+			}
+		}
+		scores[nodes[s]] = sum / float64(n-1)
+		for _, v := range queue {
+			distance[v] = -1
+		}
+	}
+	return scores
+}
+
+// coreNumbers computes the k-core number of every node by repeatedly
+// removing a node of minimum degree (Batagelj and Zaversnik).
+func coreNumbers(nodes []string, undirected map[string][]string) map[string]int {
+	degree := make(map[string]int, len(nodes))
+	maxDegree := 0
+	for _, v := range nodes {
+		degree[v] = len(undirected[v])
+		if degree[v] > maxDegree {
+			maxDegree = degree[v]
+		}
+	}
+
+	// Bucket nodes by current degree
+	buckets := make([]map[string]bool, maxDegree+1)
+	for d := range buckets {
+		buckets[d] = make(map[string]bool)
+	}
+	for _, v := range nodes {
+		buckets[degree[v]][v] = true
+	}
+
+	core := make(map[string]int, len(nodes))
+	removed := make(map[string]bool, len(nodes))
+	k := 0
+	for d := 0; d <= maxDegree; {
+		if len(buckets[d]) == 0 {
+			d++
+			continue
+		}
+		// Core numbers do not depend on the order of removal
+		var v string
+		for u := range buckets[d] {
+			v = u
+			break
+		}
+		delete(buckets[d], v)
+		if d > k {
+			k = d
+		}
+		core[v] = k
+		removed[v] = true
+
+		for _, w := range undirected[v] {
+			if removed[w] || degree[w] <= d {
+				continue
+			}
+			delete(buckets[degree[w]], w)
+			degree[w]--
+			buckets[degree[w]][w] = true
+		}
+		if d > 0 && len(buckets[d-1]) > 0 {
+			d--
+		}
+	}
+	return core
+}
+
+// articulation finds the articulation points and bridges of the undirected
+// graph with an iterative Tarjan search. Points are sorted; each bridge is
+// returned once with its endpoints in search order.
+func articulation(nodes []string, undirected map[string][]string) ([]string, [][2]string) {
+	type frame struct {
+		node     string
+		parent   string
+		next     int
+		children int
+	}
+
+	index := make(map[string]int, len(nodes))
+	low := make(map[string]int, len(nodes))
+	isPoint := make(map[string]bool)
+	var bridges [][2]string
+
+	for _, root := range nodes {
+		if _, visited := index[root]; visited {
+			continue
+		}
+		index[root], low[root] = len(index), len(index)
+		frames := []frame{{node: root}}
+
+		for len(frames) > 0 {
+			top := &frames[len(frames)-1]
+			v := top.node
+
+			if top.next < len(undirected[v]) {
+				w := undirected[v][top.next]
+				top.next++
+				if w == top.parent {
+					continue // Neighbours are distinct, so this is the tree edge
+				}
+				if _, visited := index[w]; visited {
+					if index[w] < low[v] {
+						low[v] = index[w]
+					}
+					continue
+				}
+				top.children++
+				index[w], low[w] = len(index), len(index)
+				frames = append(frames, frame{node: w, parent: v})
+				continue
+			}
+
+			// All neighbours visited: v is done
+			frames = frames[:len(frames)-1]
+			if len(frames) == 0 {
+				if top.children > 1 {
+					isPoint[v] = true
+				}
+				continue
+			}
+			parent := frames[len(frames)-1].node
+			if low[v] < low[parent] {
+				low[parent] = low[v]
+			}
+			if low[v] > index[parent] {
+				bridges = append(bridges, [2]string{parent, v})
+			}
+			if low[v] >= index[parent] && len(frames) > 1 {
+				isPoint[parent] = true
+			}
+		}
+	}
+
+	points := make([]string, 0, len(isPoint))
+	for v := range isPoint {
+		points = append(points, v)
+	}
+	sort.Strings(points)
+	return points, bridges
+}
@@ -0,0 +1,117 @@
+package analysis
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBetweennessAndPageRank(t *testing.T) {
+	// a and d reach c only through b
+	cat := graphFixture(t, []string{"a", "b", "c", "d"}, [][2]string{
+		{"a", "b"}, {"b", "c"}, {"d", "b"},
+	})
+	c := ComputeCentrality(cat, nil)
+
+	if got := c.Objects["b"].Betweenness; math.Abs(got-2.0/6) > 1e-9 {
+		t.Errorf("Expected betweenness 1/3 for b, got %.4f", got)
+	}
+	if got := c.Objects["a"].Betweenness; got != 0 {
+		t.Errorf("Expected betweenness 0 for a, got %.4f", got)
+	}
+
+	sum := 0.0
+	for _, s := range c.Objects {
+		sum += s.PageRank
+	}
+	if math.Abs(sum-1) > 1e-6 {
+		t.Errorf("Expected PageRank to sum to 1, got %.6f", sum)
+	}
+	ranked, err := c.Rank("pagerank", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ranked) != 2 || ranked[0].ObjectID != "c" || ranked[1].ObjectID != "b" {
+		t.Errorf("Expected c then b by PageRank, got %v and %v", ranked[0].ObjectID, ranked[1].ObjectID)
+	}
+
+	if _, err := c.Rank("popularity", 0); err == nil {
+		t.Error("Expected error for unknown metric")
+	}
+}
+
+func TestChokepoints(t *testing.T) {
+	// Two triangles joined by x1 -> y1, and a leaf hanging off y3
+	cat := graphFixture(t, []string{"x1", "x2", "x3", "y1", "y2", "y3", "leaf"}, [][2]string{
+		{"x1", "x2"}, {"x2", "x3"}, {"x3", "x1"},
+		{"y1", "y2"}, {"y2", "y3"}, {"y3", "y1"},
+		{"x1", "y1"}, {"y3", "leaf"},
+	})
+	c := ComputeCentrality(cat, nil)
+
+	want := []string{"x1", "y1", "y3"}
+	if len(c.ArticulationPoints) != len(want) {
+		t.Fatalf("Expected articulation points %v, got %v", want, c.ArticulationPoints)
+	}
+	for i, id := range want {
+		if c.ArticulationPoints[i] != id || !c.Objects[id].Articulation {
+			t.Errorf("Expected articulation points %v, got %v", want, c.ArticulationPoints)
+		}
+	}
+
+	// The edge to the leaf is trivially a bridge and left out
+	if len(c.Bridges) != 1 || c.Bridges[0].Source != "x1" || c.Bridges[0].Target != "y1" {
+		t.Errorf("Expected only bridge x1 -> y1, got %v", c.Bridges)
+	}
+
+	for id, core := range map[string]int{"x1": 2, "y2": 2, "leaf": 1} {
+		if got := c.Objects[id].Core; got != core {
+			t.Errorf("Expected core number %d for %s, got %d", core, id, got)
+		}
+	}
+	if c.Objects["y3"].Degree != 3 {
+		t.Errorf("Expected degree 3 for y3, got %d", c.Objects["y3"].Degree)
+	}
+
+	// x1 and y1 are closest to everything
+	ranked, _ := c.Rank("closeness", 2)
+	if ranked[0].ObjectID != "y1" || ranked[1].ObjectID != "x1" {
+		t.Errorf("Expected y1 then x1 by closeness, got %s and %s", ranked[0].ObjectID, ranked[1].ObjectID)
+	}
+}
+
+func TestCentralityEmpty(t *testing.T) {
+	c := ComputeCentrality(graphFixture(t, nil, nil), nil)
+	if len(c.Objects) != 0 || len(c.ArticulationPoints) != 0 || len(c.Bridges) != 0 {
+		t.Errorf("Expected empty centrality, got %+v", c)
+	}
+}
+
+func TestLinearCentralitySkipsPathMetrics(t *testing.T) {
+	cat := graphFixture(t, []string{"a", "b", "c", "d"}, [][2]string{
+		{"a", "b"}, {"b", "c"}, {"d", "b"},
+	})
+	c := ComputeLinearCentrality(cat, nil)
+
+	if got := c.Objects["b"].Betweenness; got != 0 {
+		t.Errorf("Expected betweenness to be skipped, got %.4f", got)
+	}
+	if c.Objects["c"].PageRank == 0 || c.Objects["b"].Degree != 3 {
+		t.Errorf("Expected PageRank and degree to be computed, got %+v", c.Objects["b"])
+	}
+	for _, metric := range PathMetrics {
+		if _, err := c.Rank(metric, 0); err == nil {
+			t.Errorf("Expected error ranking by uncomputed %s", metric)
+		}
+	}
+	if _, err := c.Rank("degree", 0); err != nil {
+		t.Errorf("Expected ranking by degree, got %v", err)
+	}
+
+	report, err := GenerateReportWithOptions(cat, ReportOptions{PathCentrality: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := report.Centrality.Objects["b"].Betweenness; math.Abs(got-2.0/6) > 1e-9 {
+		t.Errorf("Expected betweenness 1/3 with PathCentrality, got %.4f", got)
+	}
+}
@@ -0,0 +1,377 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/manu/catreview/pkg/category"
+)
+
+// Community is a cluster of files that depend on each other more than on
+// the rest of the codebase.
+type Community struct {
+	ID      int      `json:"id"`
+	Name    string   `json:"name"`    // "module:<package>", numbered when a package is split
+	Package string   `json:"package"` // Package of most members, keyed as by PackageIndex
+	Objects []string `json:"objects"` // Sorted by ID
+	Size    int      `json:"size"`
+}
+
+// Misplacement is a file clustered with files of another package.
+type Misplacement struct {
+	ObjectID  string `json:"object_id"`
+	Package   string `json:"package"`   // Package, keyed as by PackageIndex
+	Community string `json:"community"` // Community it was clustered into
+	Suggested string `json:"suggested"` // Package of most of that community
+}
+
+// Communities is a partition of the files of a codebase into modules by
+// modularity, compared with its declared package partition.
+type Communities struct {
+	Clusters          []*Community    `json:"clusters"`           // Largest first
+	Modularity        float64         `json:"modularity"`         // Q of the detected partition
+	PackageModularity float64         `json:"package_modularity"` // Q of the declared package partition
+	Misplaced         []*Misplacement `json:"misplaced"`
+}
+
+// DetectCommunities clusters the files of cat with the Louvain method,
+// maximizing modularity over the undirected graph of the morphisms selected
+// (and weighed) by edges. Members of a file, such as its types and
+// functions, count as the file; an import of an analyzed package counts as
+// a dependency on each of its files, spread evenly. Resolution 1 maximizes
+// classic modularity; higher values give smaller communities.
+//
+// Modularity values are reported at resolution 1, so the detected and the
+// declared partition can be compared directly.
+func DetectCommunities(cat *category.Category, edges *EdgeFilter, resolution float64) *Communities {
+	units, packages, graph := unitGraph(cat, edges)
+
+	// Louvain: move units between communities, then merge each community
+	// into one node and repeat on the smaller graph until nothing moves
+	membership := make([]int, len(units))
+	for i := range membership {
+		membership[i] = i
+	}
+	level := graph
+	for {
+		partition, moved := louvainMoves(level, resolution)
+		if !moved {
+			break
+		}
+		for i := range membership {
+			membership[i] = partition[membership[i]]
+		}
+		level = aggregate(level, partition)
+	}
+
+	// Declared partition: units of one package together
+	declared := make([]int, len(units))
+	byPackage := make(map[string]int)
+	for i := range units {
+		key := packages[i]
+		if key == "" {
+			key = "\x00" + units[i] // No package: a module of its own
+		}
+		if _, ok := byPackage[key]; !ok {
+			byPackage[key] = len(byPackage)
+		}
+		declared[i] = byPackage[key]
+	}
+
+	result := &Communities{
+		Clusters:          clusters(units, packages, membership),
+		Modularity:        modularity(graph, membership),
+		PackageModularity: modularity(graph, declared),
+		Misplaced:         []*Misplacement{},
+	}
+	for _, c := range result.Clusters {
+		for _, id := range c.Objects {
+			pkg := packages[indexOf(units, id)]
+			if pkg != "" && pkg != c.Package {
+				result.Misplaced = append(result.Misplaced, &Misplacement{
+					ObjectID:  id,
+					Package:   pkg,
+					Community: c.Name,
+					Suggested: c.Package,
+				})
+			}
+		}
+	}
+	sort.Slice(result.Misplaced, func(i, j int) bool {
+		return result.Misplaced[i].ObjectID < result.Misplaced[j].ObjectID
+	})
+	return result
+}
+
+// weightedGraph is an undirected graph on nodes 0..n-1. Each edge is stored
+// in both directions; a self-loop stores twice its weight, so that a node's
+// degree is the sum of its row.
+type weightedGraph []map[int]float64
+
+func (g weightedGraph) add(i, j int, w float64) {
+	g[i][j] += w
+	g[j][i] += w
+}
+
+func (g weightedGraph) degree(i int) float64 {
+	k := 0.0
+	for _, w := range g[i] {
+		k += w
+	}
+	return k
+}
+
+// unitOf returns the file an object belongs to: the object itself for files
+// and objects of more abstract models, its "file" for members of a file,
+// and "" for imported packages and external objects.
+func unitOf(obj *category.Object) string {
+	switch {
+	case obj.Type == "file":
+		return obj.ID
+	case obj.Type == "imported_package" || obj.Type == "external":
+		return ""
+	}
+	if file, ok := obj.Metadata["file"].(string); ok && file != "" {
+		return file
+	}
+	return obj.ID
+}
+
+// unitGraph builds the weighted file graph of cat. It returns the sorted
+// units, the package key of each ("" if none) and the graph. Packages are
+// keyed as by PackageIndex, so that packages sharing a name stay apart.
+func unitGraph(cat *category.Category, edges *EdgeFilter) ([]string, []string, weightedGraph) {
+	pkgs := NewPackageIndex(cat)
+	index := make(map[string]int)
+	var units, packages []string
+	for _, obj := range cat.Objects() {
+		if unit := unitOf(obj); unit == obj.ID {
+			index[unit] = len(units)
+			units = append(units, unit)
+			pkg, internal := pkgs.Package(obj)
+			if !internal {
+				pkg = ""
+			}
+			packages = append(packages, pkg)
+		}
+	}
+
+	// Files of each analyzed package, targets of imports
+	files := make(map[string][]int)
+	for i, pkg := range packages {
+		if pkg != "" {
+			files[pkg] = append(files[pkg], i)
+		}
+	}
+
+	graph := make(weightedGraph, len(units))
+	for i := range graph {
+		graph[i] = make(map[int]float64)
+	}
+	for _, morph := range cat.Morphisms() {
+		if !edges.Allows(morph) {
+			continue
+		}
+		source, okSource := cat.GetObject(morph.Source)
+		target, okTarget := cat.GetObject(morph.Target)
+		if !okSource || !okTarget {
+			continue
+		}
+		from, ok := index[unitOf(source)]
+		if !ok {
+			continue
+		}
+		weight := edges.Weight(morph)
+
+		if to, ok := index[unitOf(target)]; ok {
+			if from != to {
+				graph.add(from, to, weight)
+			}
+			continue
+		}
+		if pkg, internal := pkgs.Package(target); internal && len(files[pkg]) > 0 {
+			share := weight / float64(len(files[pkg]))
+			for _, to := range files[pkg] {
+				if from != to {
+					graph.add(from, to, share)
+				}
+			}
+		}
+	}
+	return units, packages, graph
+}
+
+// louvainMoves runs the local moving phase of the Louvain method: each node
+// in turn joins the neighbouring community that increases modularity most,
+// until no node moves. It returns the communities renumbered 0..k-1 and
+// whether any node moved.
+func louvainMoves(g weightedGraph, resolution float64) ([]int, bool) {
+	n := len(g)
+	community := make([]int, n)
+	degree := make([]float64, n)
+	total := make([]float64, n) // Sum of degrees per community
+	m2 := 0.0
+	for i := range g {
+		community[i] = i
+		degree[i] = g.degree(i)
+		total[i] = degree[i]
+		m2 += degree[i]
+	}
+	if m2 == 0 {
+		return community, false
+	}
+
+	moved := false
+	for pass := 0; pass < 100; pass++ {
+		changed := false
+		for i := 0; i < n; i++ {
+			current := community[i]
+			total[current] -= degree[i]
+
+			// Weight from i to each neighbouring community
+			links := make(map[int]float64)
+			for j, w := range g[i] {
+				if j != i {
+					links[community[j]] += w
+				}
+			}
+			candidates := make([]int, 0, len(links))
+			for c := range links {
+				candidates = append(candidates, c)
+			}
+			sort.Ints(candidates)
+
+			best := current
+			bestGain := links[current] - resolution*total[current]*degree[i]/m2
+			for _, c := range candidates {
+				gain := links[c] - resolution*total[c]*degree[i]/m2
+				if gain > bestGain+1e-12 {
+					best, bestGain = c, gain
+				}
+			}
+
+			community[i] = best
+			total[best] += degree[i]
+			if best != current {
+				changed, moved = true, true
+			}
+		}
+		if !changed {
+			break
+		}
+	}
+
+	// Renumber communities densely, in order of first member
+	renumber := make(map[int]int)
+	for i, c := range community {
+		if _, ok := renumber[c]; !ok {
+			renumber[c] = len(renumber)
+		}
+		community[i] = renumber[c]
+	}
+	return community, moved && len(renumber) < n
+}
+
+// aggregate merges the nodes of each community into one node.
+func aggregate(g weightedGraph, community []int) weightedGraph {
+	size := 0
+	for _, c := range community {
+		if c+1 > size {
+			size = c + 1
+		}
+	}
+	merged := make(weightedGraph, size)
+	for i := range merged {
+		merged[i] = make(map[int]float64)
+	}
+	for i, row := range g {
+		for j, w := range row {
+			merged[community[i]][community[j]] += w
+		}
+	}
+	return merged
+}
+
+// modularity computes Q = Σ_c [in_c/2m - (tot_c/2m)²] of a partition.
+func modularity(g weightedGraph, community []int) float64 {
+	internal := make(map[int]float64)
+	total := make(map[int]float64)
+	m2 := 0.0
+	for i, row := range g {
+		for j, w := range row {
+			if community[i] == community[j] {
+				internal[community[i]] += w
+			}
+			total[community[i]] += w
+			m2 += w
+		}
+	}
+	if m2 == 0 {
+		return 0
+	}
+
+	q := 0.0
+	for c, tot := range total {
+		q += internal[c]/m2 - (tot/m2)*(tot/m2)
+	}
+	return q
+}
+
+// clusters groups units by community, largest first, and names each after
+// the declared package of most of its members.
+func clusters(units, packages []string, membership []int) []*Community {
+	byID := make(map[int]*Community)
+	var result []*Community
+	for i, c := range membership {
+		comm, ok := byID[c]
+		if !ok {
+			comm = &Community{}
+			byID[c] = comm
+			result = append(result, comm)
+		}
+		comm.Objects = append(comm.Objects, units[i])
+	}
+
+	for _, comm := range result {
+		sort.Strings(comm.Objects)
+		comm.Size = len(comm.Objects)
+
+		counts := make(map[string]int)
+		for _, id := range comm.Objects {
+			if pkg := packages[indexOf(units, id)]; pkg != "" {
+				counts[pkg]++
+			}
+		}
+		for pkg, n := range counts {
+			if n > counts[comm.Package] || (n == counts[comm.Package] && pkg < comm.Package) {
+				comm.Package = pkg
+			}
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Size != result[j].Size {
+			return result[i].Size > result[j].Size
+		}
+		return result[i].Objects[0] < result[j].Objects[0]
+	})
+
+	seen := make(map[string]int)
+	for i, comm := range result {
+		comm.ID = i
+		base := comm.Package
+		if base == "" {
+			base = comm.Objects[0]
+		}
+		seen[base]++
+		comm.Name = "module:" + base
+		if seen[base] > 1 {
+			comm.Name = fmt.Sprintf("module:%s-%d", base, seen[base])
+		}
+	}
+	return result
+}
+
+// indexOf returns the position of id in the sorted slice ids.
+func indexOf(ids []string, id string) int {
+	return sort.SearchStrings(ids, id)
+}
@@ -0,0 +1,123 @@
+package analysis
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/manu/catreview/internal/modeltest"
+	"github.com/manu/catreview/pkg/category"
+)
+
+// communityFixture builds two tightly knit groups of files, {a1, a2, a3}
+// and {b1, b2, b3, mover}, joined by one dependency. mover is declared in
+// package a but only talks to package b.
+func communityFixture(t *testing.T) *category.Category {
+	files := []struct{ path, pkg string }{
+		{"a/a1.go", "a"}, {"a/a2.go", "a"}, {"a/a3.go", "a"}, {"a/mover.go", "a"},
+		{"b/b1.go", "b"}, {"b/b2.go", "b"}, {"b/b3.go", "b"},
+	}
+	var objects []*category.Object
+	for _, f := range files {
+		objects = append(objects, category.NewObject(f.path, "file", f.path, map[string]interface{}{
+			"package": f.pkg, "import_path": "example.com/" + f.pkg,
+		}))
+	}
+	// A function declared in mover counts as mover
+	objects = append(objects, category.NewObject("a.Move", "function", "Move", map[string]interface{}{"package": "a", "file": "a/mover.go"}))
+
+	edges := [][2]string{
+		{"a/a1.go", "a/a2.go"}, {"a/a2.go", "a/a3.go"}, {"a/a3.go", "a/a1.go"},
+		{"b/b1.go", "b/b2.go"}, {"b/b2.go", "b/b3.go"}, {"b/b3.go", "b/b1.go"},
+		{"a/mover.go", "b/b1.go"}, {"a.Move", "b/b2.go"}, {"b/b3.go", "a/mover.go"},
+		{"a/a1.go", "b/b1.go"},
+	}
+	var morphisms []*category.Morphism
+	for i, e := range edges {
+		morphisms = append(morphisms, category.NewMorphism(fmt.Sprintf("e%d", i), e[0], e[1], "function_call", nil))
+	}
+	return modeltest.Build(t, "communities", objects, morphisms)
+}
+
+func TestDetectCommunities(t *testing.T) {
+	c := DetectCommunities(communityFixture(t), nil, 1)
+
+	if len(c.Clusters) != 2 {
+		t.Fatalf("Expected 2 communities, got %d: %+v", len(c.Clusters), c.Clusters)
+	}
+	b := c.Clusters[0]
+	if b.Name != "module:b" || b.Size != 4 {
+		t.Errorf("Expected module:b with 4 files first, got %s with %v", b.Name, b.Objects)
+	}
+	if a := c.Clusters[1]; a.Name != "module:a" || a.Size != 3 {
+		t.Errorf("Expected module:a with 3 files, got %s with %v", a.Name, a.Objects)
+	}
+
+	if len(c.Misplaced) != 1 {
+		t.Fatalf("Expected 1 misplaced file, got %+v", c.Misplaced)
+	}
+	if m := c.Misplaced[0]; m.ObjectID != "a/mover.go" || m.Package != "a" || m.Suggested != "b" {
+		t.Errorf("Expected a/mover.go to belong with b, got %+v", m)
+	}
+
+	if c.Modularity <= c.PackageModularity {
+		t.Errorf("Expected detected modularity %.3f above declared %.3f", c.Modularity, c.PackageModularity)
+	}
+}
+
+func TestCommunitiesResolveImports(t *testing.T) {
+	// x.go imports package b, which counts as depending on its files
+	cat := communityFixture(t)
+	modeltest.Add(t, cat, []*category.Object{
+		category.NewObject("x/x.go", "file", "x.go", map[string]interface{}{"package": "x"}),
+		category.NewObject("import:example.com/b", "imported_package", "example.com/b",
+			map[string]interface{}{"import_path": "example.com/b"}),
+	}, []*category.Morphism{
+		category.NewMorphism("imp", "x/x.go", "import:example.com/b", "import", nil),
+	})
+
+	_, _, graph := unitGraph(cat, nil)
+	if len(graph) != 8 {
+		t.Fatalf("Expected 8 files, got %d", len(graph))
+	}
+	// Files are sorted: b/b1.go is 4, x/x.go is 7
+	if w := graph[7][4]; w != 1.0/3 {
+		t.Errorf("Expected import weight 1/3 on x/x.go - b/b1.go, got %.3f", w)
+	}
+}
+
+func TestDetectCommunitiesEmpty(t *testing.T) {
+	c := DetectCommunities(graphFixture(t, []string{"a", "b"}, nil), nil, 1)
+	if len(c.Clusters) != 2 || c.Modularity != 0 || len(c.Misplaced) != 0 {
+		t.Errorf("Expected singleton communities without dependencies, got %+v", c)
+	}
+}
+
+func TestCommunitiesKeyPackagesByDirectory(t *testing.T) {
+	files := []struct{ path, pkg string }{
+		{"cmd/server/main.go", "main"},
+		{"cmd/worker/main.go", "main"},
+		{"pkg/errors/err.go", "errors"},
+	}
+	var objects []*category.Object
+	for _, f := range files {
+		objects = append(objects, category.NewObject(f.path, "file", f.path, map[string]interface{}{
+			"package": f.pkg, "import_path": "example.com/app/" + path.Dir(f.path),
+		}))
+	}
+	objects = append(objects, category.NewObject("import:errors", "imported_package", "errors",
+		map[string]interface{}{"import_path": "errors"}))
+	cat := modeltest.Build(t, "mains", objects, []*category.Morphism{
+		category.NewMorphism("imp", "cmd/worker/main.go", "import:errors", "import", nil),
+	})
+
+	units, packages, graph := unitGraph(cat, nil)
+	if got := strings.Join(packages, ","); got != "cmd/server,cmd/worker,pkg/errors" {
+		t.Errorf("Expected the main packages apart, got %v for %v", packages, units)
+	}
+	// The standard library errors package is not pkg/errors
+	if len(graph[1]) != 0 {
+		t.Errorf("Expected no dependency of cmd/worker, got %v", graph[1])
+	}
+}
@@ -4,15 +4,21 @@
 // - Basu-Isik diagram complexity: c(D) = Σc_objects + Σc_morphisms + c_composition
 // - Kolmogorov complexity estimation via compression
 // - Coupling metrics (afferent/efferent coupling, instability)
+// - Package metrics: abstractness and distance from the main sequence
+// - Centrality: PageRank, betweenness, closeness, k-cores, articulation points
+// - Community detection (Louvain) compared with the declared packages
+// - Dead code: functions, types and files unreachable from the roots
 // - Cycle detection in dependency graphs
+// - Logical coupling from version-control history (hidden dependencies)
 package analysis
 
 import (
 	"compress/gzip"
 	"bytes"
-	"encoding/json"
+	"crypto/sha256"
 	"fmt"
 	"math"
+	"strings"
 
 	"github.com/manu/catreview/pkg/category"
 )
@@ -20,6 +26,10 @@ import (
 // ComplexityAnalyzer computes categorical complexity metrics.
 type ComplexityAnalyzer struct {
 	cat *category.Category
+
+	// Edges selects and weighs the morphisms counted as dependencies
+	// (nil: all structural morphisms)
+	Edges *EdgeFilter
 }
 
 // NewComplexityAnalyzer creates a new complexity analyzer.
@@ -69,8 +79,8 @@ func (a *ComplexityAnalyzer) objectComplexity() float64 {
 func (a *ComplexityAnalyzer) morphismComplexity() float64 {
 	total := 0.0
 	for _, morph := range a.cat.Morphisms() {
-		if morph.Type == "identity" {
-			continue // Identity morphisms have minimal complexity
+		if !a.Edges.Allows(morph) {
+			continue // Identity and co-change morphisms add no structural complexity
 		}
 
 		// Base complexity by morphism type
@@ -86,7 +96,7 @@ func (a *ComplexityAnalyzer) morphismComplexity() float64 {
 			typeComplexity = 2.5
 		}
 
-		total += typeComplexity
+		total += typeComplexity * a.Edges.Weight(morph)
 	}
 	return total
 }
@@ -96,7 +106,7 @@ func (a *ComplexityAnalyzer) compositionComplexity() float64 {
 	// Build adjacency map for composition chains
 	adjacency := make(map[string][]string)
 	for _, morph := range a.cat.Morphisms() {
-		if morph.Type == "identity" {
+		if !a.Edges.Allows(morph) {
 			continue
 		}
 		adjacency[morph.Target] = append(adjacency[morph.Target], morph.Source)
@@ -105,7 +115,7 @@ func (a *ComplexityAnalyzer) compositionComplexity() float64 {
 	// Count composable chains of length 2+
 	chains := 0.0
 	for _, morph := range a.cat.Morphisms() {
-		if morph.Type == "identity" {
+		if !a.Edges.Allows(morph) {
 			continue
 		}
 		// Count how many morphisms can compose with this one
@@ -124,8 +134,8 @@ func (a *ComplexityAnalyzer) compositionComplexity() float64 {
 //
 // This is an upper bound on the true Kolmogorov complexity.
 func (a *ComplexityAnalyzer) KolmogorovComplexity() (int, error) {
-	// Serialize category to JSON
-	data, err := json.Marshal(a.cat)
+	// Serialize category to canonical JSON
+	data, err := category.CanonicalJSON(a.cat)
 	if err != nil {
 		return 0, err
 	}
@@ -149,12 +159,16 @@ type CouplingMetrics struct {
 	AfferentCoupling int     `json:"afferent_coupling"`  // Ca: incoming dependencies
 	EfferentCoupling int     `json:"efferent_coupling"`  // Ce: outgoing dependencies
 	Instability      float64 `json:"instability"`        // I = Ce / (Ca + Ce)
-	Abstractness     float64 `json:"abstractness"`       // A (0-1, based on type)
+	Abstractness     float64 `json:"abstractness"`       // A = abstract types / types declared by the object
+	Distance         float64 `json:"distance"`           // D = |A + I - 1|, distance from the main sequence
+	AfferentWeight   float64 `json:"afferent_weight,omitempty"` // Weighted Ca, with per-type edge weights
+	EfferentWeight   float64 `json:"efferent_weight,omitempty"` // Weighted Ce, with per-type edge weights
 }
 
 // ComputeCoupling computes coupling metrics for all objects.
 func (a *ComplexityAnalyzer) ComputeCoupling() map[string]*CouplingMetrics {
 	metrics := make(map[string]*CouplingMetrics)
+	abstractness := a.computeAbstractness()
 
 	// Initialize metrics for all objects
 	for _, obj := range a.cat.Objects() {
@@ -162,60 +176,93 @@ func (a *ComplexityAnalyzer) ComputeCoupling() map[string]*CouplingMetrics {
 			ObjectID:         obj.ID,
 			AfferentCoupling: 0,
 			EfferentCoupling: 0,
-			Abstractness:     a.computeAbstractness(obj),
+			Abstractness:     abstractness[obj.ID],
 		}
 	}
 
 	// Count incoming and outgoing dependencies
 	for _, morph := range a.cat.Morphisms() {
-		if morph.Type == "identity" {
+		if !a.Edges.Allows(morph) {
 			continue
 		}
 
+		weight := a.Edges.Weight(morph)
+
 		// Efferent (outgoing) from source
 		if m, exists := metrics[morph.Source]; exists {
 			m.EfferentCoupling++
+			m.EfferentWeight += weight
 		}
 
 		// Afferent (incoming) to target
 		if m, exists := metrics[morph.Target]; exists {
 			m.AfferentCoupling++
+			m.AfferentWeight += weight
 		}
 	}
 
-	// Compute instability: I = Ce / (Ca + Ce)
+	// Compute instability: I = Ce / (Ca + Ce), from weights when weighted
+	weighted := a.Edges.Weighted()
 	for _, m := range metrics {
-		total := m.AfferentCoupling + m.EfferentCoupling
+		total := m.AfferentWeight + m.EfferentWeight
 		if total > 0 {
-			m.Instability = float64(m.EfferentCoupling) / float64(total)
+			m.Instability = m.EfferentWeight / total
+		}
+		m.Distance = math.Abs(m.Abstractness + m.Instability - 1)
+		if !weighted {
+			m.AfferentWeight, m.EfferentWeight = 0, 0
 		}
 	}
 
 	return metrics
 }
 
-// computeAbstractness estimates abstractness of an object (0-1).
-func (a *ComplexityAnalyzer) computeAbstractness(obj *category.Object) float64 {
-	switch obj.Type {
-	case "interface":
-		return 1.0 // Fully abstract
-	case "struct":
-		return 0.1 // Mostly concrete
-	case "function":
-		if exported, ok := obj.Metadata["is_exported"].(bool); ok && exported {
-			return 0.5 // Exported functions are semi-abstract (API)
+// computeAbstractness returns, for each object declaring types, the ratio
+// of interfaces among them: a type declares itself, a file the types in it
+// and a package object the types of its package.
+func (a *ComplexityAnalyzer) computeAbstractness() map[string]float64 {
+	types := make(map[string]int)
+	abstract := make(map[string]int)
+	packages := make(map[string]string) // Package name -> package object ID
+	for _, obj := range a.cat.Objects() {
+		if obj.Type == "package" {
+			packages[obj.Name] = obj.ID
+		}
+	}
+
+	for _, obj := range a.cat.Objects() {
+		if !isType(obj) {
+			continue
+		}
+		owners := []string{obj.ID}
+		if file, ok := obj.Metadata["file"].(string); ok && file != "" {
+			owners = append(owners, file)
+		}
+		if name, ok := obj.Metadata["package"].(string); ok && packages[name] != "" {
+			owners = append(owners, packages[name])
 		}
-		return 0.2
-	case "package":
-		return 0.3
-	default:
-		return 0.0
+		for _, id := range owners {
+			types[id]++
+			if obj.Type == "interface" {
+				abstract[id]++
+			}
+		}
+	}
+
+	ratios := make(map[string]float64, len(types))
+	for id, n := range types {
+		ratios[id] = float64(abstract[id]) / float64(n)
 	}
+	return ratios
 }
 
 // CycleAnalyzer detects cycles in the dependency graph.
 type CycleAnalyzer struct {
 	cat *category.Category
+
+	// Edges selects the morphisms followed as dependencies
+	// (nil: all structural morphisms)
+	Edges *EdgeFilter
 }
 
 // NewCycleAnalyzer creates a new cycle analyzer.
@@ -229,87 +276,75 @@ type Cycle struct {
 	Length  int      `json:"length"`
 }
 
-// FindCycles detects all cycles in the dependency graph using DFS.
-func (c *CycleAnalyzer) FindCycles() []*Cycle {
-	// Build adjacency list
-	adjacency := make(map[string][]string)
-	for _, morph := range c.cat.Morphisms() {
-		if morph.Type == "identity" {
-			continue
-		}
-		adjacency[morph.Source] = append(adjacency[morph.Source], morph.Target)
-	}
-
-	// Track visited nodes and current path
-	visited := make(map[string]bool)
-	recStack := make(map[string]bool)
-	var cycles []*Cycle
-
-	// DFS from each node
-	for _, obj := range c.cat.Objects() {
-		if !visited[obj.ID] {
-			path := []string{}
-			c.dfs(obj.ID, adjacency, visited, recStack, path, &cycles)
-		}
-	}
-
-	return cycles
+// Fingerprint identifies the cycle by its objects, in order from the
+// smallest object ID.
+func (c *Cycle) Fingerprint() string {
+	return Fingerprint(append([]string{"cycle"}, c.Objects...)...)
 }
 
-// dfs performs depth-first search to detect cycles.
-func (c *CycleAnalyzer) dfs(
-	node string,
-	adjacency map[string][]string,
-	visited map[string]bool,
-	recStack map[string]bool,
-	path []string,
-	cycles *[]*Cycle,
-) {
-	visited[node] = true
-	recStack[node] = true
-	path = append(path, node)
-
-	// Visit neighbors
-	for _, neighbor := range adjacency[node] {
-		if !visited[neighbor] {
-			c.dfs(neighbor, adjacency, visited, recStack, path, cycles)
-		} else if recStack[neighbor] {
-			// Found a cycle - extract it from path
-			cycleStart := -1
-			for i, n := range path {
-				if n == neighbor {
-					cycleStart = i
-					break
-				}
-			}
-			if cycleStart >= 0 {
-				cycle := &Cycle{
-					Objects: append([]string{}, path[cycleStart:]...),
-					Length:  len(path) - cycleStart,
-				}
-				*cycles = append(*cycles, cycle)
-			}
-		}
-	}
+// Fingerprint identifies a finding by the parts that define it, such as a
+// rule and the objects involved, but not by line numbers or counts, so that
+// it survives unrelated changes to the code.
+func Fingerprint(parts ...string) string {
+	hash := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return fmt.Sprintf("%x", hash[:8])
+}
 
-	recStack[node] = false
+// FindCycles lists the elementary cycles of the dependency graph, up to
+// DefaultCycleLimit. See EnumerateCycles.
+func (c *CycleAnalyzer) FindCycles() []*Cycle {
+	cycles, _ := c.EnumerateCycles(DefaultCycleLimit)
+	return cycles
 }
 
 // Report generates a comprehensive analysis report.
 type Report struct {
+	ModelHash        string                   `json:"model_hash"`
 	CategoryStats    map[string]int           `json:"category_stats"`
 	DiagramComplexity float64                 `json:"diagram_complexity"`
 	KolmogorovComplexity int                  `json:"kolmogorov_complexity"`
 	CouplingMetrics  map[string]*CouplingMetrics `json:"coupling_metrics"`
+	StronglyConnected []*Component            `json:"strongly_connected"`
 	Cycles           []*Cycle                 `json:"cycles"`
+	CyclesTruncated  bool                     `json:"cycles_truncated,omitempty"`
 	TopUnstable      []*CouplingMetrics       `json:"top_unstable"`
 	TopCoupled       []*CouplingMetrics       `json:"top_coupled"`
+	Packages         []*PackageMetrics        `json:"packages"`
+	Centrality       *Centrality              `json:"centrality"`
+	Communities      *Communities             `json:"communities"`
+	DeadCode         *DeadCode                `json:"dead_code"`
+	HiddenDependencies []*HiddenDependency    `json:"hidden_dependencies,omitempty"`
+	TeamCoupling     *TeamCouplingMatrix      `json:"team_coupling,omitempty"`
+}
+
+// ReportOptions tunes GenerateReportWithOptions.
+type ReportOptions struct {
+	// CycleLimit caps the elementary cycles listed in the report; 0 lists
+	// none. Strongly connected components are always reported.
+	CycleLimit int
+
+	// Edges selects and weighs the morphisms analyzed as dependencies
+	// (nil: all structural morphisms)
+	Edges *EdgeFilter
+
+	// PathCentrality computes betweenness and closeness, which take O(n·m)
+	// time; otherwise only the centrality metrics linear in the model size
+	// are reported.
+	PathCentrality bool
 }
 
-// GenerateReport creates a comprehensive analysis report.
+// GenerateReport creates a comprehensive analysis report, listing up to
+// DefaultCycleLimit cycles.
 func GenerateReport(cat *category.Category) (*Report, error) {
+	return GenerateReportWithOptions(cat, ReportOptions{CycleLimit: DefaultCycleLimit})
+}
+
+// GenerateReportWithOptions creates a comprehensive analysis report.
+func GenerateReportWithOptions(cat *category.Category, opts ReportOptions) (*Report, error) {
 	complexityAnalyzer := NewComplexityAnalyzer(cat)
+	complexityAnalyzer.Edges = opts.Edges
 	cycleAnalyzer := NewCycleAnalyzer(cat)
+	cycleAnalyzer.Edges = opts.Edges
 
 	// Compute metrics
 	diagramComplexity := complexityAnalyzer.DiagramComplexity()
@@ -319,7 +354,20 @@ func GenerateReport(cat *category.Category) (*Report, error) {
 	}
 
 	couplingMetrics := complexityAnalyzer.ComputeCoupling()
-	cycles := cycleAnalyzer.FindCycles()
+	components := cycleAnalyzer.StronglyConnectedComponents()
+	cycles, truncated := []*Cycle{}, false
+	if opts.CycleLimit > 0 {
+		cycles, truncated = cycleAnalyzer.EnumerateCycles(opts.CycleLimit)
+	}
+	hidden := NewLogicalCouplingAnalyzer(cat).FindHiddenDependencies()
+	centrality := ComputeLinearCentrality(cat, opts.Edges)
+	if opts.PathCentrality {
+		centrality = ComputeCentrality(cat, opts.Edges)
+	}
+	deadCode, err := FindDeadCode(cat, DeadCodeOptions{Edges: opts.Edges})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find dead code: %v", err)
+	}
 
 	// Find top unstable and coupled components
 	topUnstable := findTopN(couplingMetrics, 10, func(m *CouplingMetrics) float64 {
@@ -330,13 +378,22 @@ func GenerateReport(cat *category.Category) (*Report, error) {
 	})
 
 	return &Report{
+		ModelHash:            cat.Hash(),
 		CategoryStats:        cat.Stats(),
 		DiagramComplexity:    diagramComplexity,
 		KolmogorovComplexity: kolmogorov,
 		CouplingMetrics:      couplingMetrics,
+		StronglyConnected:    components,
 		Cycles:               cycles,
+		CyclesTruncated:      truncated,
 		TopUnstable:          topUnstable,
 		TopCoupled:           topCoupled,
+		Packages:             ComputePackageMetrics(cat, opts.Edges),
+		Centrality:           centrality,
+		Communities:          DetectCommunities(cat, opts.Edges, 1),
+		DeadCode:             deadCode,
+		HiddenDependencies:   hidden,
+		TeamCoupling:         ComputeTeamCoupling(cat, opts.Edges),
 	}, nil
 }
 
@@ -348,13 +405,13 @@ func findTopN(metrics map[string]*CouplingMetrics, n int, scorer func(*CouplingM
 		slice = append(slice, m)
 	}
 
-	// Simple selection sort for top N
+	// Simple selection sort for top N, ties broken by object ID
 	for i := 0; i < n && i < len(slice); i++ {
 		maxIdx := i
 		maxScore := scorer(slice[i])
 		for j := i + 1; j < len(slice); j++ {
 			score := scorer(slice[j])
-			if score > maxScore {
+			if score > maxScore || (score == maxScore && slice[j].ObjectID < slice[maxIdx].ObjectID) {
 				maxIdx = j
 				maxScore = score
 			}
@@ -0,0 +1,241 @@
+package analysis
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/manu/catreview/pkg/category"
+)
+
+// Dead code roots: the objects a program or library is used through.
+const (
+	RootMain     = "main"     // main functions of main packages
+	RootExported = "exported" // Exported API of library packages
+	RootInit     = "init"     // init functions
+	RootTest     = "test"     // Test, Benchmark, Example and Fuzz functions of test files
+)
+
+// DeadCodeRoots lists the root kinds, all enabled by default.
+var DeadCodeRoots = []string{RootMain, RootExported, RootInit, RootTest}
+
+// DeadCodeOptions configures FindDeadCode.
+type DeadCodeOptions struct {
+	// Roots selects the root kinds (empty: all of DeadCodeRoots)
+	Roots []string
+
+	// Allow lists further roots, such as names registered by reflection or
+	// called from assembly: object IDs or names, matched as globs
+	// ("handlers.*") or as regular expressions with a "regex:" prefix
+	Allow []string
+
+	// Edges selects the morphisms followed from the roots (nil: all
+	// structural morphisms). Declarations ("defines") are never followed.
+	Edges *EdgeFilter
+}
+
+// DeadObject is a function or type no root reaches.
+type DeadObject struct {
+	ObjectID   string `json:"object_id"`
+	Type       string `json:"type"`
+	File       string `json:"file,omitempty"`
+	Referenced bool   `json:"referenced"` // Referenced, but only by dead code
+}
+
+// DeadCode lists the objects of a codebase unreachable from its roots.
+type DeadCode struct {
+	Roots     int           `json:"roots"`
+	Reachable int           `json:"reachable"`
+	Functions []*DeadObject `json:"functions"` // Unused functions and methods, by ID
+	Types     []*DeadObject `json:"types"`     // Unused types, by ID
+	Files     []string      `json:"files"`     // Files declaring only dead code
+}
+
+// FindDeadCode finds the functions and types of cat that no root reaches
+// through the morphisms selected by opts.Edges.
+//
+// Without type information method calls can't be resolved, so dispatch is
+// approximated: a reachable type keeps all its methods alive, and a
+// reachable method its receiver type. Package-level initializers always
+// run, so files are roots too. Files declaring functions or types, none of
+// which is reachable, are reported as orphaned.
+func FindDeadCode(cat *category.Category, opts DeadCodeOptions) (*DeadCode, error) {
+	roots := opts.Roots
+	if len(roots) == 0 {
+		roots = DeadCodeRoots
+	}
+	for _, root := range roots {
+		if !containsType(DeadCodeRoots, root) {
+			return nil, fmt.Errorf("unknown root %q (want %s)", root, strings.Join(DeadCodeRoots, ", "))
+		}
+	}
+	allowed, err := compileAllowlist(opts.Allow)
+	if err != nil {
+		return nil, err
+	}
+
+	// Uses of each object, and the methods of each type
+	uses := make(map[string][]string)
+	referenced := make(map[string]bool)
+	for _, morph := range cat.Morphisms() {
+		if opts.Edges.Allows(morph) && morph.Type != "defines" && morph.Source != morph.Target {
+			uses[morph.Source] = append(uses[morph.Source], morph.Target)
+			referenced[morph.Target] = true
+		}
+	}
+	for _, obj := range cat.Objects() {
+		if recv := receiverType(obj); recv != "" {
+			uses[recv] = append(uses[recv], obj.ID)
+			uses[obj.ID] = append(uses[obj.ID], recv)
+		}
+	}
+
+	result := &DeadCode{
+		Functions: []*DeadObject{},
+		Types:     []*DeadObject{},
+		Files:     []string{},
+	}
+	reachable := make(map[string]bool)
+	var queue []string
+	for _, obj := range cat.Objects() {
+		if obj.Type == "file" || allowed(obj) || containsType(roots, rootKind(obj)) {
+			result.Roots++
+			reachable[obj.ID] = true
+			queue = append(queue, obj.ID)
+		}
+	}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for _, target := range uses[id] {
+			if _, ok := cat.GetObject(target); ok && !reachable[target] {
+				reachable[target] = true
+				queue = append(queue, target)
+			}
+		}
+	}
+	result.Reachable = len(reachable)
+
+	// Files with declarations, and whether any of them is alive
+	declares := make(map[string]bool)
+	for _, obj := range cat.Objects() {
+		file, _ := obj.Metadata["file"].(string)
+		switch obj.Type {
+		case "function", "struct", "interface", "type":
+		default:
+			continue
+		}
+		if file != "" {
+			declares[file] = declares[file] || reachable[obj.ID]
+		}
+		if reachable[obj.ID] {
+			continue
+		}
+
+		dead := &DeadObject{ObjectID: obj.ID, Type: obj.Type, File: file, Referenced: referenced[obj.ID]}
+		if obj.Type == "function" {
+			result.Functions = append(result.Functions, dead)
+		} else {
+			result.Types = append(result.Types, dead)
+		}
+	}
+	for file, alive := range declares {
+		if !alive {
+			result.Files = append(result.Files, file)
+		}
+	}
+
+	for _, list := range [][]*DeadObject{result.Functions, result.Types} {
+		sort.Slice(list, func(i, j int) bool { return list[i].ObjectID < list[j].ObjectID })
+	}
+	sort.Strings(result.Files)
+	return result, nil
+}
+
+// rootKind returns the kind of root an object is, or "" if it is none.
+func rootKind(obj *category.Object) string {
+	if obj.Type != "function" {
+		if entryPointKind(obj) == EntryPointExported {
+			return RootExported
+		}
+		return ""
+	}
+
+	file, _ := obj.Metadata["file"].(string)
+	isMethod := receiverType(obj) != ""
+	switch {
+	case obj.Name == "init" && !isMethod:
+		return RootInit
+	case strings.HasSuffix(file, "_test.go") && !isMethod && isTestName(obj.Name):
+		return RootTest
+	}
+	switch entryPointKind(obj) {
+	case EntryPointMain:
+		return RootMain
+	case EntryPointExported:
+		return RootExported
+	}
+	return ""
+}
+
+// isTestName reports whether a function name is one the go tool runs.
+func isTestName(name string) bool {
+	for _, prefix := range []string{"Test", "Benchmark", "Example", "Fuzz"} {
+		if rest, ok := strings.CutPrefix(name, prefix); ok {
+			r, _ := utf8.DecodeRuneInString(rest)
+			return rest == "" || !unicode.IsLower(r)
+		}
+	}
+	return false
+}
+
+// receiverType returns the type a method ("pkg.Recv.Name" or
+// "pkg.*Recv.Name") is declared on, or "" for other objects.
+func receiverType(obj *category.Object) string {
+	pkg, _ := obj.Metadata["package"].(string)
+	if obj.Type != "function" || pkg == "" {
+		return ""
+	}
+	recv, _, isMethod := strings.Cut(strings.TrimPrefix(obj.ID, pkg+"."), ".")
+	if !isMethod || recv == "" {
+		return ""
+	}
+	return pkg + "." + strings.TrimLeft(recv, "*")
+}
+
+// compileAllowlist compiles allowlist patterns into a predicate matching
+// objects by ID or name.
+func compileAllowlist(patterns []string) (func(*category.Object) bool, error) {
+	var matchers []func(string) bool
+	for _, pattern := range patterns {
+		if expr, ok := strings.CutPrefix(pattern, "regex:"); ok {
+			re, err := regexp.Compile(expr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid allowlist pattern %q: %v", pattern, err)
+			}
+			matchers = append(matchers, re.MatchString)
+			continue
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid allowlist pattern %q: %v", pattern, err)
+		}
+		glob := pattern
+		matchers = append(matchers, func(s string) bool {
+			ok, _ := path.Match(glob, s)
+			return ok
+		})
+	}
+
+	return func(obj *category.Object) bool {
+		for _, match := range matchers {
+			if match(obj.ID) || match(obj.Name) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
@@ -0,0 +1,134 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/manu/catreview/internal/modeltest"
+	"github.com/manu/catreview/pkg/category"
+)
+
+// deadCodeFixture models a main package using part of a library:
+//
+//	main.main --function_call--> lib.Run --function_call--> lib.helper
+//	lib.Run --type_dependency--> lib.state, the receiver of lib.*state.reset
+//	lib.unused --function_call--> lib.orphanHelper (both in lib/old.go)
+func deadCodeFixture(t *testing.T) *category.Category {
+	objects := []*category.Object{
+		category.NewObject("cmd/main.go", "file", "main.go", map[string]interface{}{"package": "main"}),
+		category.NewObject("main.main", "function", "main", map[string]interface{}{"package": "main", "file": "cmd/main.go"}),
+		category.NewObject("main.setup", "function", "setup", map[string]interface{}{"package": "main", "file": "cmd/main.go"}),
+		category.NewObject("lib/lib.go", "file", "lib.go", map[string]interface{}{"package": "lib"}),
+		category.NewObject("lib.Run", "function", "Run", map[string]interface{}{"package": "lib", "file": "lib/lib.go", "is_exported": true}),
+		category.NewObject("lib.helper", "function", "helper", map[string]interface{}{"package": "lib", "file": "lib/lib.go", "is_exported": false}),
+		category.NewObject("lib.init", "function", "init", map[string]interface{}{"package": "lib", "file": "lib/lib.go", "is_exported": false}),
+		category.NewObject("lib.state", "struct", "state", map[string]interface{}{"package": "lib", "file": "lib/lib.go"}),
+		category.NewObject("lib.*state.reset", "function", "reset", map[string]interface{}{"package": "lib", "file": "lib/lib.go", "is_exported": false}),
+		category.NewObject("lib.handler", "function", "handler", map[string]interface{}{"package": "lib", "file": "lib/lib.go", "is_exported": false}),
+		category.NewObject("lib.cache", "struct", "cache", map[string]interface{}{"package": "lib", "file": "lib/lib.go"}),
+		category.NewObject("lib/old.go", "file", "old.go", map[string]interface{}{"package": "lib"}),
+		category.NewObject("lib.unused", "function", "unused", map[string]interface{}{"package": "lib", "file": "lib/old.go", "is_exported": false}),
+		category.NewObject("lib.orphanHelper", "function", "orphanHelper", map[string]interface{}{"package": "lib", "file": "lib/old.go", "is_exported": false}),
+	}
+
+	morphisms := []*category.Morphism{
+		category.NewMorphism("d1", "cmd/main.go", "main.setup", "defines", nil),
+		category.NewMorphism("d2", "lib/lib.go", "lib.helper", "defines", nil),
+		category.NewMorphism("c1", "main.main", "lib.Run", "function_call", nil),
+		category.NewMorphism("c2", "lib.Run", "lib.helper", "function_call", nil),
+		category.NewMorphism("t1", "lib.Run", "lib.state", "type_dependency", nil),
+		category.NewMorphism("c3", "lib.unused", "lib.orphanHelper", "function_call", nil),
+		category.NewMorphism("x1", "lib/lib.go", "lib/old.go", "co_changes_with", nil),
+	}
+	return modeltest.Build(t, "deadcode", objects, morphisms)
+}
+
+func deadIDs(objects []*DeadObject) map[string]*DeadObject {
+	ids := make(map[string]*DeadObject)
+	for _, obj := range objects {
+		ids[obj.ObjectID] = obj
+	}
+	return ids
+}
+
+func TestFindDeadCode(t *testing.T) {
+	dead, err := FindDeadCode(deadCodeFixture(t), DeadCodeOptions{})
+	if err != nil {
+		t.Fatalf("FindDeadCode failed: %v", err)
+	}
+
+	functions := deadIDs(dead.Functions)
+	want := []string{"lib.handler", "lib.orphanHelper", "lib.unused", "main.setup"}
+	if len(functions) != len(want) {
+		t.Errorf("Expected dead functions %v, got %v", want, functions)
+	}
+	for _, id := range want {
+		if _, ok := functions[id]; !ok {
+			t.Errorf("Expected %s to be dead", id)
+		}
+	}
+	if !functions["lib.orphanHelper"].Referenced || functions["lib.unused"].Referenced {
+		t.Error("Expected lib.orphanHelper referenced only by dead code, lib.unused not at all")
+	}
+
+	// lib.state is used, and keeps its method alive; lib.cache is not
+	types := deadIDs(dead.Types)
+	if len(types) != 1 || types["lib.cache"] == nil {
+		t.Errorf("Expected only lib.cache dead, got %v", types)
+	}
+
+	if len(dead.Files) != 1 || dead.Files[0] != "lib/old.go" {
+		t.Errorf("Expected lib/old.go orphaned, got %v", dead.Files)
+	}
+}
+
+func TestFindDeadCodeOptions(t *testing.T) {
+	dead, err := FindDeadCode(deadCodeFixture(t), DeadCodeOptions{
+		Allow: []string{"handler", "regex:^main\\.set"},
+	})
+	if err != nil {
+		t.Fatalf("FindDeadCode failed: %v", err)
+	}
+	functions := deadIDs(dead.Functions)
+	for _, id := range []string{"lib.handler", "main.setup"} {
+		if functions[id] != nil {
+			t.Errorf("Expected allowlisted %s alive", id)
+		}
+	}
+
+	// Following only imports, nothing is called
+	dead, err = FindDeadCode(deadCodeFixture(t), DeadCodeOptions{Edges: &EdgeFilter{Include: []string{"import"}}})
+	if err != nil {
+		t.Fatalf("FindDeadCode failed: %v", err)
+	}
+	if deadIDs(dead.Functions)["lib.helper"] == nil {
+		t.Error("Expected lib.helper dead when calls are not followed")
+	}
+
+	if _, err := FindDeadCode(deadCodeFixture(t), DeadCodeOptions{Roots: []string{"everything"}}); err == nil {
+		t.Error("Expected an error for an unknown root kind")
+	}
+	if _, err := FindDeadCode(deadCodeFixture(t), DeadCodeOptions{Allow: []string{"regex:("}}); err == nil {
+		t.Error("Expected an error for an invalid allowlist pattern")
+	}
+}
+
+func TestRootKind(t *testing.T) {
+	tests := []struct {
+		obj  *category.Object
+		want string
+	}{
+		{category.NewObject("main.main", "function", "main", map[string]interface{}{"package": "main"}), RootMain},
+		{category.NewObject("lib.init", "function", "init", map[string]interface{}{"package": "lib"}), RootInit},
+		{category.NewObject("lib.*T.init", "function", "init", map[string]interface{}{"package": "lib"}), ""},
+		{category.NewObject("lib.TestFoo", "function", "TestFoo", map[string]interface{}{"package": "lib", "file": "lib/a_test.go"}), RootTest},
+		{category.NewObject("lib.Testify", "function", "Testify", map[string]interface{}{"package": "lib", "file": "lib/a_test.go"}), ""},
+		{category.NewObject("lib.TestFoo", "function", "TestFoo", map[string]interface{}{"package": "lib", "file": "lib/a.go", "is_exported": true}), RootExported},
+		{category.NewObject("lib.Config", "struct", "Config", map[string]interface{}{"package": "lib"}), RootExported},
+		{category.NewObject("lib.config", "struct", "config", map[string]interface{}{"package": "lib"}), ""},
+	}
+	for _, tt := range tests {
+		if got := rootKind(tt.obj); got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.obj.ID, tt.want, got)
+		}
+	}
+}
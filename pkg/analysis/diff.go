@@ -0,0 +1,137 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// CouplingDelta is the change in coupling of a single object between two models.
+// Objects missing from one side have zero coupling there.
+type CouplingDelta struct {
+	ObjectID          string  `json:"object_id"`
+	AfferentBefore    int     `json:"afferent_before"`
+	AfferentAfter     int     `json:"afferent_after"`
+	EfferentBefore    int     `json:"efferent_before"`
+	EfferentAfter     int     `json:"efferent_after"`
+	InstabilityBefore float64 `json:"instability_before"`
+	InstabilityAfter  float64 `json:"instability_after"`
+}
+
+// TotalDelta returns the change in total coupling (Ca + Ce).
+func (d *CouplingDelta) TotalDelta() int {
+	return (d.AfferentAfter + d.EfferentAfter) - (d.AfferentBefore + d.EfferentBefore)
+}
+
+// ComplexityDelta compares the complexity metrics of two reports.
+type ComplexityDelta struct {
+	DiagramBefore    float64 `json:"diagram_before"`
+	DiagramAfter     float64 `json:"diagram_after"`
+	KolmogorovBefore int     `json:"kolmogorov_before"`
+	KolmogorovAfter  int     `json:"kolmogorov_after"`
+}
+
+// ChangeReport summarizes the architectural impact of a change between
+// two models: new and resolved cycles, coupling deltas and complexity deltas.
+//
+// CyclesIncomplete is set when either report lists only some of its cycles:
+// a cycle beyond the before report's limit then shows as new, and one beyond
+// the after report's limit as resolved.
+type ChangeReport struct {
+	BeforeHash       string           `json:"before_hash"`
+	AfterHash        string           `json:"after_hash"`
+	NewCycles        []*Cycle         `json:"new_cycles"`
+	ResolvedCycles   []*Cycle         `json:"resolved_cycles"`
+	CyclesIncomplete bool             `json:"cycles_incomplete,omitempty"`
+	CouplingDeltas   []*CouplingDelta `json:"coupling_deltas"`
+	Complexity       *ComplexityDelta `json:"complexity"`
+}
+
+// CompareReports builds a change report from the analysis reports of the
+// before and after models.
+func CompareReports(before, after *Report) *ChangeReport {
+	change := &ChangeReport{
+		BeforeHash:       before.ModelHash,
+		AfterHash:        after.ModelHash,
+		NewCycles:        cycleDifference(after.Cycles, before.Cycles),
+		ResolvedCycles:   cycleDifference(before.Cycles, after.Cycles),
+		CyclesIncomplete: before.CyclesTruncated || after.CyclesTruncated,
+		CouplingDeltas:   []*CouplingDelta{},
+		Complexity: &ComplexityDelta{
+			DiagramBefore:    before.DiagramComplexity,
+			DiagramAfter:     after.DiagramComplexity,
+			KolmogorovBefore: before.KolmogorovComplexity,
+			KolmogorovAfter:  after.KolmogorovComplexity,
+		},
+	}
+
+	ids := make(map[string]bool)
+	for id := range before.CouplingMetrics {
+		ids[id] = true
+	}
+	for id := range after.CouplingMetrics {
+		ids[id] = true
+	}
+
+	for id := range ids {
+		delta := &CouplingDelta{ObjectID: id}
+		if m, ok := before.CouplingMetrics[id]; ok {
+			delta.AfferentBefore = m.AfferentCoupling
+			delta.EfferentBefore = m.EfferentCoupling
+			delta.InstabilityBefore = m.Instability
+		}
+		if m, ok := after.CouplingMetrics[id]; ok {
+			delta.AfferentAfter = m.AfferentCoupling
+			delta.EfferentAfter = m.EfferentCoupling
+			delta.InstabilityAfter = m.Instability
+		}
+		if delta.AfferentBefore != delta.AfferentAfter || delta.EfferentBefore != delta.EfferentAfter {
+			change.CouplingDeltas = append(change.CouplingDeltas, delta)
+		}
+	}
+
+	// Largest absolute change first
+	sort.Slice(change.CouplingDeltas, func(i, j int) bool {
+		di := math.Abs(float64(change.CouplingDeltas[i].TotalDelta()))
+		dj := math.Abs(float64(change.CouplingDeltas[j].TotalDelta()))
+		if di != dj {
+			return di > dj
+		}
+		return change.CouplingDeltas[i].ObjectID < change.CouplingDeltas[j].ObjectID
+	})
+
+	return change
+}
+
+// cycleDifference returns the cycles in a that do not occur in b.
+// Cycles are compared independently of their starting object.
+func cycleDifference(a, b []*Cycle) []*Cycle {
+	known := make(map[string]bool, len(b))
+	for _, c := range b {
+		known[cycleKey(c)] = true
+	}
+
+	diff := []*Cycle{}
+	for _, c := range a {
+		if !known[cycleKey(c)] {
+			diff = append(diff, c)
+		}
+	}
+	return diff
+}
+
+// cycleKey returns a rotation-independent key for a cycle.
+func cycleKey(c *Cycle) string {
+	if len(c.Objects) == 0 {
+		return ""
+	}
+	start := 0
+	for i, id := range c.Objects {
+		if id < c.Objects[start] {
+			start = i
+		}
+	}
+	rotated := append(append([]string{}, c.Objects[start:]...), c.Objects[:start]...)
+	return fmt.Sprintf("%d:%s", len(rotated), strings.Join(rotated, "\x00"))
+}
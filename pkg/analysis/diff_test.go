@@ -0,0 +1,81 @@
+package analysis
+
+import "testing"
+
+func TestCompareReports(t *testing.T) {
+	before := &Report{
+		ModelHash: "before",
+		Cycles: []*Cycle{
+			{Objects: []string{"a", "b"}, Length: 2},
+			{Objects: []string{"c", "d", "e"}, Length: 3},
+		},
+		CouplingMetrics: map[string]*CouplingMetrics{
+			"a": {ObjectID: "a", AfferentCoupling: 1, EfferentCoupling: 1, Instability: 0.5},
+			"b": {ObjectID: "b", AfferentCoupling: 1, EfferentCoupling: 1, Instability: 0.5},
+			"x": {ObjectID: "x", EfferentCoupling: 2, Instability: 1},
+		},
+		DiagramComplexity:    10,
+		KolmogorovComplexity: 100,
+	}
+	after := &Report{
+		ModelHash: "after",
+		Cycles: []*Cycle{
+			{Objects: []string{"b", "a"}, Length: 2},      // The same cycle, rotated
+			{Objects: []string{"e", "d", "c"}, Length: 3}, // Reversed: a different cycle
+			{Objects: []string{"f", "g"}, Length: 2},
+		},
+		CouplingMetrics: map[string]*CouplingMetrics{
+			"a": {ObjectID: "a", AfferentCoupling: 1, EfferentCoupling: 1, Instability: 0.5},
+			"b": {ObjectID: "b", AfferentCoupling: 3, EfferentCoupling: 1, Instability: 0.25},
+			"y": {ObjectID: "y", AfferentCoupling: 1},
+		},
+		DiagramComplexity:    12,
+		KolmogorovComplexity: 90,
+	}
+
+	change := CompareReports(before, after)
+	if change.BeforeHash != "before" || change.AfterHash != "after" {
+		t.Errorf("Unexpected hashes %s %s", change.BeforeHash, change.AfterHash)
+	}
+	if len(change.NewCycles) != 2 || change.NewCycles[0].Objects[0] != "e" || change.NewCycles[1].Objects[0] != "f" {
+		t.Errorf("Expected e-d-c and f-g as new cycles, got %v", change.NewCycles)
+	}
+	if len(change.ResolvedCycles) != 1 || change.ResolvedCycles[0].Objects[0] != "c" {
+		t.Errorf("Expected c-d-e resolved, got %v", change.ResolvedCycles)
+	}
+
+	// a is unchanged; x disappeared, b gained two dependents, y appeared
+	want := []struct {
+		id    string
+		total int
+	}{{"b", 2}, {"x", -2}, {"y", 1}}
+	if len(change.CouplingDeltas) != len(want) {
+		t.Fatalf("Expected %d coupling deltas, got %d", len(want), len(change.CouplingDeltas))
+	}
+	for i, w := range want {
+		d := change.CouplingDeltas[i]
+		if d.ObjectID != w.id || d.TotalDelta() != w.total {
+			t.Errorf("Delta %d: expected %s %+d, got %s %+d", i, w.id, w.total, d.ObjectID, d.TotalDelta())
+		}
+	}
+	if b := change.CouplingDeltas[0]; b.InstabilityBefore != 0.5 || b.InstabilityAfter != 0.25 {
+		t.Errorf("Expected b's instability from 0.5 to 0.25, got %v to %v", b.InstabilityBefore, b.InstabilityAfter)
+	}
+
+	if c := change.Complexity; c.DiagramBefore != 10 || c.DiagramAfter != 12 || c.KolmogorovBefore != 100 || c.KolmogorovAfter != 90 {
+		t.Errorf("Unexpected complexity delta %+v", c)
+	}
+}
+
+func TestCompareReportsTruncatedCycles(t *testing.T) {
+	before := &Report{Cycles: []*Cycle{{Objects: []string{"a", "b"}, Length: 2}}, CyclesTruncated: true}
+	after := &Report{Cycles: []*Cycle{{Objects: []string{"c", "d"}, Length: 2}}}
+
+	change := CompareReports(before, after)
+	if !change.CyclesIncomplete {
+		t.Error("Expected the cycle delta marked incomplete when a report's cycles are truncated")
+	}
+	if change := CompareReports(after, after); change.CyclesIncomplete {
+		t.Error("Expected a complete cycle delta when no report is truncated")
+	}
+}
@@ -0,0 +1,189 @@
+package analysis_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/manu/catreview/internal/modeltest"
+	"github.com/manu/catreview/pkg/analysis"
+	"github.com/manu/catreview/pkg/category"
+	"github.com/manu/catreview/pkg/extractor"
+)
+
+// shop extracts the shop module from its absolute path.
+func shop(t *testing.T) *category.Category {
+	t.Helper()
+	cat, err := extractor.NewGoExtractor().ExtractFromPath(modeltest.Shop(t))
+	if err != nil {
+		t.Fatalf("ExtractFromPath failed: %v", err)
+	}
+	return cat
+}
+
+func TestExtractedPackageMetrics(t *testing.T) {
+	byKey := make(map[string]*analysis.PackageMetrics)
+	for _, p := range analysis.ComputePackageMetrics(shop(t), nil) {
+		byKey[p.Package] = p
+	}
+	// Both main packages stay apart, keyed by their directory
+	for _, key := range []string{"cmd/shop", "tools/gen"} {
+		if p := byKey[key]; p == nil || p.Name != "main" || p.Afferent != 0 {
+			t.Errorf("Expected main package %s, got %+v", key, p)
+		}
+	}
+	if domain := byKey["internal/domain"]; domain == nil || domain.Afferent != 2 || domain.Efferent != 2 {
+		t.Errorf("Expected internal/domain used by both mains and using infra and audit, got %+v", domain)
+	}
+	// github.com/acme/audit is not the module's audit package
+	if infra := byKey["internal/infra"]; infra == nil || infra.Efferent != 0 || infra.External != 1 {
+		t.Errorf("Expected internal/infra to depend on an external package only, got %+v", infra)
+	}
+	if audit := byKey["audit"]; audit == nil || audit.Afferent != 1 {
+		t.Errorf("Expected audit used by internal/domain only, got %+v", audit)
+	}
+}
+
+func TestExtractedImportLinks(t *testing.T) {
+	cat := shop(t)
+	linked, err := analysis.LinkImportedPackages(cat)
+	if err != nil {
+		t.Fatalf("LinkImportedPackages failed: %v", err)
+	}
+	// audit, domain and infra, each of one file
+	if linked != 3 {
+		t.Errorf("Expected 3 links, got %d", linked)
+	}
+	if _, ok := cat.GetMorphism("resolves_to:import:github.com/acme/audit->audit/audit.go"); ok {
+		t.Error("Expected the third-party audit package to stay unresolved")
+	}
+}
+
+func TestExtractedDeadCode(t *testing.T) {
+	dead, err := analysis.FindDeadCode(shop(t), analysis.DeadCodeOptions{})
+	if err != nil {
+		t.Fatalf("FindDeadCode failed: %v", err)
+	}
+	if len(dead.Functions) != 1 || dead.Functions[0].ObjectID != "domain.legacy" ||
+		dead.Functions[0].File != "internal/domain/order.go" {
+		t.Errorf("Expected domain.legacy alone to be dead, got %+v", dead.Functions)
+	}
+	if len(dead.Types) != 0 || len(dead.Files) != 0 {
+		t.Errorf("Expected no dead types or files, got %v %v", dead.Types, dead.Files)
+	}
+}
+
+func TestExtractedTestRoots(t *testing.T) {
+	ext := extractor.NewGoExtractor()
+	ext.Tests = true
+	cat, err := ext.ExtractFromPath(modeltest.Shop(t))
+	if err != nil {
+		t.Fatalf("ExtractFromPath failed: %v", err)
+	}
+
+	tests := []struct {
+		roots []string
+		want  string
+	}{
+		// TestValidate keeps its helper alive
+		{nil, "domain.legacy"},
+		{[]string{analysis.RootMain, analysis.RootExported, analysis.RootInit}, "domain.TestValidate,domain.legacy,domain.sampleOrder"},
+	}
+	for _, tt := range tests {
+		dead, err := analysis.FindDeadCode(cat, analysis.DeadCodeOptions{Roots: tt.roots})
+		if err != nil {
+			t.Fatalf("FindDeadCode failed: %v", err)
+		}
+		var ids []string
+		for _, obj := range dead.Functions {
+			ids = append(ids, obj.ObjectID)
+		}
+		if got := strings.Join(ids, ","); got != tt.want {
+			t.Errorf("Roots %v: expected dead %s, got %s", tt.roots, tt.want, got)
+		}
+	}
+}
+
+func TestExtractedImpact(t *testing.T) {
+	impact := analysis.ComputeImpact(shop(t), []string{"internal/infra/db.go"}, nil, 0)
+
+	// Importers of infra, and tools/gen through domain
+	want := "cmd/shop/main.go,internal/domain/order.go,internal/infra/db.go,tools/gen/main.go"
+	if got := strings.Join(impact.Files, ","); got != want {
+		t.Errorf("Expected files %s, got %s", want, got)
+	}
+	var mains []string
+	for _, e := range impact.EntryPoints {
+		if e.EntryPoint == "main" && e.Type == "function" {
+			mains = append(mains, e.ObjectID)
+		}
+	}
+	if strings.Join(mains, ",") != "main.main" {
+		t.Errorf("Expected main.main as the affected entry point, got %v", mains)
+	}
+}
+
+func TestExtractedCentrality(t *testing.T) {
+	top, err := analysis.ComputeCentrality(shop(t), nil).Rank("pagerank", 1)
+	if err != nil {
+		t.Fatalf("Rank failed: %v", err)
+	}
+	// Order is used by main, Place and Validate
+	if len(top) != 1 || top[0].ObjectID != "domain.Order" {
+		t.Errorf("Expected domain.Order to rank first, got %+v", top)
+	}
+}
+
+func TestExtractedCycles(t *testing.T) {
+	if components := analysis.NewCycleAnalyzer(shop(t)).StronglyConnectedComponents(); len(components) != 0 {
+		t.Errorf("Expected an acyclic module, got %+v", components)
+	}
+}
+
+func TestExtractedCommunities(t *testing.T) {
+	communities := analysis.DetectCommunities(shop(t), nil, 1)
+
+	// Packages are keyed by directory, so the two main packages are not one
+	packages := make(map[string]bool)
+	for _, m := range communities.Misplaced {
+		packages[m.Package] = true
+	}
+	for _, c := range communities.Clusters {
+		packages[c.Package] = true
+	}
+	if !packages["cmd/shop"] || !packages["tools/gen"] || packages["main"] {
+		t.Errorf("Expected packages keyed by directory, got %v", packages)
+	}
+}
+
+func TestExtractedImpactThroughModulePath(t *testing.T) {
+	// The module path does not end in the directory names, and the model is
+	// extracted from above the module root
+	root := t.TempDir()
+	files := map[string]string{
+		"shop/go.mod":          "module example.com/shop/v2\n",
+		"shop/store/store.go":  "package store\n\nfunc Save() {}\n",
+		"shop/api/api.go":      "package api\n\nimport \"example.com/shop/v2/store\"\n\nvar _ = store.Save\n",
+		"shop/cmd/app/main.go": "package main\n\nimport _ \"example.com/shop/v2/api\"\n\nfunc main() {}\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cat, err := extractor.NewGoExtractor().ExtractFromPath(root)
+	if err != nil {
+		t.Fatalf("ExtractFromPath failed: %v", err)
+	}
+
+	impact := analysis.ComputeImpact(cat, []string{"shop/store/store.go"}, nil, 0)
+	want := "shop/api/api.go,shop/cmd/app/main.go,shop/store/store.go"
+	if got := strings.Join(impact.Files, ","); got != want {
+		t.Errorf("Expected files %s, got %s", want, got)
+	}
+}
@@ -0,0 +1,134 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/manu/catreview/pkg/category"
+)
+
+// EdgeFilter selects the morphisms an analysis treats as dependencies and
+// weighs them by type, so that questions such as "package import cycles"
+// and "call cycles" can be asked separately.
+//
+// A nil filter selects every structural morphism (see IsStructural) with
+// weight 1. Identities are never selected.
+type EdgeFilter struct {
+	Include []string           // Types to select; empty selects all structural types
+	Exclude []string           // Types never to select
+	Weights map[string]float64 // Weight per type; missing types weigh 1
+}
+
+// ParseEdgeFilter builds a filter from comma-separated type lists and
+// "type=weight" pairs, as given on the command line. It returns nil when
+// all arguments are empty.
+func ParseEdgeFilter(include, exclude string, weights map[string]string) (*EdgeFilter, error) {
+	f := &EdgeFilter{
+		Include: splitTypes(include),
+		Exclude: splitTypes(exclude),
+	}
+	for typ, value := range weights {
+		w, err := strconv.ParseFloat(value, 64)
+		if err != nil || w < 0 {
+			return nil, fmt.Errorf("invalid weight %q for edge type %s", value, typ)
+		}
+		if f.Weights == nil {
+			f.Weights = make(map[string]float64)
+		}
+		f.Weights[typ] = w
+	}
+
+	if len(f.Include) == 0 && len(f.Exclude) == 0 && len(f.Weights) == 0 {
+		return nil, nil
+	}
+	return f, nil
+}
+
+// splitTypes splits a comma-separated list of morphism types.
+func splitTypes(list string) []string {
+	var types []string
+	for _, typ := range strings.Split(list, ",") {
+		if typ = strings.TrimSpace(typ); typ != "" {
+			types = append(types, typ)
+		}
+	}
+	return types
+}
+
+// Allows reports whether the filter selects a morphism.
+func (f *EdgeFilter) Allows(m *category.Morphism) bool {
+	if m.Type == "identity" {
+		return false
+	}
+	if f == nil {
+		return IsStructural(m)
+	}
+	if len(f.Include) > 0 {
+		if !containsType(f.Include, m.Type) {
+			return false
+		}
+	} else if !IsStructural(m) {
+		return false
+	}
+	return !containsType(f.Exclude, m.Type)
+}
+
+// Weight returns the weight of a selected morphism.
+func (f *EdgeFilter) Weight(m *category.Morphism) float64 {
+	if f == nil {
+		return 1
+	}
+	if w, ok := f.Weights[m.Type]; ok {
+		return w
+	}
+	return 1
+}
+
+// Weighted reports whether the filter assigns any weight other than 1.
+func (f *EdgeFilter) Weighted() bool {
+	if f == nil {
+		return false
+	}
+	for _, w := range f.Weights {
+		if w != 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// String describes the filter, e.g. "import,type_dependency -defines".
+func (f *EdgeFilter) String() string {
+	if f == nil {
+		return "all structural"
+	}
+	var parts []string
+	if len(f.Include) > 0 {
+		parts = append(parts, strings.Join(f.Include, ","))
+	} else {
+		parts = append(parts, "all structural")
+	}
+	for _, typ := range f.Exclude {
+		parts = append(parts, "-"+typ)
+	}
+	types := make([]string, 0, len(f.Weights))
+	for typ := range f.Weights {
+		types = append(types, typ)
+	}
+	sort.Strings(types)
+	for _, typ := range types {
+		parts = append(parts, fmt.Sprintf("%s=%g", typ, f.Weights[typ]))
+	}
+	return strings.Join(parts, " ")
+}
+
+func containsType(types []string, typ string) bool {
+	for _, t := range types {
+		if t == typ {
+			return true
+		}
+	}
+	return false
+}
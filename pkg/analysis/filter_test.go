@@ -0,0 +1,74 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/manu/catreview/internal/modeltest"
+	"github.com/manu/catreview/pkg/category"
+)
+
+func TestEdgeFilter(t *testing.T) {
+	imp := category.NewMorphism("m1", "a", "b", "import", nil)
+	call := category.NewMorphism("m2", "a", "b", "function_call", nil)
+	defines := category.NewMorphism("m3", "a", "b", "defines", nil)
+	cochange := category.NewMorphism("m4", "a", "b", "co_changes_with", nil)
+	identity := category.NewMorphism("id_a", "a", "a", "identity", nil)
+
+	var all *EdgeFilter
+	if !all.Allows(imp) || !all.Allows(defines) || all.Allows(cochange) || all.Allows(identity) {
+		t.Error("Expected nil filter to select exactly the structural morphisms")
+	}
+
+	f, err := ParseEdgeFilter("import, co_changes_with", "", map[string]string{"import": "2.5"})
+	if err != nil {
+		t.Fatalf("ParseEdgeFilter failed: %v", err)
+	}
+	if !f.Allows(imp) || f.Allows(call) || !f.Allows(cochange) {
+		t.Error("Expected --edges to select only the listed types")
+	}
+	if f.Weight(imp) != 2.5 || f.Weight(call) != 1 || !f.Weighted() {
+		t.Errorf("Expected weights 2.5 and 1, got %v and %v", f.Weight(imp), f.Weight(call))
+	}
+
+	f, _ = ParseEdgeFilter("", "defines", nil)
+	if f.Allows(defines) || !f.Allows(call) {
+		t.Error("Expected --exclude-edges to drop only the listed types")
+	}
+
+	if f, _ := ParseEdgeFilter("", "", nil); f != nil {
+		t.Error("Expected nil filter without arguments")
+	}
+	if _, err := ParseEdgeFilter("", "", map[string]string{"import": "heavy"}); err == nil {
+		t.Error("Expected error for an invalid weight")
+	}
+}
+
+func TestCycleAnalyzerEdges(t *testing.T) {
+	// a imports b, and b only calls a
+	cat := graphFixture(t, []string{"a", "b"}, [][2]string{{"a", "b"}})
+	modeltest.Add(t, cat, nil, []*category.Morphism{category.NewMorphism("call", "b", "a", "function_call", nil)})
+
+	analyzer := NewCycleAnalyzer(cat)
+	if len(analyzer.StronglyConnectedComponents()) != 1 {
+		t.Error("Expected a cycle over all structural morphisms")
+	}
+	analyzer.Edges = &EdgeFilter{Include: []string{"import"}}
+	if len(analyzer.StronglyConnectedComponents()) != 0 {
+		t.Error("Expected no import cycle")
+	}
+}
+
+func TestWeightedCoupling(t *testing.T) {
+	cat := graphFixture(t, []string{"a", "b", "c"}, [][2]string{{"a", "b"}})
+	modeltest.Add(t, cat, nil, []*category.Morphism{category.NewMorphism("call", "c", "a", "function_call", nil)})
+
+	analyzer := NewComplexityAnalyzer(cat)
+	analyzer.Edges = &EdgeFilter{Weights: map[string]float64{"function_call": 3}}
+	a := analyzer.ComputeCoupling()["a"]
+	if a.AfferentCoupling != 1 || a.AfferentWeight != 3 || a.EfferentWeight != 1 {
+		t.Errorf("Expected Ca=1 weighing 3 and Ce weighing 1, got %+v", a)
+	}
+	if a.Instability != 0.25 {
+		t.Errorf("Expected weighted instability 0.25, got %v", a.Instability)
+	}
+}
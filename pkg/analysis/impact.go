@@ -0,0 +1,201 @@
+package analysis
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/manu/catreview/pkg/category"
+)
+
+// Entry point kinds.
+const (
+	EntryPointMain     = "main"     // A main package or its main function
+	EntryPointExported = "exported" // Exported API of a library package
+)
+
+// AffectedObject is an object a change may break.
+type AffectedObject struct {
+	ObjectID   string `json:"object_id"`
+	Type       string `json:"type"`
+	Distance   int    `json:"distance"`              // Dependencies between it and the nearest change; 0 for changed objects
+	Via        string `json:"via,omitempty"`         // Next object on a shortest path to the change
+	EntryPoint string `json:"entry_point,omitempty"` // Entry point kind, if it is one
+}
+
+// Impact is the reverse transitive closure of a change: every object that
+// depends, directly or transitively, on a changed file.
+type Impact struct {
+	Changed     []string          `json:"changed"`      // Changed files found in the model
+	Affected    []*AffectedObject `json:"affected"`     // Nearest first, then by ID
+	EntryPoints []*AffectedObject `json:"entry_points"` // Affected main packages and exported API
+	Files       []string          `json:"files"`        // Affected files, including the changed ones
+}
+
+// ComputeImpact finds the objects of cat affected by changes to the given
+// files, following the morphisms selected by edges backwards up to maxDepth
+// dependencies (0: no limit).
+//
+// A changed file changes the objects it declares. An affected file also
+// affects, at the same distance, the package it belongs to as seen by
+// other packages: the imported packages resolving to it (see
+// ImportResolver). Files not in the model are ignored.
+func ComputeImpact(cat *category.Category, files []string, edges *EdgeFilter, maxDepth int) *Impact {
+	impact := &Impact{
+		Changed:     []string{},
+		Affected:    []*AffectedObject{},
+		EntryPoints: []*AffectedObject{},
+		Files:       []string{},
+	}
+
+	changed := make(map[string]bool)
+	for _, file := range files {
+		if obj, ok := cat.GetObject(file); ok && obj.Type == "file" && !changed[file] {
+			changed[file] = true
+			impact.Changed = append(impact.Changed, file)
+		}
+	}
+	sort.Strings(impact.Changed)
+
+	// Dependents of each object, and the imported packages containing each
+	// file: a change to the file is a change to the package
+	dependents := make(map[string][]string)
+	for _, morph := range cat.Morphisms() {
+		if edges.Allows(morph) && morph.Source != morph.Target {
+			dependents[morph.Target] = append(dependents[morph.Target], morph.Source)
+		}
+	}
+	for _, sources := range dependents {
+		sort.Strings(sources)
+	}
+	packages := make(map[string][]string)
+	resolver := NewImportResolver(cat)
+	for _, obj := range cat.Objects() {
+		if importPath, ok := obj.Metadata["import_path"].(string); ok && obj.Type == "imported_package" {
+			for _, file := range resolver.Resolve(importPath) {
+				packages[file] = append(packages[file], obj.ID)
+			}
+		}
+	}
+
+	// Seed the search with the changed files and the objects they declare
+	affected := make(map[string]*AffectedObject)
+	var queue []string
+	for _, obj := range cat.Objects() {
+		file, _ := obj.Metadata["file"].(string)
+		if changed[obj.ID] || changed[file] {
+			affected[obj.ID] = &AffectedObject{ObjectID: obj.ID, Type: obj.Type}
+			queue = append(queue, obj.ID)
+		}
+	}
+
+	// Breadth-first search against the direction of dependencies, where
+	// reaching a file's package costs nothing (0-1 BFS)
+	done := make(map[string]bool)
+	reach := func(id, via string, distance int) bool {
+		if a, seen := affected[id]; seen && a.Distance <= distance {
+			return false
+		}
+		obj, _ := cat.GetObject(id)
+		affected[id] = &AffectedObject{ObjectID: id, Type: obj.Type, Distance: distance, Via: via}
+		return true
+	}
+	var front []string // Reached at no cost: the head of the deque
+	for len(front) > 0 || len(queue) > 0 {
+		var id string
+		if len(front) > 0 {
+			id, front = front[len(front)-1], front[:len(front)-1]
+		} else {
+			id, queue = queue[0], queue[1:]
+		}
+		if done[id] {
+			continue
+		}
+		done[id] = true
+		distance := affected[id].Distance
+
+		for _, pkg := range packages[id] {
+			if reach(pkg, id, distance) {
+				front = append(front, pkg)
+			}
+		}
+		if maxDepth > 0 && distance >= maxDepth {
+			continue
+		}
+		for _, source := range dependents[id] {
+			if reach(source, id, distance+1) {
+				queue = append(queue, source)
+			}
+		}
+	}
+
+	var affectedFiles []string
+	for id, a := range affected {
+		obj, _ := cat.GetObject(id)
+		a.EntryPoint = entryPointKind(obj)
+		impact.Affected = append(impact.Affected, a)
+		if a.EntryPoint != "" {
+			impact.EntryPoints = append(impact.EntryPoints, a)
+		}
+		if obj.Type == "file" {
+			affectedFiles = append(affectedFiles, id)
+		} else if file, ok := obj.Metadata["file"].(string); ok && file != "" {
+			affectedFiles = append(affectedFiles, file)
+		}
+	}
+	for _, list := range [][]*AffectedObject{impact.Affected, impact.EntryPoints} {
+		sort.Slice(list, func(i, j int) bool {
+			if list[i].Distance != list[j].Distance {
+				return list[i].Distance < list[j].Distance
+			}
+			return list[i].ObjectID < list[j].ObjectID
+		})
+	}
+	sort.Strings(affectedFiles)
+	for i, file := range affectedFiles {
+		if i == 0 || file != affectedFiles[i-1] {
+			impact.Files = append(impact.Files, file)
+		}
+	}
+
+	return impact
+}
+
+// entryPointKind classifies an object as an entry point: files and main
+// functions of main packages, and exported functions and types of other
+// packages. It returns "" for other objects.
+func entryPointKind(obj *category.Object) string {
+	pkg, _ := obj.Metadata["package"].(string)
+	if pkg == "main" {
+		if obj.Type == "file" || (obj.Type == "function" && obj.Name == "main") {
+			return EntryPointMain
+		}
+		return ""
+	}
+	if pkg == "" {
+		return ""
+	}
+
+	switch obj.Type {
+	case "function":
+		exported, _ := obj.Metadata["is_exported"].(bool)
+		// Methods ("pkg.Recv.Name") are API only on exported receivers
+		if recv, _, isMethod := strings.Cut(strings.TrimPrefix(obj.ID, pkg+"."), "."); isMethod {
+			exported = exported && isExportedName(strings.TrimLeft(recv, "*"))
+		}
+		if exported {
+			return EntryPointExported
+		}
+	case "struct", "interface", "type":
+		if isExportedName(obj.Name) {
+			return EntryPointExported
+		}
+	}
+	return ""
+}
+
+// isExportedName reports whether a Go identifier is exported.
+func isExportedName(name string) bool {
+	r := []rune(name)
+	return len(r) > 0 && unicode.IsUpper(r[0])
+}
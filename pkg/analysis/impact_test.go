@@ -0,0 +1,147 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/manu/catreview/internal/modeltest"
+	"github.com/manu/catreview/pkg/category"
+)
+
+// impactFixture models a store package used by an api package, which a
+// main package imports:
+//
+//	cmd/app/main.go --import--> api --import--> store
+//	api.Handle --function_call--> store.Save
+func impactFixture(t *testing.T) *category.Category {
+	objects := []*category.Object{
+		category.NewObject("store/store.go", "file", "store.go", map[string]interface{}{"package": "store", "import_path": "example.com/store"}),
+		category.NewObject("store.Save", "function", "Save", map[string]interface{}{"package": "store", "file": "store/store.go", "is_exported": true}),
+		category.NewObject("store.helper", "function", "helper", map[string]interface{}{"package": "store", "file": "store/store.go", "is_exported": false}),
+		category.NewObject("api/api.go", "file", "api.go", map[string]interface{}{"package": "api", "import_path": "example.com/api"}),
+		category.NewObject("api.Handle", "function", "Handle", map[string]interface{}{"package": "api", "file": "api/api.go", "is_exported": true}),
+		category.NewObject("api.*server.Serve", "function", "Serve", map[string]interface{}{"package": "api", "file": "api/api.go", "is_exported": true}),
+		category.NewObject("cmd/app/main.go", "file", "main.go", map[string]interface{}{"package": "main", "import_path": "example.com/cmd/app"}),
+		category.NewObject("main.main", "function", "main", map[string]interface{}{"package": "main", "file": "cmd/app/main.go"}),
+		category.NewObject("other/other.go", "file", "other.go", map[string]interface{}{"package": "other", "import_path": "example.com/other"}),
+		category.NewObject("import:example.com/store", "imported_package", "example.com/store", map[string]interface{}{"import_path": "example.com/store"}),
+		category.NewObject("import:example.com/api", "imported_package", "example.com/api", map[string]interface{}{"import_path": "example.com/api"}),
+	}
+
+	morphisms := []*category.Morphism{
+		category.NewMorphism("d1", "store/store.go", "store.Save", "defines", nil),
+		category.NewMorphism("d2", "api/api.go", "api.Handle", "defines", nil),
+		category.NewMorphism("d3", "api/api.go", "api.*server.Serve", "defines", nil),
+		category.NewMorphism("i1", "api/api.go", "import:example.com/store", "import", nil),
+		category.NewMorphism("i2", "cmd/app/main.go", "import:example.com/api", "import", nil),
+		category.NewMorphism("c1", "api.Handle", "store.Save", "function_call", nil),
+		category.NewMorphism("x1", "other/other.go", "store/store.go", "co_changes_with", nil),
+	}
+	return modeltest.Build(t, "impact", objects, morphisms)
+}
+
+func TestComputeImpact(t *testing.T) {
+	impact := ComputeImpact(impactFixture(t), []string{"store/store.go", "missing.go"}, nil, 0)
+
+	if len(impact.Changed) != 1 || impact.Changed[0] != "store/store.go" {
+		t.Errorf("Expected only store/store.go changed, got %v", impact.Changed)
+	}
+
+	distances := make(map[string]int)
+	via := make(map[string]string)
+	for _, a := range impact.Affected {
+		distances[a.ObjectID] = a.Distance
+		via[a.ObjectID] = a.Via
+	}
+	want := map[string]int{
+		"store/store.go":           0,
+		"store.Save":               0,
+		"store.helper":             0,
+		"import:example.com/store": 0, // The package of the changed file
+		"api/api.go":               1,
+		"api.Handle":               1,
+		"import:example.com/api":   1, // The package of api.go
+		"cmd/app/main.go":          2,
+	}
+	for id, d := range want {
+		got, ok := distances[id]
+		if !ok || got != d {
+			t.Errorf("%s: expected distance %d, got %d (affected: %v)", id, d, got, ok)
+		}
+	}
+	if len(distances) != len(want) {
+		t.Errorf("Expected %d affected objects, got %v", len(want), distances)
+	}
+	if via["api.Handle"] != "store.Save" {
+		t.Errorf("Expected api.Handle affected via store.Save, got %s", via["api.Handle"])
+	}
+	if _, ok := distances["other/other.go"]; ok {
+		t.Error("Co-changes should not propagate impact by default")
+	}
+
+	// Sorted by distance, then ID
+	for i := 1; i < len(impact.Affected); i++ {
+		a, b := impact.Affected[i-1], impact.Affected[i]
+		if a.Distance > b.Distance || (a.Distance == b.Distance && a.ObjectID > b.ObjectID) {
+			t.Errorf("Affected objects out of order: %s before %s", a.ObjectID, b.ObjectID)
+		}
+	}
+
+	entryPoints := make(map[string]string)
+	for _, a := range impact.EntryPoints {
+		entryPoints[a.ObjectID] = a.EntryPoint
+	}
+	if entryPoints["store.Save"] != EntryPointExported || entryPoints["api.Handle"] != EntryPointExported ||
+		entryPoints["cmd/app/main.go"] != EntryPointMain {
+		t.Errorf("Expected store.Save, api.Handle and cmd/app/main.go as entry points, got %v", entryPoints)
+	}
+	if _, ok := entryPoints["store.helper"]; ok {
+		t.Error("Unexported function should not be an entry point")
+	}
+
+	wantFiles := []string{"api/api.go", "cmd/app/main.go", "store/store.go"}
+	if len(impact.Files) != len(wantFiles) {
+		t.Fatalf("Expected files %v, got %v", wantFiles, impact.Files)
+	}
+	for i, f := range wantFiles {
+		if impact.Files[i] != f {
+			t.Errorf("Expected files %v, got %v", wantFiles, impact.Files)
+		}
+	}
+}
+
+func TestComputeImpactLimits(t *testing.T) {
+	// Only direct dependents
+	impact := ComputeImpact(impactFixture(t), []string{"store/store.go"}, nil, 1)
+	for _, a := range impact.Affected {
+		if a.Distance > 1 {
+			t.Errorf("Expected distance at most 1, got %s at %d", a.ObjectID, a.Distance)
+		}
+	}
+
+	// Following imports only, the call from api.Handle is ignored
+	impact = ComputeImpact(impactFixture(t), []string{"store/store.go"}, &EdgeFilter{Include: []string{"import"}}, 0)
+	for _, a := range impact.Affected {
+		if a.ObjectID == "api.Handle" {
+			t.Error("Expected api.Handle unaffected when following imports only")
+		}
+	}
+}
+
+func TestEntryPointKind(t *testing.T) {
+	tests := []struct {
+		obj  *category.Object
+		want string
+	}{
+		{category.NewObject("api.*server.Serve", "function", "Serve", map[string]interface{}{"package": "api", "is_exported": true}), ""},
+		{category.NewObject("api.*Server.Serve", "function", "Serve", map[string]interface{}{"package": "api", "is_exported": true}), EntryPointExported},
+		{category.NewObject("api.Config", "struct", "Config", map[string]interface{}{"package": "api"}), EntryPointExported},
+		{category.NewObject("api.config", "struct", "config", map[string]interface{}{"package": "api"}), ""},
+		{category.NewObject("main.run", "function", "run", map[string]interface{}{"package": "main"}), ""},
+		{category.NewObject("import:fmt", "imported_package", "fmt", nil), ""},
+	}
+	for _, tt := range tests {
+		if got := entryPointKind(tt.obj); got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.obj.ID, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package analysis
+
+import (
+	"fmt"
+
+	"github.com/manu/catreview/pkg/category"
+)
+
+// Location is a position in the source code. Line is 0 when unknown.
+type Location struct {
+	File string `json:"file"`
+	Line int    `json:"line,omitempty"`
+}
+
+// String formats the location as "file:line", or "file" without a line.
+func (l Location) String() string {
+	if l.Line > 0 {
+		return fmt.Sprintf("%s:%d", l.File, l.Line)
+	}
+	return l.File
+}
+
+// ObjectLocation returns where an object is declared: the file of a file
+// object, or the "file" and "line" of a declaration. Objects outside the
+// source, such as imported packages, have no location.
+func ObjectLocation(obj *category.Object) (Location, bool) {
+	if obj.Type == "file" {
+		return Location{File: obj.ID}, true
+	}
+	file, _ := obj.Metadata["file"].(string)
+	if file == "" {
+		return Location{}, false
+	}
+	return Location{File: file, Line: metadataInt(obj.Metadata, "line")}, true
+}
+
+// MorphismLocation returns where a morphism originates: the "line" it was
+// extracted from, in the file of its source object, or the location of the
+// source object itself.
+func MorphismLocation(cat *category.Category, m *category.Morphism) (Location, bool) {
+	source, ok := cat.GetObject(m.Source)
+	if !ok {
+		return Location{}, false
+	}
+	loc, ok := ObjectLocation(source)
+	if !ok {
+		return Location{}, false
+	}
+	if line := metadataInt(m.Metadata, "line"); line > 0 {
+		loc.Line = line
+	}
+	return loc, true
+}
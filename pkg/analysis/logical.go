@@ -0,0 +1,323 @@
+package analysis
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/manu/catreview/pkg/category"
+)
+
+// IsStructural reports whether a morphism describes a structural relationship
+// derived from source code. Identity morphisms and logical coupling from
+// version-control history ("co_changes_with") are not structural.
+func IsStructural(m *category.Morphism) bool {
+	return m.Type != "identity" && m.Type != "co_changes_with"
+}
+
+// DefaultMinCoChanges is the default minimum number of shared commits for
+// two files to be logically coupled, both when co-change morphisms are
+// extracted and when hidden dependencies are reported.
+const DefaultMinCoChanges = 2
+
+// HiddenDependency is a pair of files that frequently change together
+// without any structural relationship between them.
+type HiddenDependency struct {
+	FileA      string  `json:"file_a"`
+	FileB      string  `json:"file_b"`
+	CoChanges  int     `json:"co_changes"`
+	Confidence float64 `json:"confidence"` // co_changes / min(commits(A), commits(B))
+}
+
+// LogicalCouplingAnalyzer compares logical coupling (co-change history) with
+// structural coupling (imports, calls, type references).
+type LogicalCouplingAnalyzer struct {
+	cat *category.Category
+
+	// MinCoChanges is the minimum number of shared commits to report a pair.
+	MinCoChanges int
+	// MinConfidence is the minimum co-change confidence to report a pair.
+	MinConfidence float64
+}
+
+// NewLogicalCouplingAnalyzer creates a new logical coupling analyzer.
+func NewLogicalCouplingAnalyzer(cat *category.Category) *LogicalCouplingAnalyzer {
+	return &LogicalCouplingAnalyzer{
+		cat:           cat,
+		MinCoChanges:  DefaultMinCoChanges,
+		MinConfidence: 0.5,
+	}
+}
+
+// FindHiddenDependencies returns file pairs that are logically coupled but not
+// structurally coupled, strongest first.
+//
+// Two files are structurally coupled when a structural morphism connects
+// objects defined in them, when one imports the package the other belongs to,
+// or when they belong to the same package directory.
+func (a *LogicalCouplingAnalyzer) FindHiddenDependencies() []*HiddenDependency {
+	structural := a.structuralPairs()
+	var hidden []*HiddenDependency
+
+	for _, morph := range a.cat.Morphisms() {
+		if morph.Type != "co_changes_with" {
+			continue
+		}
+		weight := metadataInt(morph.Metadata, "weight")
+		if weight < a.MinCoChanges {
+			continue
+		}
+		if structural[filePair(morph.Source, morph.Target)] {
+			continue
+		}
+
+		confidence := 0.0
+		minCommits := minInt(a.commits(morph.Source), a.commits(morph.Target))
+		if minCommits > 0 {
+			confidence = float64(weight) / float64(minCommits)
+		}
+		if confidence < a.MinConfidence {
+			continue
+		}
+
+		hidden = append(hidden, &HiddenDependency{
+			FileA:      morph.Source,
+			FileB:      morph.Target,
+			CoChanges:  weight,
+			Confidence: confidence,
+		})
+	}
+
+	sort.Slice(hidden, func(i, j int) bool {
+		if hidden[i].CoChanges != hidden[j].CoChanges {
+			return hidden[i].CoChanges > hidden[j].CoChanges
+		}
+		if hidden[i].FileA != hidden[j].FileA {
+			return hidden[i].FileA < hidden[j].FileA
+		}
+		return hidden[i].FileB < hidden[j].FileB
+	})
+
+	return hidden
+}
+
+// structuralPairs collects all file pairs connected by structural relationships.
+func (a *LogicalCouplingAnalyzer) structuralPairs() map[[2]string]bool {
+	pairs := make(map[[2]string]bool)
+	resolver := NewImportResolver(a.cat)
+
+	// Files in the same package directory share a namespace
+	byDir := make(map[string][]string)
+	for _, obj := range a.cat.Objects() {
+		if obj.Type == "file" {
+			dir := filepath.Dir(obj.ID)
+			byDir[dir] = append(byDir[dir], obj.ID)
+		}
+	}
+	for _, files := range byDir {
+		for i := 0; i < len(files); i++ {
+			for j := i + 1; j < len(files); j++ {
+				pairs[filePair(files[i], files[j])] = true
+			}
+		}
+	}
+
+	for _, morph := range a.cat.Morphisms() {
+		if !IsStructural(morph) {
+			continue
+		}
+		source := owningFile(a.cat, morph.Source)
+		if source == "" {
+			continue
+		}
+
+		// Imports link the importing file with every file of the imported package
+		if target, ok := a.cat.GetObject(morph.Target); ok && target.Type == "imported_package" {
+			importPath, _ := target.Metadata["import_path"].(string)
+			for _, file := range resolver.Resolve(importPath) {
+				if file != source {
+					pairs[filePair(source, file)] = true
+				}
+			}
+			continue
+		}
+
+		target := owningFile(a.cat, morph.Target)
+		if target != "" && target != source {
+			pairs[filePair(source, target)] = true
+		}
+	}
+
+	return pairs
+}
+
+// commits returns the churn commit count recorded on a file object.
+func (a *LogicalCouplingAnalyzer) commits(id string) int {
+	obj, ok := a.cat.GetObject(id)
+	if !ok {
+		return 0
+	}
+	return metadataInt(obj.Metadata, "commits")
+}
+
+// ImportResolver resolves import paths to the file objects of the package
+// they refer to. Files recording the "import_path" of their package, as
+// extracted within a Go module, resolve exactly; other files by matching
+// the import path against their directories.
+type ImportResolver struct {
+	packages map[string][]string // Recorded import path -> file IDs
+	dirs     map[string][]string // Slash-separated directory -> file IDs without an import path
+}
+
+// NewImportResolver indexes the file objects of a category by import path
+// or directory.
+func NewImportResolver(cat *category.Category) *ImportResolver {
+	r := &ImportResolver{packages: make(map[string][]string), dirs: make(map[string][]string)}
+	for _, obj := range cat.Objects() {
+		if obj.Type != "file" {
+			continue
+		}
+		if importPath, ok := obj.Metadata["import_path"].(string); ok && importPath != "" {
+			r.packages[importPath] = append(r.packages[importPath], obj.ID)
+			continue
+		}
+		dir := filepath.ToSlash(filepath.Dir(obj.ID))
+		r.dirs[dir] = append(r.dirs[dir], obj.ID)
+	}
+	return r
+}
+
+// Resolve returns the file IDs of the package importPath refers to, or nil
+// if it is not in the category.
+//
+// Without a recorded import path, a directory matches when its trailing
+// path segments equal the trailing segments of the import path, and the
+// directory sharing the most wins. At least two segments must match, so
+// that a directory "store" does not claim every import ending in /store,
+// such as a third-party one. An import path of a single segment, such as a
+// standard library package, only matches a directory of that name at the
+// root, so that "errors" does not resolve to a local pkg/errors.
+func (r *ImportResolver) Resolve(importPath string) []string {
+	if importPath == "" {
+		return nil
+	}
+	if files, ok := r.packages[importPath]; ok {
+		return files
+	}
+	importSegs := strings.Split(importPath, "/")
+
+	bestDir := ""
+	bestScore := 0
+	for dir := range r.dirs {
+		dirSegs := strings.Split(path.Clean(dir), "/")
+		score := commonSuffix(importSegs, dirSegs)
+		required := minInt(2, len(importSegs))
+		if len(importSegs) == 1 {
+			required = len(dirSegs)
+		}
+		if score < required {
+			continue
+		}
+		if score > bestScore || (score == bestScore && dir < bestDir) {
+			bestDir = dir
+			bestScore = score
+		}
+	}
+	if bestScore == 0 {
+		return nil
+	}
+	return r.dirs[bestDir]
+}
+
+// LinkImportedPackages adds a "resolves_to" morphism from every
+// imported_package object to each file of the package it resolves to.
+// In a merged model this connects the imports of one model to the concrete
+// package files extracted in another. It returns the number of morphisms
+// added; existing links are kept.
+func LinkImportedPackages(cat *category.Category) (int, error) {
+	resolver := NewImportResolver(cat)
+	linked := 0
+
+	for _, obj := range cat.Objects() {
+		if obj.Type != "imported_package" {
+			continue
+		}
+		importPath, ok := obj.Metadata["import_path"].(string)
+		if !ok {
+			importPath = obj.Name
+		}
+
+		for _, file := range resolver.Resolve(importPath) {
+			id := fmt.Sprintf("resolves_to:%s->%s", obj.ID, file)
+			if _, exists := cat.GetMorphism(id); exists {
+				continue
+			}
+			m := category.NewMorphism(id, obj.ID, file, "resolves_to", map[string]interface{}{
+				"import_path": importPath,
+			})
+			if err := cat.AddMorphism(m); err != nil {
+				return linked, fmt.Errorf("failed to link %s: %v", obj.ID, err)
+			}
+			linked++
+		}
+	}
+
+	return linked, nil
+}
+
+// commonSuffix counts how many trailing segments two paths share.
+func commonSuffix(a, b []string) int {
+	n := 0
+	for i, j := len(a)-1, len(b)-1; i >= 0 && j >= 0; i, j = i-1, j-1 {
+		if a[i] != b[j] || a[i] == "." || a[i] == "" {
+			break
+		}
+		n++
+	}
+	return n
+}
+
+// owningFile returns the ID of the file object an object is defined in.
+func owningFile(cat *category.Category, id string) string {
+	obj, ok := cat.GetObject(id)
+	if !ok {
+		return ""
+	}
+	if obj.Type == "file" {
+		return obj.ID
+	}
+	if file, ok := obj.Metadata["file"].(string); ok {
+		return file
+	}
+	return ""
+}
+
+// filePair returns an order-independent key for two files.
+func filePair(a, b string) [2]string {
+	if a > b {
+		a, b = b, a
+	}
+	return [2]string{a, b}
+}
+
+// metadataInt reads an integer metadata value, accepting both native ints
+// and the float64 values produced by decoding JSON.
+func metadataInt(metadata map[string]interface{}, key string) int {
+	switch v := metadata[key].(type) {
+	case int:
+		return v
+	case float64:
+		return int(v)
+	default:
+		return 0
+	}
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
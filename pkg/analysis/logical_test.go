@@ -0,0 +1,146 @@
+package analysis
+
+import (
+	"path"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/manu/catreview/internal/modeltest"
+	"github.com/manu/catreview/pkg/category"
+)
+
+// logicalFixture models three packages of example.com/app:
+//
+//	api/handler.go imports example.com/app/store
+//	store/db.go, store/cache.go
+//	billing/invoice.go, only coupled through history
+func logicalFixture(t *testing.T) *category.Category {
+	files := []struct {
+		path    string
+		commits int
+	}{
+		{"api/handler.go", 5},
+		{"store/db.go", 4},
+		{"store/cache.go", 4},
+		{"billing/invoice.go", 3},
+	}
+	var objects []*category.Object
+	for _, f := range files {
+		objects = append(objects, category.NewObject(f.path, "file", f.path, map[string]interface{}{
+			"commits": f.commits, "import_path": "example.com/app/" + path.Dir(f.path),
+		}))
+	}
+	objects = append(objects, category.NewObject("import:example.com/app/store", "imported_package", "example.com/app/store",
+		map[string]interface{}{"import_path": "example.com/app/store"}))
+	morphisms := []*category.Morphism{
+		category.NewMorphism("import:api/handler.go->example.com/app/store",
+			"api/handler.go", "import:example.com/app/store", "import", nil),
+	}
+
+	coChanges := []struct {
+		a, b   string
+		weight int
+	}{
+		{"api/handler.go", "store/db.go", 4},        // Structural: import
+		{"store/cache.go", "store/db.go", 3},        // Structural: same package
+		{"api/handler.go", "billing/invoice.go", 3}, // Hidden
+		{"billing/invoice.go", "store/cache.go", 1}, // Below MinCoChanges
+	}
+	for _, c := range coChanges {
+		morphisms = append(morphisms, category.NewMorphism("co_change:"+c.a+"->"+c.b, c.a, c.b, "co_changes_with",
+			map[string]interface{}{"weight": float64(c.weight)}))
+	}
+	return modeltest.Build(t, "logical", objects, morphisms)
+}
+
+func TestFindHiddenDependencies(t *testing.T) {
+	hidden := NewLogicalCouplingAnalyzer(logicalFixture(t)).FindHiddenDependencies()
+	if len(hidden) != 1 {
+		t.Fatalf("Expected 1 hidden dependency, got %d", len(hidden))
+	}
+	h := hidden[0]
+	if h.FileA != "api/handler.go" || h.FileB != "billing/invoice.go" || h.CoChanges != 3 || h.Confidence != 1 {
+		t.Errorf("Unexpected hidden dependency %+v", h)
+	}
+
+	analyzer := NewLogicalCouplingAnalyzer(logicalFixture(t))
+	analyzer.MinCoChanges = 1
+	analyzer.MinConfidence = 0.5
+	if hidden := analyzer.FindHiddenDependencies(); len(hidden) != 1 {
+		t.Errorf("Expected the weak pair to stay below MinConfidence, got %d pairs", len(hidden))
+	}
+	analyzer.MinConfidence = 0
+	if hidden := analyzer.FindHiddenDependencies(); len(hidden) != 2 || hidden[1].CoChanges != 1 {
+		t.Errorf("Expected both pairs, strongest first, got %v", hidden)
+	}
+}
+
+func TestImportResolver(t *testing.T) {
+	var objects []*category.Object
+	for _, file := range []string{"pkg/store/db.go", "pkg/store/cache.go", "internal/store/db.go", "util/util.go"} {
+		objects = append(objects, category.NewObject(file, "file", file, nil))
+	}
+	// Files extracted within a module record the import path of their package
+	for _, file := range []string{"store/db.go", "mod/store/db.go"} {
+		objects = append(objects, category.NewObject("lib/"+file, "file", file,
+			map[string]interface{}{"import_path": "example.com/lib/" + path.Dir(file)}))
+	}
+	r := NewImportResolver(modeltest.Build(t, "resolve", objects, nil))
+
+	tests := []struct {
+		importPath string
+		want       string
+	}{
+		{"example.com/app/pkg/store", "pkg/store/cache.go,pkg/store/db.go"},
+		{"example.com/app/internal/store", "internal/store/db.go"},
+		{"example.com/app/util", ""},    // One shared segment is not enough
+		{"example.com/other/store", ""}, // Nor for a third-party package
+		{"util", "util/util.go"},
+		{"store", ""}, // A single segment matches root directories only
+		{"database/sql", ""},
+		{"", ""},
+		{"example.com/lib/store", "lib/store/db.go"},
+		{"example.com/lib/mod/store", "lib/mod/store/db.go"},
+		{"example.com/lib", ""}, // Recorded import paths match exactly
+	}
+	for _, tt := range tests {
+		files := append([]string{}, r.Resolve(tt.importPath)...)
+		sort.Strings(files)
+		if got := strings.Join(files, ","); got != tt.want {
+			t.Errorf("Resolve(%q) = %q, want %q", tt.importPath, got, tt.want)
+		}
+	}
+}
+
+func TestLinkImportedPackages(t *testing.T) {
+	var objects []*category.Object
+	for _, file := range []string{"svc/api/handler.go", "lib/store/db.go", "lib/store/cache.go"} {
+		objects = append(objects, category.NewObject(file, "file", file, nil))
+	}
+	objects = append(objects,
+		category.NewObject("import:example.com/lib/store", "imported_package", "example.com/lib/store",
+			map[string]interface{}{"import_path": "example.com/lib/store"}),
+		category.NewObject("import:fmt", "imported_package", "fmt", nil))
+	cat := modeltest.Build(t, "merged", objects, []*category.Morphism{
+		category.NewMorphism("resolves_to:import:example.com/lib/store->lib/store/db.go",
+			"import:example.com/lib/store", "lib/store/db.go", "resolves_to", nil),
+	})
+
+	linked, err := LinkImportedPackages(cat)
+	if err != nil {
+		t.Fatalf("LinkImportedPackages failed: %v", err)
+	}
+	// db.go was already linked and fmt resolves to no file
+	if linked != 1 {
+		t.Errorf("Expected 1 new link, got %d", linked)
+	}
+	m, ok := cat.GetMorphism("resolves_to:import:example.com/lib/store->lib/store/cache.go")
+	if !ok || m.Type != "resolves_to" || m.Metadata["import_path"] != "example.com/lib/store" {
+		t.Errorf("Expected the store import linked to cache.go, got %v", m)
+	}
+
+	if linked, err := LinkImportedPackages(cat); err != nil || linked != 0 {
+		t.Errorf("Expected linking again to add nothing, got %d (%v)", linked, err)
+	}
+}
@@ -0,0 +1,217 @@
+package analysis
+
+import (
+	"math"
+	"path"
+	"path/filepath"
+	"sort"
+
+	"github.com/manu/catreview/pkg/category"
+)
+
+// Zones of the abstractness-instability plane.
+const (
+	ZoneMainSequence = "main_sequence" // Balanced: D within ZoneDistance
+	ZonePain         = "pain"          // Stable and concrete: hard to change, yet depended upon
+	ZoneUselessness  = "uselessness"   // Unstable and abstract: abstractions nobody depends on
+	ZoneIsolated     = "isolated"      // No cross-package dependencies, so I is undefined
+)
+
+// ZoneDistance is the distance from the main sequence beyond which a package
+// lies in the zone of pain or the zone of uselessness.
+const ZoneDistance = 0.5
+
+// PackageMetrics holds Robert Martin's package metrics.
+type PackageMetrics struct {
+	Package       string  `json:"package"`        // Package key, see PackageIndex
+	Name          string  `json:"name,omitempty"` // Declared package name
+	Types         int     `json:"types"`          // Declared types (structs, interfaces, named types)
+	AbstractTypes int     `json:"abstract_types"` // Declared interfaces
+	Abstractness  float64 `json:"abstractness"`   // A = abstract types / types
+	Afferent      int     `json:"afferent"`       // Ca: analyzed packages depending on this one
+	Efferent      int     `json:"efferent"`       // Ce: analyzed packages this one depends on
+	External      int     `json:"external"`       // Packages outside the analyzed code this one depends on
+	Instability   float64 `json:"instability"`    // I = Ce / (Ca + Ce)
+	Distance      float64 `json:"distance"`       // D = |A + I - 1|
+	Zone          string  `json:"zone"`
+}
+
+// isType reports whether an object is a declared type.
+func isType(obj *category.Object) bool {
+	return obj.Type == "struct" || obj.Type == "interface" || obj.Type == "type"
+}
+
+// PackageOf returns the package an object belongs to: its "package"
+// metadata, the name of a package-level object, or, for imported packages,
+// the last element of the import path. The second result reports whether
+// the object is part of the analyzed code.
+func PackageOf(obj *category.Object) (string, bool) {
+	if name, ok := obj.Metadata["package"].(string); ok && name != "" {
+		return name, true
+	}
+	switch obj.Type {
+	case "package":
+		return obj.Name, true
+	case "imported_package":
+		if importPath, ok := obj.Metadata["import_path"].(string); ok && importPath != "" {
+			return path.Base(importPath), false
+		}
+		return obj.Name, false
+	}
+	return "", false
+}
+
+// PackageIndex assigns the objects of a category to packages, keyed by the
+// slash-separated directory of their files, so that packages sharing a name,
+// such as several main packages, stay apart. Objects of abstract models
+// without files are keyed by their package name. Imported packages resolve
+// to the analyzed package directory they refer to, if any; otherwise they
+// are external and keyed by their import path.
+type PackageIndex struct {
+	resolver *ImportResolver
+	names    map[string]string // Package key -> declared name
+}
+
+// NewPackageIndex indexes the packages of cat.
+func NewPackageIndex(cat *category.Category) *PackageIndex {
+	x := &PackageIndex{resolver: NewImportResolver(cat), names: make(map[string]string)}
+	for _, obj := range cat.Objects() {
+		if obj.Type == "imported_package" {
+			continue
+		}
+		if key, internal := x.Package(obj); internal && x.names[key] == "" {
+			x.names[key], _ = PackageOf(obj)
+		}
+	}
+	return x
+}
+
+// Package returns the key of the package an object belongs to and whether
+// the package is part of the analyzed code.
+func (x *PackageIndex) Package(obj *category.Object) (string, bool) {
+	if obj.Type == "imported_package" {
+		importPath, ok := obj.Metadata["import_path"].(string)
+		if !ok || importPath == "" {
+			importPath = obj.Name
+		}
+		if files := x.resolver.Resolve(importPath); len(files) > 0 {
+			return filepath.ToSlash(filepath.Dir(files[0])), true
+		}
+		return importPath, false
+	}
+
+	name, internal := PackageOf(obj)
+	if !internal {
+		return name, false
+	}
+	file := ""
+	if obj.Type == "file" {
+		file = obj.ID
+	} else if f, ok := obj.Metadata["file"].(string); ok {
+		file = f
+	}
+	if file == "" {
+		return name, true
+	}
+	return filepath.ToSlash(filepath.Dir(file)), true
+}
+
+// Name returns the declared name of the package with the given key.
+func (x *PackageIndex) Name(key string) string {
+	return x.names[key]
+}
+
+// ComputePackageMetrics computes abstractness, instability and distance from
+// the main sequence for every package of cat, sorted by decreasing distance.
+//
+// Packages are identified as by PackageIndex. Only dependencies between
+// analyzed packages count towards Ca and Ce, each distinct package once, so
+// an import of an analyzed package counts as a dependency on it; external
+// packages are counted separately. Packages without any declared type have
+// A = 0.
+func ComputePackageMetrics(cat *category.Category, edges *EdgeFilter) []*PackageMetrics {
+	index := NewPackageIndex(cat)
+	packages := make(map[string]*PackageMetrics)
+	for _, obj := range cat.Objects() {
+		key, internal := index.Package(obj)
+		if !internal {
+			continue
+		}
+		p, exists := packages[key]
+		if !exists {
+			p = &PackageMetrics{Package: key, Name: index.Name(key)}
+			packages[key] = p
+		}
+		if isType(obj) {
+			p.Types++
+			if obj.Type == "interface" {
+				p.AbstractTypes++
+			}
+		}
+	}
+
+	afferent := make(map[string]map[string]bool)
+	efferent := make(map[string]map[string]bool)
+	external := make(map[string]map[string]bool)
+	for _, morph := range cat.Morphisms() {
+		if !edges.Allows(morph) {
+			continue
+		}
+		source, okSource := cat.GetObject(morph.Source)
+		target, okTarget := cat.GetObject(morph.Target)
+		if !okSource || !okTarget {
+			continue
+		}
+		from, fromInternal := index.Package(source)
+		to, toInternal := index.Package(target)
+		if !fromInternal || to == "" || from == to {
+			continue
+		}
+		if !toInternal {
+			addPackage(external, from, to)
+			continue
+		}
+		addPackage(efferent, from, to)
+		addPackage(afferent, to, from)
+	}
+
+	metrics := make([]*PackageMetrics, 0, len(packages))
+	for name, p := range packages {
+		p.Afferent, p.Efferent, p.External = len(afferent[name]), len(efferent[name]), len(external[name])
+		if p.Types > 0 {
+			p.Abstractness = float64(p.AbstractTypes) / float64(p.Types)
+		}
+		if total := p.Afferent + p.Efferent; total > 0 {
+			p.Instability = float64(p.Efferent) / float64(total)
+		}
+		p.Distance = math.Abs(p.Abstractness + p.Instability - 1)
+
+		switch {
+		case p.Afferent+p.Efferent == 0:
+			p.Zone = ZoneIsolated
+		case p.Distance <= ZoneDistance:
+			p.Zone = ZoneMainSequence
+		case p.Abstractness+p.Instability < 1:
+			p.Zone = ZonePain
+		default:
+			p.Zone = ZoneUselessness
+		}
+		metrics = append(metrics, p)
+	}
+
+	sort.Slice(metrics, func(i, j int) bool {
+		if metrics[i].Distance != metrics[j].Distance {
+			return metrics[i].Distance > metrics[j].Distance
+		}
+		return metrics[i].Package < metrics[j].Package
+	})
+	return metrics
+}
+
+// addPackage adds to to the set of packages related to from.
+func addPackage(sets map[string]map[string]bool, from, to string) {
+	if sets[from] == nil {
+		sets[from] = make(map[string]bool)
+	}
+	sets[from][to] = true
+}
@@ -0,0 +1,184 @@
+package analysis
+
+import (
+	"math"
+	"testing"
+
+	"github.com/manu/catreview/internal/modeltest"
+	"github.com/manu/catreview/pkg/category"
+)
+
+// martinFixture builds packages api, store, core, contracts and lonely of
+// module example.com/app. Packages depend on each other through imports of
+// their paths.
+func martinFixture(t *testing.T) *category.Category {
+	files := []struct{ path, pkg string }{
+		{"api/a.go", "api"},
+		{"store/s.go", "store"},
+		{"core/c.go", "core"},
+		{"contracts/k.go", "contracts"},
+		{"lonely/l.go", "lonely"},
+	}
+	var objects []*category.Object
+	var morphisms []*category.Morphism
+	packageOf := make(map[string]string)
+	for _, f := range files {
+		packageOf[f.path] = f.pkg
+		objects = append(objects, category.NewObject(f.path, "file", f.path, map[string]interface{}{
+			"package": f.pkg, "path": f.path, "import_path": "example.com/app/" + f.pkg,
+		}))
+	}
+	types := []struct{ id, kind, file string }{
+		{"store.Store", "interface", "store/s.go"},
+		{"store.impl", "struct", "store/s.go"},
+		{"core.T1", "struct", "core/c.go"},
+		{"core.T2", "type", "core/c.go"},
+		{"contracts.Contract", "interface", "contracts/k.go"},
+	}
+	for _, typ := range types {
+		objects = append(objects, category.NewObject(typ.id, typ.kind, typ.id, map[string]interface{}{"package": packageOf[typ.file], "file": typ.file}))
+		morphisms = append(morphisms, category.NewMorphism("defines:"+typ.id, typ.file, typ.id, "defines", nil))
+	}
+
+	for _, path := range []string{"example.com/app/store", "example.com/app/core", "database/sql"} {
+		objects = append(objects, category.NewObject("import:"+path, "imported_package", path, map[string]interface{}{"import_path": path}))
+	}
+	imports := [][2]string{
+		{"api/a.go", "example.com/app/store"},
+		{"api/a.go", "example.com/app/core"},
+		{"store/s.go", "example.com/app/core"},
+		{"store/s.go", "database/sql"},
+		{"contracts/k.go", "example.com/app/core"},
+	}
+	for _, imp := range imports {
+		morphisms = append(morphisms, category.NewMorphism("import:"+imp[0]+"->"+imp[1], imp[0], "import:"+imp[1], "import", nil))
+	}
+	return modeltest.Build(t, "martin", objects, morphisms)
+}
+
+func TestComputePackageMetrics(t *testing.T) {
+	metrics := ComputePackageMetrics(martinFixture(t), nil)
+	if len(metrics) != 5 {
+		t.Fatalf("Expected 5 packages, got %d", len(metrics))
+	}
+	byName := make(map[string]*PackageMetrics)
+	for _, p := range metrics {
+		byName[p.Package] = p
+	}
+
+	tests := []struct {
+		pkg    string
+		a, i   float64
+		ca, ce int
+		zone   string
+	}{
+		{"api", 0, 1, 0, 2, ZoneMainSequence},
+		{"store", 0.5, 0.5, 1, 1, ZoneMainSequence}, // database/sql is external
+		{"core", 0, 0, 3, 0, ZonePain},
+		{"contracts", 1, 1, 0, 1, ZoneUselessness},
+		{"lonely", 0, 0, 0, 0, ZoneIsolated},
+	}
+	for _, tt := range tests {
+		p := byName[tt.pkg]
+		if p == nil {
+			t.Errorf("Missing package %s", tt.pkg)
+			continue
+		}
+		if math.Abs(p.Abstractness-tt.a) > 1e-9 || math.Abs(p.Instability-tt.i) > 1e-9 {
+			t.Errorf("%s: expected A=%.2f I=%.2f, got A=%.2f I=%.2f", tt.pkg, tt.a, tt.i, p.Abstractness, p.Instability)
+		}
+		if p.Afferent != tt.ca || p.Efferent != tt.ce {
+			t.Errorf("%s: expected Ca=%d Ce=%d, got Ca=%d Ce=%d", tt.pkg, tt.ca, tt.ce, p.Afferent, p.Efferent)
+		}
+		if want := math.Abs(tt.a + tt.i - 1); math.Abs(p.Distance-want) > 1e-9 {
+			t.Errorf("%s: expected D=%.2f, got %.2f", tt.pkg, want, p.Distance)
+		}
+		if p.Zone != tt.zone {
+			t.Errorf("%s: expected zone %s, got %s", tt.pkg, tt.zone, p.Zone)
+		}
+	}
+
+	if byName["store"].External != 1 || byName["api"].External != 0 {
+		t.Errorf("Expected database/sql as the only external dependency, got %d and %d",
+			byName["store"].External, byName["api"].External)
+	}
+
+	// Sorted by decreasing distance, ties by name
+	if metrics[0].Package != "contracts" || metrics[1].Package != "core" {
+		t.Errorf("Expected contracts and core first, got %s and %s", metrics[0].Package, metrics[1].Package)
+	}
+}
+
+func TestPackageMetricsEdgeFilter(t *testing.T) {
+	// Without imports no package depends on another
+	filter := &EdgeFilter{Exclude: []string{"import"}}
+	for _, p := range ComputePackageMetrics(martinFixture(t), filter) {
+		if p.Zone != ZoneIsolated {
+			t.Errorf("%s: expected isolated without imports, got %s", p.Package, p.Zone)
+		}
+	}
+}
+
+func TestCouplingAbstractness(t *testing.T) {
+	metrics := NewComplexityAnalyzer(martinFixture(t)).ComputeCoupling()
+
+	tests := map[string]float64{
+		"store/s.go":         0.5, // One interface, one struct
+		"store.Store":        1,
+		"core/c.go":          0,
+		"contracts.Contract": 1,
+		"api/a.go":           0, // Declares no types
+	}
+	for id, want := range tests {
+		if got := metrics[id].Abstractness; got != want {
+			t.Errorf("%s: expected A=%.2f, got %.2f", id, want, got)
+		}
+	}
+
+	// contracts.Contract: A=1, no dependencies (I=0)
+	if d := metrics["contracts.Contract"].Distance; d != 0 {
+		t.Errorf("Expected D=0 for contracts.Contract, got %.2f", d)
+	}
+}
+
+func TestPackageMetricsKeyedByDirectory(t *testing.T) {
+	files := []struct{ path, pkg string }{
+		{"cmd/server/main.go", "main"},
+		{"cmd/worker/main.go", "main"},
+		{"pkg/errors/err.go", "errors"},
+	}
+	var objects []*category.Object
+	for _, f := range files {
+		objects = append(objects, category.NewObject(f.path, "file", f.path, map[string]interface{}{"package": f.pkg}))
+	}
+	for _, path := range []string{"example.com/app/pkg/errors", "errors"} {
+		objects = append(objects, category.NewObject("import:"+path, "imported_package", path, map[string]interface{}{"import_path": path}))
+	}
+	imports := [][2]string{
+		{"cmd/server/main.go", "example.com/app/pkg/errors"},
+		{"cmd/worker/main.go", "errors"}, // The standard library, not pkg/errors
+	}
+	var morphisms []*category.Morphism
+	for _, imp := range imports {
+		morphisms = append(morphisms, category.NewMorphism("import:"+imp[0]+"->"+imp[1], imp[0], "import:"+imp[1], "import", nil))
+	}
+	cat := modeltest.Build(t, "mains", objects, morphisms)
+
+	byKey := make(map[string]*PackageMetrics)
+	for _, p := range ComputePackageMetrics(cat, nil) {
+		byKey[p.Package] = p
+	}
+	if len(byKey) != 3 {
+		t.Fatalf("Expected the two main packages apart, got %v", byKey)
+	}
+	server, worker, errs := byKey["cmd/server"], byKey["cmd/worker"], byKey["pkg/errors"]
+	if server.Name != "main" || server.Efferent != 1 || server.External != 0 {
+		t.Errorf("Expected cmd/server to depend on pkg/errors, got %+v", server)
+	}
+	if worker.Efferent != 0 || worker.External != 1 || worker.Zone != ZoneIsolated {
+		t.Errorf("Expected cmd/worker to depend on the standard library only, got %+v", worker)
+	}
+	if errs.Afferent != 1 {
+		t.Errorf("Expected pkg/errors depended on once, got Ca=%d", errs.Afferent)
+	}
+}
@@ -0,0 +1,348 @@
+package analysis
+
+import (
+	"sort"
+
+	"github.com/manu/catreview/pkg/category"
+)
+
+// DefaultCycleLimit caps the elementary cycles enumerated for a report.
+const DefaultCycleLimit = 100
+
+// Component is a strongly connected component of the dependency graph: a
+// maximal set of objects that all depend on each other, directly or
+// transitively.
+type Component struct {
+	ID      int      `json:"id"`      // Position in the condensation's topological order
+	Objects []string `json:"objects"` // Sorted by ID
+	Size    int      `json:"size"`
+	Edges   []string `json:"edges"` // Dependencies within the component
+}
+
+// Cyclic reports whether the component contains a cycle: it has more than
+// one object, or an object depending on itself.
+func (c *Component) Cyclic() bool {
+	return c.Size > 1 || len(c.Edges) > 0
+}
+
+// CondensationEdge is a dependency between two components.
+type CondensationEdge struct {
+	From      int `json:"from"`
+	To        int `json:"to"`
+	Morphisms int `json:"morphisms"` // Dependencies it aggregates
+}
+
+// Condensation is the DAG obtained by collapsing every strongly connected
+// component into one node. Components are numbered in topological order:
+// every edge runs from a lower to a higher ID.
+type Condensation struct {
+	Components []*Component        `json:"components"`
+	Edges      []*CondensationEdge `json:"edges"`
+
+	of map[string]int
+}
+
+// ComponentOf returns the component containing the object with the given ID.
+func (d *Condensation) ComponentOf(objectID string) (*Component, bool) {
+	id, ok := d.of[objectID]
+	if !ok {
+		return nil, false
+	}
+	return d.Components[id], true
+}
+
+// dependencyGraph returns the objects of cat sorted by ID and, for each, the
+// sorted distinct targets of its morphisms selected by edges.
+func dependencyGraph(cat *category.Category, edges *EdgeFilter) ([]string, map[string][]string) {
+	var nodes []string
+	for _, obj := range cat.Objects() {
+		nodes = append(nodes, obj.ID)
+	}
+
+	seen := make(map[[2]string]bool)
+	adjacency := make(map[string][]string)
+	for _, morph := range cat.Morphisms() {
+		if !edges.Allows(morph) || seen[[2]string{morph.Source, morph.Target}] {
+			continue
+		}
+		seen[[2]string{morph.Source, morph.Target}] = true
+		adjacency[morph.Source] = append(adjacency[morph.Source], morph.Target)
+	}
+	for _, targets := range adjacency {
+		sort.Strings(targets)
+	}
+	return nodes, adjacency
+}
+
+// tarjan returns the strongly connected components of a graph in reverse
+// topological order. It is iterative, so deep dependency chains cannot
+// exhaust the stack.
+func tarjan(nodes []string, adjacency map[string][]string) [][]string {
+	type frame struct {
+		node string
+		next int
+	}
+
+	index := make(map[string]int)
+	lowlink := make(map[string]int)
+	onStack := make(map[string]bool)
+	var stack []string
+	var components [][]string
+
+	for _, root := range nodes {
+		if _, visited := index[root]; visited {
+			continue
+		}
+
+		frames := []frame{{node: root}}
+		index[root], lowlink[root] = len(index), len(index)
+		stack = append(stack, root)
+		onStack[root] = true
+
+		for len(frames) > 0 {
+			top := &frames[len(frames)-1]
+			v := top.node
+
+			if top.next < len(adjacency[v]) {
+				w := adjacency[v][top.next]
+				top.next++
+				if _, visited := index[w]; !visited {
+					index[w], lowlink[w] = len(index), len(index)
+					stack = append(stack, w)
+					onStack[w] = true
+					frames = append(frames, frame{node: w})
+				} else if onStack[w] && index[w] < lowlink[v] {
+					lowlink[v] = index[w]
+				}
+				continue
+			}
+
+			// All successors visited: v is done
+			frames = frames[:len(frames)-1]
+			if len(frames) > 0 {
+				parent := frames[len(frames)-1].node
+				if lowlink[v] < lowlink[parent] {
+					lowlink[parent] = lowlink[v]
+				}
+			}
+			if lowlink[v] == index[v] {
+				var component []string
+				for {
+					w := stack[len(stack)-1]
+					stack = stack[:len(stack)-1]
+					onStack[w] = false
+					component = append(component, w)
+					if w == v {
+						break
+					}
+				}
+				sort.Strings(component)
+				components = append(components, component)
+			}
+		}
+	}
+	return components
+}
+
+// Condensation computes the strongly connected components of the
+// dependency graph and the DAG between them.
+func (c *CycleAnalyzer) Condensation() *Condensation {
+	nodes, adjacency := dependencyGraph(c.cat, c.Edges)
+	sccs := tarjan(nodes, adjacency)
+
+	d := &Condensation{
+		Components: make([]*Component, len(sccs)),
+		Edges:      []*CondensationEdge{},
+		of:         make(map[string]int),
+	}
+	for i := range sccs {
+		// Tarjan emits components in reverse topological order
+		objects := sccs[len(sccs)-1-i]
+		d.Components[i] = &Component{ID: i, Objects: objects, Size: len(objects), Edges: []string{}}
+		for _, id := range objects {
+			d.of[id] = i
+		}
+	}
+
+	edges := make(map[[2]int]*CondensationEdge)
+	for _, morph := range c.cat.Morphisms() {
+		if !c.Edges.Allows(morph) {
+			continue
+		}
+		from, okFrom := d.of[morph.Source]
+		to, okTo := d.of[morph.Target]
+		if !okFrom || !okTo {
+			continue
+		}
+		if from == to {
+			d.Components[from].Edges = append(d.Components[from].Edges, morph.ID)
+			continue
+		}
+		edge, exists := edges[[2]int{from, to}]
+		if !exists {
+			edge = &CondensationEdge{From: from, To: to}
+			edges[[2]int{from, to}] = edge
+			d.Edges = append(d.Edges, edge)
+		}
+		edge.Morphisms++
+	}
+	sort.Slice(d.Edges, func(i, j int) bool {
+		if d.Edges[i].From != d.Edges[j].From {
+			return d.Edges[i].From < d.Edges[j].From
+		}
+		return d.Edges[i].To < d.Edges[j].To
+	})
+
+	return d
+}
+
+// StronglyConnectedComponents returns the components containing a cycle,
+// largest first.
+func (c *CycleAnalyzer) StronglyConnectedComponents() []*Component {
+	components := []*Component{}
+	for _, comp := range c.Condensation().Components {
+		if comp.Cyclic() {
+			components = append(components, comp)
+		}
+	}
+	sort.SliceStable(components, func(i, j int) bool {
+		return components[i].Size > components[j].Size
+	})
+	return components
+}
+
+// EnumerateCycles lists the elementary cycles of the dependency graph with
+// Johnson's algorithm, stopping after max cycles (max <= 0 means no limit).
+// Each cycle starts at its smallest object ID, so every cycle is reported
+// exactly once. The second result reports whether enumeration stopped at
+// the limit.
+func (c *CycleAnalyzer) EnumerateCycles(max int) ([]*Cycle, bool) {
+	nodes, adjacency := dependencyGraph(c.cat, c.Edges)
+	cycles := []*Cycle{}
+
+	// Cycles never leave a strongly connected component. As in Johnson's
+	// algorithm, each component is searched for the cycles through its
+	// smallest object, which is then removed and the rest split again.
+	pending := tarjan(nodes, adjacency)
+	for len(pending) > 0 {
+		scc := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+
+		members := make(map[string]bool, len(scc))
+		for _, id := range scc {
+			members[id] = true
+		}
+		allowed := func(w string) bool { return members[w] }
+
+		start := scc[0]
+		if c.circuits(start, adjacency, allowed, &cycles, max) {
+			return cycles, true
+		}
+
+		delete(members, start)
+		sub := make(map[string][]string, len(scc)-1)
+		for _, id := range scc[1:] {
+			for _, w := range adjacency[id] {
+				if members[w] {
+					sub[id] = append(sub[id], w)
+				}
+			}
+		}
+		for _, rest := range tarjan(scc[1:], sub) {
+			if len(rest) > 1 || hasSelfLoop(rest[0], sub) {
+				pending = append(pending, rest)
+			}
+		}
+	}
+	return cycles, false
+}
+
+// hasSelfLoop reports whether v depends on itself.
+func hasSelfLoop(v string, adjacency map[string][]string) bool {
+	for _, w := range adjacency[v] {
+		if w == v {
+			return true
+		}
+	}
+	return false
+}
+
+// circuits appends the elementary cycles through start within the allowed
+// nodes, as in Johnson's CIRCUIT procedure, iteratively. It returns true
+// once max cycles have been found.
+func (c *CycleAnalyzer) circuits(start string, adjacency map[string][]string, allowed func(string) bool, cycles *[]*Cycle, max int) bool {
+	type frame struct {
+		node  string
+		next  int
+		found bool
+	}
+
+	blocked := map[string]bool{start: true}
+	blockers := make(map[string]map[string]bool) // Johnson's B lists
+	unblock := func(v string) {
+		pending := []string{v}
+		for len(pending) > 0 {
+			u := pending[len(pending)-1]
+			pending = pending[:len(pending)-1]
+			if !blocked[u] {
+				continue
+			}
+			blocked[u] = false
+			for w := range blockers[u] {
+				pending = append(pending, w)
+			}
+			delete(blockers, u)
+		}
+	}
+
+	path := []string{start}
+	frames := []frame{{node: start}}
+	for len(frames) > 0 {
+		top := &frames[len(frames)-1]
+		v := top.node
+
+		if top.next < len(adjacency[v]) {
+			w := adjacency[v][top.next]
+			top.next++
+			if !allowed(w) {
+				continue
+			}
+			if w == start {
+				*cycles = append(*cycles, &Cycle{
+					Objects: append([]string{}, path...),
+					Length:  len(path),
+				})
+				top.found = true
+				if max > 0 && len(*cycles) >= max {
+					return true
+				}
+			} else if !blocked[w] {
+				blocked[w] = true
+				path = append(path, w)
+				frames = append(frames, frame{node: w})
+			}
+			continue
+		}
+
+		// All successors explored
+		if top.found {
+			unblock(v)
+		} else {
+			for _, w := range adjacency[v] {
+				if allowed(w) {
+					if blockers[w] == nil {
+						blockers[w] = make(map[string]bool)
+					}
+					blockers[w][v] = true
+				}
+			}
+		}
+		found := top.found
+		frames = frames[:len(frames)-1]
+		path = path[:len(path)-1]
+		if found && len(frames) > 0 {
+			frames[len(frames)-1].found = true
+		}
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+package analysis
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/manu/catreview/internal/modeltest"
+	"github.com/manu/catreview/pkg/category"
+)
+
+// graphFixture builds a category with one object per node and one import
+// morphism per edge.
+func graphFixture(t *testing.T, nodes []string, edges [][2]string) *category.Category {
+	objects := make([]*category.Object, len(nodes))
+	for i, id := range nodes {
+		objects[i] = category.NewObject(id, "file", id, nil)
+	}
+	morphisms := make([]*category.Morphism, len(edges))
+	for i, e := range edges {
+		morphisms[i] = category.NewMorphism(fmt.Sprintf("e%d", i), e[0], e[1], "import", nil)
+	}
+	return modeltest.Build(t, "graph", objects, morphisms)
+}
+
+func TestStronglyConnectedComponents(t *testing.T) {
+	// {a, b, c} form a component with two cycles, d depends on it, e loops
+	cat := graphFixture(t, []string{"a", "b", "c", "d", "e"}, [][2]string{
+		{"a", "b"}, {"b", "c"}, {"c", "a"}, {"b", "a"}, {"d", "a"}, {"e", "e"},
+	})
+	modeltest.Add(t, cat, nil, []*category.Morphism{category.NewMorphism("cochange", "d", "e", "co_changes_with", nil)})
+
+	analyzer := NewCycleAnalyzer(cat)
+	components := analyzer.StronglyConnectedComponents()
+	if len(components) != 2 {
+		t.Fatalf("Expected 2 cyclic components, got %d", len(components))
+	}
+	abc := components[0]
+	if abc.Size != 3 || abc.Objects[0] != "a" || len(abc.Edges) != 4 {
+		t.Errorf("Expected component {a, b, c} with 4 edges, got %v with %v", abc.Objects, abc.Edges)
+	}
+	if components[1].Size != 1 || components[1].Objects[0] != "e" {
+		t.Errorf("Expected self-loop component {e}, got %v", components[1].Objects)
+	}
+
+	d := analyzer.Condensation()
+	if len(d.Components) != 3 {
+		t.Fatalf("Expected 3 components in the condensation, got %d", len(d.Components))
+	}
+	from, _ := d.ComponentOf("d")
+	to, _ := d.ComponentOf("a")
+	if len(d.Edges) != 1 || d.Edges[0].From != from.ID || d.Edges[0].To != to.ID {
+		t.Errorf("Expected one condensation edge d → {a, b, c}, got %+v", d.Edges)
+	}
+	for _, e := range d.Edges {
+		if e.From >= e.To {
+			t.Errorf("Expected topological numbering, got edge %d → %d", e.From, e.To)
+		}
+	}
+}
+
+func TestEnumerateCycles(t *testing.T) {
+	// Complete graph on 4 nodes: 6 two-cycles, 8 three-cycles, 6 four-cycles
+	nodes := []string{"a", "b", "c", "d"}
+	var edges [][2]string
+	for _, x := range nodes {
+		for _, y := range nodes {
+			if x != y {
+				edges = append(edges, [2]string{x, y})
+			}
+		}
+	}
+	analyzer := NewCycleAnalyzer(graphFixture(t, nodes, edges))
+
+	cycles, truncated := analyzer.EnumerateCycles(0)
+	if len(cycles) != 20 || truncated {
+		t.Fatalf("Expected all 20 elementary cycles, got %d (truncated %v)", len(cycles), truncated)
+	}
+	seen := make(map[string]bool)
+	for _, c := range cycles {
+		key := cycleKey(c)
+		if seen[key] {
+			t.Errorf("Cycle %v reported twice", c.Objects)
+		}
+		seen[key] = true
+	}
+
+	cycles, truncated = analyzer.EnumerateCycles(5)
+	if len(cycles) != 5 || !truncated {
+		t.Errorf("Expected 5 cycles and truncation, got %d (truncated %v)", len(cycles), truncated)
+	}
+}
+
+func TestEnumerateCyclesDeepChain(t *testing.T) {
+	// A long ring must not exhaust the stack
+	const n = 20000
+	nodes := make([]string, n)
+	edges := make([][2]string, n)
+	for i := range nodes {
+		nodes[i] = fmt.Sprintf("n%06d", i)
+	}
+	for i := range nodes {
+		edges[i] = [2]string{nodes[i], nodes[(i+1)%n]}
+	}
+	analyzer := NewCycleAnalyzer(graphFixture(t, nodes, edges))
+
+	if components := analyzer.StronglyConnectedComponents(); len(components) != 1 || components[0].Size != n {
+		t.Fatalf("Expected one component of %d objects", n)
+	}
+	cycles, _ := analyzer.EnumerateCycles(10)
+	if len(cycles) != 1 || cycles[0].Length != n {
+		t.Errorf("Expected one cycle of length %d, got %d cycles", n, len(cycles))
+	}
+}
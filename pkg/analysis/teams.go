@@ -0,0 +1,76 @@
+package analysis
+
+import (
+	"sort"
+
+	"github.com/manu/catreview/pkg/category"
+)
+
+// TeamCouplingMatrix counts structural dependencies between owning teams.
+//
+// Counts[i][j] is the number of morphisms from objects owned by Teams[i] to
+// objects owned by Teams[j]. Off-diagonal entries are cross-team couplings.
+type TeamCouplingMatrix struct {
+	Teams  []string `json:"teams"`
+	Counts [][]int  `json:"counts"`
+}
+
+// ComputeTeamCoupling builds the team coupling matrix from "owner" metadata,
+// counting the morphisms selected by edges (nil: all structural morphisms).
+// Morphisms with an unowned endpoint are ignored. Returns nil if no object
+// in the category has an owner.
+func ComputeTeamCoupling(cat *category.Category, edges *EdgeFilter) *TeamCouplingMatrix {
+	owners := make(map[string]string)
+	for _, obj := range cat.Objects() {
+		if owner, ok := obj.Metadata["owner"].(string); ok && owner != "" {
+			owners[obj.ID] = owner
+		}
+	}
+	if len(owners) == 0 {
+		return nil
+	}
+
+	teamSet := make(map[string]bool)
+	for _, owner := range owners {
+		teamSet[owner] = true
+	}
+	teams := make([]string, 0, len(teamSet))
+	for team := range teamSet {
+		teams = append(teams, team)
+	}
+	sort.Strings(teams)
+
+	index := make(map[string]int, len(teams))
+	counts := make([][]int, len(teams))
+	for i, team := range teams {
+		index[team] = i
+		counts[i] = make([]int, len(teams))
+	}
+
+	for _, morph := range cat.Morphisms() {
+		if !edges.Allows(morph) {
+			continue
+		}
+		source, sok := owners[morph.Source]
+		target, tok := owners[morph.Target]
+		if !sok || !tok {
+			continue
+		}
+		counts[index[source]][index[target]]++
+	}
+
+	return &TeamCouplingMatrix{Teams: teams, Counts: counts}
+}
+
+// CrossTeam returns the total number of dependencies between different teams.
+func (m *TeamCouplingMatrix) CrossTeam() int {
+	total := 0
+	for i := range m.Counts {
+		for j := range m.Counts[i] {
+			if i != j {
+				total += m.Counts[i][j]
+			}
+		}
+	}
+	return total
+}
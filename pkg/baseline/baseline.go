@@ -0,0 +1,203 @@
+// Package baseline records the known findings of a codebase, such as
+// dependency cycles and rule violations, so that checks of an old codebase
+// fail only on new findings and tighten as known ones are fixed.
+package baseline
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/manu/catreview/pkg/analysis"
+	"github.com/manu/catreview/pkg/category"
+	"github.com/manu/catreview/pkg/rules"
+)
+
+// Version is the version of the baseline file format.
+const Version = 1
+
+// Kinds of findings.
+const (
+	KindCycle    = "cycle"    // A dependency cycle
+	KindRule     = "rule"     // A forbidden dependency
+	KindCoupling = "coupling" // A package over its coupling limit
+)
+
+// Entry is a finding, identified by its fingerprint. Count measures a
+// coupling finding, the efferent coupling of its package, which must not
+// grow beyond the baselined count; it is 0 for other findings. The other
+// fields describe the finding for people reading the baseline.
+type Entry struct {
+	Fingerprint string `json:"fingerprint"`
+	Kind        string `json:"kind"`
+	Rule        string `json:"rule,omitempty"`
+	Count       int    `json:"count,omitempty"`
+	Message     string `json:"message"`
+}
+
+// Baseline is a set of known findings.
+type Baseline struct {
+	Version  int      `json:"version"`
+	Findings []*Entry `json:"findings"` // Sorted by kind, then fingerprint
+	index    map[string]*Entry
+}
+
+// New creates a baseline of findings. Of findings sharing a fingerprint,
+// the first is kept.
+func New(findings []*Entry) *Baseline {
+	b := &Baseline{Version: Version, Findings: []*Entry{}, index: make(map[string]*Entry)}
+	for _, f := range findings {
+		if b.index[f.Fingerprint] == nil {
+			b.index[f.Fingerprint] = f
+			b.Findings = append(b.Findings, f)
+		}
+	}
+	sort.Slice(b.Findings, func(i, j int) bool {
+		if b.Findings[i].Kind != b.Findings[j].Kind {
+			return b.Findings[i].Kind < b.Findings[j].Kind
+		}
+		return b.Findings[i].Fingerprint < b.Findings[j].Fingerprint
+	})
+	return b
+}
+
+// Load reads a baseline file.
+func Load(path string) (*Baseline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var b Baseline
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("failed to parse baseline %s: %v", path, err)
+	}
+	if b.Version != Version {
+		return nil, fmt.Errorf("unsupported baseline version %d in %s", b.Version, path)
+	}
+	return New(b.Findings), nil
+}
+
+// Save writes the baseline as canonical, indented JSON, so that it diffs
+// well under version control.
+func (b *Baseline) Save(path string) error {
+	data, err := category.CanonicalJSONIndent(b)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// Contains reports whether a finding is known.
+func (b *Baseline) Contains(fingerprint string) bool {
+	return b.index[fingerprint] != nil
+}
+
+// Known reports whether a current finding is known and, for a coupling
+// finding, has not grown beyond its baselined count.
+func (b *Baseline) Known(current *Entry) bool {
+	known := b.index[current.Fingerprint]
+	return known != nil && (known.Count == 0 || current.Count <= known.Count)
+}
+
+// Count returns the number of known findings of a kind.
+func (b *Baseline) Count(kind string) int {
+	n := 0
+	for _, f := range b.Findings {
+		if f.Kind == kind {
+			n++
+		}
+	}
+	return n
+}
+
+// Fixed returns the known findings of the given kinds that are not among
+// the current findings. Only kinds that were checked completely can tell
+// fixed findings apart from findings that were not looked for.
+func (b *Baseline) Fixed(current []*Entry, kinds ...string) []*Entry {
+	found := make(map[string]bool, len(current))
+	for _, f := range current {
+		found[f.Fingerprint] = true
+	}
+	var fixed []*Entry
+	for _, f := range b.Findings {
+		if !found[f.Fingerprint] && containsKind(kinds, f.Kind) {
+			fixed = append(fixed, f)
+		}
+	}
+	return fixed
+}
+
+// Lowered returns the current findings of the given kinds whose count
+// dropped below their baselined count.
+func (b *Baseline) Lowered(current []*Entry, kinds ...string) []*Entry {
+	var lowered []*Entry
+	for _, f := range current {
+		if known := b.index[f.Fingerprint]; known != nil && f.Count < known.Count && containsKind(kinds, f.Kind) {
+			lowered = append(lowered, f)
+		}
+	}
+	return lowered
+}
+
+// With returns a baseline whose findings are replaced by the given ones of
+// the same fingerprint, such as lowered ones.
+func (b *Baseline) With(findings []*Entry) *Baseline {
+	var kept []*Entry
+	for _, f := range findings {
+		if b.Contains(f.Fingerprint) {
+			kept = append(kept, f)
+		}
+	}
+	return New(append(kept, b.Findings...))
+}
+
+// Without returns a baseline without the given findings.
+func (b *Baseline) Without(findings []*Entry) *Baseline {
+	drop := make(map[string]bool, len(findings))
+	for _, f := range findings {
+		drop[f.Fingerprint] = true
+	}
+	var kept []*Entry
+	for _, f := range b.Findings {
+		if !drop[f.Fingerprint] {
+			kept = append(kept, f)
+		}
+	}
+	return New(kept)
+}
+
+// CycleEntry describes a dependency cycle as a finding.
+func CycleEntry(c *analysis.Cycle) *Entry {
+	objects := append(append([]string{}, c.Objects...), c.Objects[0])
+	return &Entry{
+		Fingerprint: c.Fingerprint(),
+		Kind:        KindCycle,
+		Message:     strings.Join(objects, " -> "),
+	}
+}
+
+// ViolationEntry describes a rule violation as a finding.
+func ViolationEntry(v *rules.Violation) *Entry {
+	kind := KindRule
+	if v.Kind == rules.KindCoupling {
+		kind = KindCoupling
+	}
+	return &Entry{
+		Fingerprint: v.Fingerprint(),
+		Kind:        kind,
+		Rule:        v.Rule,
+		Count:       v.Efferent,
+		Message:     v.Message,
+	}
+}
+
+func containsKind(kinds []string, kind string) bool {
+	for _, k := range kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,106 @@
+package baseline
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/manu/catreview/pkg/analysis"
+	"github.com/manu/catreview/pkg/rules"
+)
+
+func TestFingerprintsAreStable(t *testing.T) {
+	a := CycleEntry(&analysis.Cycle{Objects: []string{"a", "b"}, Length: 2})
+	b := CycleEntry(&analysis.Cycle{Objects: []string{"a", "b"}, Length: 2})
+	c := CycleEntry(&analysis.Cycle{Objects: []string{"a", "c"}, Length: 2})
+	if a.Fingerprint != b.Fingerprint || a.Fingerprint == c.Fingerprint {
+		t.Errorf("Expected equal cycles to share fingerprints only: %s %s %s", a.Fingerprint, b.Fingerprint, c.Fingerprint)
+	}
+	if a.Message != "a -> b -> a" {
+		t.Errorf("Expected the cycle closed in its message, got %q", a.Message)
+	}
+
+	// Positions, messages and evidence do not matter
+	v1 := &rules.Violation{Rule: "pure", Kind: rules.KindDependency, Source: "x.go", Target: "y.go", Message: "x.go must not depend on y.go",
+		Location: &analysis.Location{File: "x.go", Line: 3}}
+	v2 := &rules.Violation{Rule: "pure", Kind: rules.KindDependency, Source: "x.go", Target: "y.go", Message: "changed",
+		Location: &analysis.Location{File: "x.go", Line: 40}}
+	if ViolationEntry(v1).Fingerprint != ViolationEntry(v2).Fingerprint {
+		t.Error("Expected violations of the same rule and files to share a fingerprint")
+	}
+	coupling := ViolationEntry(&rules.Violation{Rule: "small", Kind: rules.KindCoupling, Source: "pkg"})
+	if coupling.Kind != KindCoupling || ViolationEntry(v1).Kind != KindRule {
+		t.Errorf("Expected coupling and rule kinds, got %s and %s", coupling.Kind, ViolationEntry(v1).Kind)
+	}
+}
+
+func TestBaselineRatchet(t *testing.T) {
+	cycle := &Entry{Fingerprint: "c1", Kind: KindCycle, Message: "a -> b -> a"}
+	rule := &Entry{Fingerprint: "r1", Kind: KindRule, Rule: "pure", Message: "x.go must not depend on y.go"}
+	other := &Entry{Fingerprint: "r2", Kind: KindRule, Rule: "pure", Message: "z.go must not depend on y.go"}
+
+	base := New([]*Entry{rule, cycle, rule, other})
+	if len(base.Findings) != 3 || base.Findings[0] != cycle {
+		t.Fatalf("Expected 3 findings sorted by kind, got %v", base.Findings)
+	}
+	if !base.Contains("r1") || base.Contains("r3") || base.Count(KindRule) != 2 {
+		t.Error("Unexpected baseline contents")
+	}
+
+	// r2 was fixed; the cycle was not checked, so it is kept
+	current := []*Entry{rule, {Fingerprint: "r3", Kind: KindRule}}
+	fixed := base.Fixed(current, KindRule, KindCoupling)
+	if len(fixed) != 1 || fixed[0] != other {
+		t.Fatalf("Expected r2 fixed, got %v", fixed)
+	}
+	tightened := base.Without(fixed)
+	if len(tightened.Findings) != 2 || tightened.Contains("r2") || tightened.Contains("r3") {
+		t.Errorf("Expected c1 and r1 left, got %v", tightened.Findings)
+	}
+
+	path := filepath.Join(t.TempDir(), "baseline.json")
+	if err := tightened.Save(path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(loaded.Findings) != 2 || !loaded.Contains("c1") || !loaded.Contains("r1") {
+		t.Errorf("Expected the saved findings back, got %v", loaded.Findings)
+	}
+}
+
+func TestBaselineCouplingCounts(t *testing.T) {
+	coupling := func(efferent int) *Entry {
+		return ViolationEntry(&rules.Violation{Rule: "small", Kind: rules.KindCoupling, Source: "pkg", Efferent: efferent})
+	}
+	base := New([]*Entry{coupling(3), {Fingerprint: "r1", Kind: KindRule}})
+	if base.Findings[0].Count != 3 {
+		t.Fatalf("Expected the efferent coupling recorded, got %+v", base.Findings[0])
+	}
+
+	// The same package over its limit is known until its coupling grows
+	if !base.Known(coupling(3)) || !base.Known(coupling(2)) || base.Known(coupling(4)) {
+		t.Error("Expected coupling findings known up to their baselined count")
+	}
+	if !base.Known(&Entry{Fingerprint: "r1", Kind: KindRule}) || base.Known(&Entry{Fingerprint: "r2", Kind: KindRule}) {
+		t.Error("Expected findings without counts known by fingerprint")
+	}
+
+	// Tightening lowers the count, so that it cannot grow back
+	current := []*Entry{coupling(2), {Fingerprint: "r1", Kind: KindRule}}
+	lowered := base.Lowered(current, KindRule, KindCoupling)
+	if len(lowered) != 1 || lowered[0].Count != 2 {
+		t.Fatalf("Expected the coupling finding lowered to 2, got %v", lowered)
+	}
+	if base.Lowered(current, KindRule) != nil {
+		t.Error("Expected coupling findings lowered only when checked")
+	}
+	tightened := base.With(lowered)
+	if len(tightened.Findings) != 2 || tightened.Known(coupling(3)) || !tightened.Known(coupling(2)) {
+		t.Errorf("Expected the lowered count to replace the baselined one, got %v", tightened.Findings)
+	}
+	if base.With([]*Entry{{Fingerprint: "r9", Kind: KindRule}}).Contains("r9") {
+		t.Error("Expected With not to add new findings")
+	}
+}
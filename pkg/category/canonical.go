@@ -0,0 +1,67 @@
+package category
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+)
+
+// Canonical serialization
+//
+// Models are serialized canonically so that identical code always produces
+// byte-identical output and stable hashes:
+// - Objects, morphisms and identities are keyed by ID; map keys are sorted
+// - Metadata maps are serialized with sorted keys at every nesting level
+// - HTML characters (<, >, &) are not escaped, so IDs like "a->b" stay readable
+// - Numbers use encoding/json's shortest representation, so an int 3 and
+//   the float64 3 produced by decoding JSON serialize identically
+//
+// Hashes are SHA-256 digests of the compact canonical form, hex-encoded.
+
+// CanonicalJSON returns the compact canonical JSON encoding of v.
+func CanonicalJSON(v interface{}) ([]byte, error) {
+	return marshalCanonical(v, "")
+}
+
+// CanonicalJSONIndent returns the canonical JSON encoding of v, indented
+// with two spaces per level.
+func CanonicalJSONIndent(v interface{}) ([]byte, error) {
+	return marshalCanonical(v, "  ")
+}
+
+// marshalCanonical encodes v without HTML escaping and without the trailing
+// newline added by json.Encoder.
+func marshalCanonical(v interface{}, indent string) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if indent != "" {
+		enc.SetIndent("", indent)
+	}
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// canonicalHash returns the hex SHA-256 digest of v's canonical JSON.
+func canonicalHash(v interface{}) string {
+	data, err := CanonicalJSON(v)
+	if err != nil {
+		// Only unsupported metadata values (channels, functions) fail to encode
+		data = []byte(fmt.Sprintf("%#v", v))
+	}
+	hash := sha256.Sum256(data)
+	return fmt.Sprintf("%x", hash)
+}
+
+// Hash returns a content hash of the whole category.
+//
+// The hash is the full SHA-256 digest of the canonical JSON encoding of the
+// category (name, objects, morphisms and identities). Two categories have
+// the same hash exactly when their canonical serializations are identical,
+// regardless of the order in which objects and morphisms were added.
+func (c *Category) Hash() string {
+	return canonicalHash(c)
+}
@@ -0,0 +1,177 @@
+package category
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Standard constructions
+//
+// These build new categories from existing ones:
+// - Op(C): same objects, every morphism reversed
+// - Product(C, D): pairs of objects and pairs of morphisms
+// - Coproduct(C, D): disjoint union, with IDs tagged by their category
+//
+// The corresponding functors (projections, injections, the opposite functor)
+// live in the functor package.
+
+// opSuffix marks the name of an opposite category.
+const opSuffix = "^op"
+
+// Op returns the opposite category C^op.
+//
+// C^op has the same objects as C and a morphism f: B → A for every
+// morphism f: A → B of C, with the same ID and metadata. Analyzing C^op
+// answers "who depends on me" with the same code that answers "what do I
+// depend on" for C. Op is an involution: c.Op().Op() equals c.
+func (c *Category) Op() (*Category, error) {
+	name := c.Name + opSuffix
+	if strings.HasSuffix(c.Name, opSuffix) {
+		name = strings.TrimSuffix(c.Name, opSuffix)
+	}
+
+	op := NewCategory(name)
+	for _, obj := range c.Objects() {
+		if err := op.AddObject(obj); err != nil {
+			return nil, err
+		}
+	}
+	for _, m := range c.Morphisms() {
+		if m.Type == "identity" {
+			continue
+		}
+		err := op.AddMorphism(&Morphism{
+			ID:       m.ID,
+			Source:   m.Target,
+			Target:   m.Source,
+			Type:     m.Type,
+			Metadata: m.Metadata,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return op, nil
+}
+
+// ProductObjectID returns the ID of the pair (a, b) in a product category.
+func ProductObjectID(a, b string) string {
+	return fmt.Sprintf("(%s,%s)", a, b)
+}
+
+// Product returns the product category C × D.
+//
+// Objects are pairs (a, b) of objects a of C and b of D. Morphisms are pairs
+// (f, g): (a, b) → (a', b') of morphisms f: a → a' and g: b → b', where
+// either component may be an identity. Objects record their components in
+// the "left" and "right" metadata, morphisms the IDs of their component
+// morphisms. The product has |Mor(C)| × |Mor(D)| morphisms, so it is meant
+// for small categories such as abstractions, not file-level models.
+func Product(c, d *Category) (*Category, error) {
+	product := NewCategory(fmt.Sprintf("%s×%s", c.Name, d.Name))
+
+	for _, a := range c.Objects() {
+		for _, b := range d.Objects() {
+			err := product.AddObject(NewObject(
+				ProductObjectID(a.ID, b.ID),
+				"product",
+				fmt.Sprintf("(%s,%s)", a.Name, b.Name),
+				map[string]interface{}{
+					"left":  a.ID,
+					"right": b.ID,
+				},
+			))
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, f := range c.Morphisms() {
+		for _, g := range d.Morphisms() {
+			if f.Type == "identity" && g.Type == "identity" {
+				continue // Identities of pairs are created with the objects
+			}
+			err := product.AddMorphism(NewMorphism(
+				fmt.Sprintf("(%s,%s)", f.ID, g.ID),
+				ProductObjectID(f.Source, g.Source),
+				ProductObjectID(f.Target, g.Target),
+				"product",
+				map[string]interface{}{
+					"left":  f.ID,
+					"right": g.ID,
+				},
+			))
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return product, nil
+}
+
+// CoproductTags returns the tags Coproduct uses for the objects and
+// morphisms of c and d: their names, or "left" and "right" when the names
+// are empty or equal.
+func CoproductTags(c, d *Category) (string, string) {
+	if c.Name == "" || d.Name == "" || c.Name == d.Name {
+		return "left", "right"
+	}
+	return c.Name, d.Name
+}
+
+// CoproductID returns the ID of an object or morphism tagged in a coproduct.
+func CoproductID(tag, id string) string {
+	return fmt.Sprintf("%s:%s", tag, id)
+}
+
+// Coproduct returns the coproduct category C + D, the disjoint union of C
+// and D. Every object and morphism ID is tagged with its category (see
+// CoproductTags), so models with overlapping IDs stay apart. Tagged entries
+// record their "tag" and "original_id" in their metadata.
+func Coproduct(c, d *Category) (*Category, error) {
+	leftTag, rightTag := CoproductTags(c, d)
+	coproduct := NewCategory(fmt.Sprintf("%s+%s", c.Name, d.Name))
+
+	for _, part := range []struct {
+		tag string
+		cat *Category
+	}{{leftTag, c}, {rightTag, d}} {
+		for _, obj := range part.cat.Objects() {
+			metadata := tagMetadata(obj.Metadata, part.tag, obj.ID)
+			if err := coproduct.AddObject(NewObject(CoproductID(part.tag, obj.ID), obj.Type, obj.Name, metadata)); err != nil {
+				return nil, err
+			}
+		}
+		for _, m := range part.cat.Morphisms() {
+			if m.Type == "identity" {
+				continue
+			}
+			metadata := tagMetadata(m.Metadata, part.tag, m.ID)
+			err := coproduct.AddMorphism(NewMorphism(
+				CoproductID(part.tag, m.ID),
+				CoproductID(part.tag, m.Source),
+				CoproductID(part.tag, m.Target),
+				m.Type,
+				metadata,
+			))
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return coproduct, nil
+}
+
+// tagMetadata copies metadata and records the coproduct tag and original ID.
+func tagMetadata(metadata map[string]interface{}, tag, id string) map[string]interface{} {
+	tagged := make(map[string]interface{}, len(metadata)+2)
+	for k, v := range metadata {
+		tagged[k] = v
+	}
+	tagged["tag"] = tag
+	tagged["original_id"] = id
+	return tagged
+}
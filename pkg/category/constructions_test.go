@@ -0,0 +1,110 @@
+package category_test
+
+import (
+	"testing"
+
+	"github.com/manu/catreview/internal/modeltest"
+	"github.com/manu/catreview/pkg/category"
+)
+
+// chain returns the category A → B → C with the given name.
+func chain(t *testing.T, name string) *category.Category {
+	return modeltest.Build(t, name, []*category.Object{
+		category.NewObject("A", "module", "A", nil),
+		category.NewObject("B", "module", "B", nil),
+		category.NewObject("C", "module", "C", nil),
+	}, []*category.Morphism{
+		category.NewMorphism("f", "A", "B", "dependency", nil),
+		category.NewMorphism("g", "B", "C", "dependency", nil),
+	})
+}
+
+func TestOp(t *testing.T) {
+	cat := chain(t, "test")
+	op, err := cat.Op()
+	if err != nil {
+		t.Fatalf("Op failed: %v", err)
+	}
+
+	if op.Name != "test^op" {
+		t.Errorf("Expected name 'test^op', got '%s'", op.Name)
+	}
+	f, exists := op.GetMorphism("f")
+	if !exists {
+		t.Fatal("Expected morphism f in opposite category")
+	}
+	if f.Source != "B" || f.Target != "A" {
+		t.Errorf("Expected f: B → A, got %s → %s", f.Source, f.Target)
+	}
+	if err := op.VerifyAxioms(); err != nil {
+		t.Errorf("Opposite category violates axioms: %v", err)
+	}
+
+	// Op is an involution
+	if opop, err := op.Op(); err != nil || opop.Hash() != cat.Hash() {
+		t.Error("Expected Op().Op() to equal the original category")
+	}
+}
+
+func TestProduct(t *testing.T) {
+	c := chain(t, "C")
+	d := modeltest.Build(t, "D", []*category.Object{
+		category.NewObject("X", "module", "X", nil),
+		category.NewObject("Y", "module", "Y", nil),
+	}, []*category.Morphism{
+		category.NewMorphism("h", "X", "Y", "dependency", nil),
+	})
+
+	product, err := category.Product(c, d)
+	if err != nil {
+		t.Fatalf("Product failed: %v", err)
+	}
+
+	stats := product.Stats()
+	if stats["objects"] != 6 {
+		t.Errorf("Expected 6 objects, got %d", stats["objects"])
+	}
+	// (2 + 3 identities) × (1 + 2 identities) pairs, minus the 6 identity pairs
+	if stats["morphisms"] != 15-6 {
+		t.Errorf("Expected 9 morphisms, got %d", stats["morphisms"])
+	}
+
+	m, exists := product.GetMorphism("(f,h)")
+	if !exists {
+		t.Fatal("Expected morphism (f,h)")
+	}
+	if m.Source != category.ProductObjectID("A", "X") || m.Target != category.ProductObjectID("B", "Y") {
+		t.Errorf("Expected (A,X) → (B,Y), got %s → %s", m.Source, m.Target)
+	}
+	if err := product.VerifyAxioms(); err != nil {
+		t.Errorf("Product violates axioms: %v", err)
+	}
+}
+
+func TestCoproduct(t *testing.T) {
+	left := chain(t, "svc-a")
+	right := chain(t, "svc-b")
+
+	coproduct, err := category.Coproduct(left, right)
+	if err != nil {
+		t.Fatalf("Coproduct failed: %v", err)
+	}
+
+	stats := coproduct.Stats()
+	if stats["objects"] != 6 || stats["morphisms"] != 4 {
+		t.Errorf("Expected 6 objects and 4 morphisms, got %d and %d", stats["objects"], stats["morphisms"])
+	}
+	obj, exists := coproduct.GetObject("svc-b:A")
+	if !exists {
+		t.Fatal("Expected tagged object svc-b:A")
+	}
+	if obj.Metadata["original_id"] != "A" || obj.Metadata["tag"] != "svc-b" {
+		t.Errorf("Expected tag metadata, got %v", obj.Metadata)
+	}
+
+	// Categories with the same name fall back to positional tags
+	leftTag, rightTag := category.CoproductTags(left, left)
+	if leftTag != "left" || rightTag != "right" {
+		t.Errorf("Expected left/right tags, got %s/%s", leftTag, rightTag)
+	}
+}
@@ -0,0 +1,138 @@
+package category
+
+import (
+	"bytes"
+)
+
+// ObjectChange describes an object present in both categories whose
+// type, name or metadata differs.
+type ObjectChange struct {
+	ID     string   `json:"id"`
+	Fields []string `json:"fields"` // Changed fields: "type", "name", "metadata"
+	Before *Object  `json:"before"`
+	After  *Object  `json:"after"`
+}
+
+// MorphismChange describes a morphism present in both categories whose
+// endpoints, type or metadata differ.
+type MorphismChange struct {
+	ID     string    `json:"id"`
+	Fields []string  `json:"fields"` // Changed fields: "source", "target", "type", "metadata"
+	Before *Morphism `json:"before"`
+	After  *Morphism `json:"after"`
+}
+
+// Delta is the difference between two categories.
+//
+// Objects and morphisms are matched by ID. Identity morphisms are derived
+// from objects and are not reported separately. All slices are sorted by ID.
+type Delta struct {
+	AddedObjects     []*Object         `json:"added_objects"`
+	RemovedObjects   []*Object         `json:"removed_objects"`
+	ChangedObjects   []*ObjectChange   `json:"changed_objects"`
+	AddedMorphisms   []*Morphism       `json:"added_morphisms"`
+	RemovedMorphisms []*Morphism       `json:"removed_morphisms"`
+	ChangedMorphisms []*MorphismChange `json:"changed_morphisms"`
+}
+
+// Diff computes the changes needed to turn before into after.
+func Diff(before, after *Category) *Delta {
+	delta := &Delta{
+		AddedObjects:     []*Object{},
+		RemovedObjects:   []*Object{},
+		ChangedObjects:   []*ObjectChange{},
+		AddedMorphisms:   []*Morphism{},
+		RemovedMorphisms: []*Morphism{},
+		ChangedMorphisms: []*MorphismChange{},
+	}
+
+	// Objects
+	for _, obj := range after.Objects() {
+		old, exists := before.GetObject(obj.ID)
+		if !exists {
+			delta.AddedObjects = append(delta.AddedObjects, obj)
+			continue
+		}
+		var fields []string
+		if old.Type != obj.Type {
+			fields = append(fields, "type")
+		}
+		if old.Name != obj.Name {
+			fields = append(fields, "name")
+		}
+		if !sameMetadata(old.Metadata, obj.Metadata) {
+			fields = append(fields, "metadata")
+		}
+		if len(fields) > 0 {
+			delta.ChangedObjects = append(delta.ChangedObjects, &ObjectChange{
+				ID: obj.ID, Fields: fields, Before: old, After: obj,
+			})
+		}
+	}
+	for _, obj := range before.Objects() {
+		if _, exists := after.GetObject(obj.ID); !exists {
+			delta.RemovedObjects = append(delta.RemovedObjects, obj)
+		}
+	}
+
+	// Morphisms
+	for _, m := range after.Morphisms() {
+		if m.Type == "identity" {
+			continue
+		}
+		old, exists := before.GetMorphism(m.ID)
+		if !exists {
+			delta.AddedMorphisms = append(delta.AddedMorphisms, m)
+			continue
+		}
+		var fields []string
+		if old.Source != m.Source {
+			fields = append(fields, "source")
+		}
+		if old.Target != m.Target {
+			fields = append(fields, "target")
+		}
+		if old.Type != m.Type {
+			fields = append(fields, "type")
+		}
+		if !sameMetadata(old.Metadata, m.Metadata) {
+			fields = append(fields, "metadata")
+		}
+		if len(fields) > 0 {
+			delta.ChangedMorphisms = append(delta.ChangedMorphisms, &MorphismChange{
+				ID: m.ID, Fields: fields, Before: old, After: m,
+			})
+		}
+	}
+	for _, m := range before.Morphisms() {
+		if m.Type == "identity" {
+			continue
+		}
+		if _, exists := after.GetMorphism(m.ID); !exists {
+			delta.RemovedMorphisms = append(delta.RemovedMorphisms, m)
+		}
+	}
+
+	return delta
+}
+
+// IsEmpty reports whether the two categories are identical.
+func (d *Delta) IsEmpty() bool {
+	return len(d.AddedObjects) == 0 && len(d.RemovedObjects) == 0 &&
+		len(d.ChangedObjects) == 0 && len(d.AddedMorphisms) == 0 &&
+		len(d.RemovedMorphisms) == 0 && len(d.ChangedMorphisms) == 0
+}
+
+// sameMetadata compares metadata by canonical encoding, so values decoded
+// from JSON compare equal to the values they were encoded from.
+func sameMetadata(a, b map[string]interface{}) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	da, errA := CanonicalJSON(a)
+	db, errB := CanonicalJSON(b)
+	if errA != nil || errB != nil {
+		return false
+	}
+	return bytes.Equal(da, db)
+}
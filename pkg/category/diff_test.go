@@ -0,0 +1,78 @@
+package category_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/manu/catreview/internal/modeltest"
+	"github.com/manu/catreview/pkg/category"
+)
+
+func TestDiff(t *testing.T) {
+	before := modeltest.Build(t, "before", []*category.Object{
+		category.NewObject("A", "file", "a.go", map[string]interface{}{"lines": 10}),
+		category.NewObject("B", "file", "b.go", nil),
+		category.NewObject("C", "file", "c.go", nil),
+	}, []*category.Morphism{
+		category.NewMorphism("f", "A", "B", "import", nil),
+		category.NewMorphism("g", "B", "C", "import", nil),
+	})
+
+	after := modeltest.Build(t, "after", []*category.Object{
+		category.NewObject("A", "file", "a.go", map[string]interface{}{"lines": 12}),
+		category.NewObject("B", "file", "b.go", nil),
+		category.NewObject("D", "file", "d.go", nil),
+	}, []*category.Morphism{
+		category.NewMorphism("f", "A", "B", "function_call", nil),
+		category.NewMorphism("h", "B", "D", "import", nil),
+	})
+
+	delta := category.Diff(before, after)
+
+	if len(delta.AddedObjects) != 1 || delta.AddedObjects[0].ID != "D" {
+		t.Errorf("Expected added object D, got %v", delta.AddedObjects)
+	}
+	if len(delta.RemovedObjects) != 1 || delta.RemovedObjects[0].ID != "C" {
+		t.Errorf("Expected removed object C, got %v", delta.RemovedObjects)
+	}
+	if len(delta.ChangedObjects) != 1 || delta.ChangedObjects[0].ID != "A" {
+		t.Fatalf("Expected changed object A, got %v", delta.ChangedObjects)
+	}
+	if fields := delta.ChangedObjects[0].Fields; len(fields) != 1 || fields[0] != "metadata" {
+		t.Errorf("Expected metadata change on A, got %v", fields)
+	}
+	if len(delta.AddedMorphisms) != 1 || delta.AddedMorphisms[0].ID != "h" {
+		t.Errorf("Expected added morphism h, got %v", delta.AddedMorphisms)
+	}
+	if len(delta.RemovedMorphisms) != 1 || delta.RemovedMorphisms[0].ID != "g" {
+		t.Errorf("Expected removed morphism g, got %v", delta.RemovedMorphisms)
+	}
+	if len(delta.ChangedMorphisms) != 1 || delta.ChangedMorphisms[0].Fields[0] != "type" {
+		t.Errorf("Expected type change on f, got %v", delta.ChangedMorphisms)
+	}
+}
+
+func TestDiffAfterRoundTrip(t *testing.T) {
+	cat := modeltest.Build(t, "test", []*category.Object{
+		category.NewObject("A", "file", "a.go", map[string]interface{}{
+			"lines":   42,
+			"authors": []string{"alice", "bob"},
+		}),
+		category.NewObject("B", "file", "b.go", nil),
+	}, []*category.Morphism{
+		category.NewMorphism("f", "A", "B", "import", nil),
+	})
+
+	data, err := json.Marshal(cat)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	var decoded category.Category
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	if delta := category.Diff(cat, &decoded); !delta.IsEmpty() {
+		t.Errorf("Expected no changes after JSON round trip, got %+v", delta)
+	}
+}
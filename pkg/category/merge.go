@@ -0,0 +1,338 @@
+package category
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ConflictPolicy decides how Merge resolves an ID or metadata key that
+// occurs with different content in more than one model.
+type ConflictPolicy string
+
+const (
+	// PreferLeft keeps the entry from the earliest model.
+	PreferLeft ConflictPolicy = "prefer-left"
+	// Union combines the entries: objects and morphisms keep the earliest
+	// type, name and endpoints and merge their metadata; metadata values
+	// are combined into a list of distinct values.
+	Union ConflictPolicy = "union"
+	// NamespacePrefix keeps both entries by prefixing the later one with
+	// its model's namespace ("<namespace>::<id>").
+	NamespacePrefix ConflictPolicy = "namespace-prefix"
+)
+
+// MergeOptions configures Merge.
+type MergeOptions struct {
+	Name       string         // Name of the merged category
+	Objects    ConflictPolicy // Object ID collisions (default PreferLeft)
+	Morphisms  ConflictPolicy // Morphism ID collisions (default PreferLeft)
+	Metadata   ConflictPolicy // Metadata key collisions when entries are merged (default PreferLeft)
+	Namespaces []string       // Namespace per model (default: model names)
+}
+
+// MergeConflict records a collision and how it was resolved.
+type MergeConflict struct {
+	Kind       string `json:"kind"` // "object", "morphism" or "metadata"
+	ID         string `json:"id"`   // Object or morphism ID in the earlier model
+	Key        string `json:"key,omitempty"`
+	Model      string `json:"model"`      // Namespace of the later, conflicting model
+	Resolution string `json:"resolution"` // "kept-left", "merged" or "renamed:<id>"
+}
+
+// MergeReport summarizes a merge.
+type MergeReport struct {
+	Models    []string         `json:"models"`
+	Objects   int              `json:"objects"`
+	Morphisms int              `json:"morphisms"`
+	Conflicts []*MergeConflict `json:"conflicts"`
+}
+
+// Merge combines several models into one category.
+//
+// Objects and morphisms are matched by ID. Entries with identical content
+// are merged silently; entries that differ are resolved according to the
+// policies in opts and recorded in the report. When an object is renamed
+// under NamespacePrefix, the morphisms of its model that touch it are
+// renamed with it, since they are no longer the same arrows. Identity
+// morphisms are recreated for the merged objects. The input models are not
+// modified.
+func Merge(opts MergeOptions, models ...*Category) (*Category, *MergeReport, error) {
+	if len(models) == 0 {
+		return nil, nil, fmt.Errorf("no models to merge")
+	}
+	for _, p := range []*ConflictPolicy{&opts.Objects, &opts.Morphisms, &opts.Metadata} {
+		if *p == "" {
+			*p = PreferLeft
+		}
+		if *p != PreferLeft && *p != Union && *p != NamespacePrefix {
+			return nil, nil, fmt.Errorf("unknown conflict policy: %s", *p)
+		}
+	}
+	namespaces, err := mergeNamespaces(opts.Namespaces, models)
+	if err != nil {
+		return nil, nil, err
+	}
+	if opts.Name == "" {
+		opts.Name = "merged"
+	}
+
+	m := &merger{
+		opts:      opts,
+		objects:   make(map[string]*Object),
+		morphisms: make(map[string]*Morphism),
+		report:    &MergeReport{Models: namespaces, Conflicts: []*MergeConflict{}},
+	}
+	for i, model := range models {
+		if err := m.add(model, namespaces[i]); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	merged := NewCategory(opts.Name)
+	objectIDs := make([]string, 0, len(m.objects))
+	for id := range m.objects {
+		objectIDs = append(objectIDs, id)
+	}
+	sort.Strings(objectIDs)
+	for _, id := range objectIDs {
+		if err := merged.AddObject(m.objects[id]); err != nil {
+			return nil, nil, err
+		}
+	}
+	morphismIDs := make([]string, 0, len(m.morphisms))
+	for id := range m.morphisms {
+		morphismIDs = append(morphismIDs, id)
+	}
+	sort.Strings(morphismIDs)
+	for _, id := range morphismIDs {
+		if err := merged.AddMorphism(m.morphisms[id]); err != nil {
+			return nil, nil, fmt.Errorf("merged morphism %s: %v", id, err)
+		}
+	}
+
+	stats := merged.Stats()
+	m.report.Objects = stats["objects"]
+	m.report.Morphisms = stats["morphisms"]
+	return merged, m.report, nil
+}
+
+// merger holds the state of a merge in progress.
+type merger struct {
+	opts      MergeOptions
+	objects   map[string]*Object
+	morphisms map[string]*Morphism
+	report    *MergeReport
+}
+
+// add merges one model into the accumulated state.
+func (m *merger) add(model *Category, ns string) error {
+	renamed := make(map[string]string)
+
+	for _, obj := range model.Objects() {
+		existing, exists := m.objects[obj.ID]
+		if !exists {
+			m.objects[obj.ID] = copyObject(obj)
+			continue
+		}
+		if existing.Type == obj.Type && existing.Name == obj.Name && sameMetadata(existing.Metadata, obj.Metadata) {
+			continue
+		}
+
+		switch m.opts.Objects {
+		case PreferLeft:
+			m.conflict("object", obj.ID, "", ns, "kept-left")
+		case Union:
+			m.mergeMetadata(existing.Metadata, obj.Metadata, obj.ID, ns)
+			m.conflict("object", obj.ID, "", ns, "merged")
+		case NamespacePrefix:
+			newID := ns + "::" + obj.ID
+			if _, taken := m.objects[newID]; taken {
+				return fmt.Errorf("cannot rename object %s: %s already exists", obj.ID, newID)
+			}
+			renamed[obj.ID] = newID
+			copied := copyObject(obj)
+			copied.ID = newID
+			m.objects[newID] = copied
+			m.conflict("object", obj.ID, "", ns, "renamed:"+newID)
+		}
+	}
+
+	for _, morph := range model.Morphisms() {
+		if morph.Type == "identity" {
+			continue
+		}
+		copied := copyMorphism(morph)
+		source, sourceRenamed := renamed[morph.Source]
+		target, targetRenamed := renamed[morph.Target]
+		if sourceRenamed {
+			copied.Source = source
+		}
+		if targetRenamed {
+			copied.Target = target
+		}
+		if sourceRenamed || targetRenamed {
+			copied.ID = ns + "::" + morph.ID
+		}
+
+		existing, exists := m.morphisms[copied.ID]
+		if !exists {
+			m.morphisms[copied.ID] = copied
+			continue
+		}
+		if existing.Source == copied.Source && existing.Target == copied.Target &&
+			existing.Type == copied.Type && sameMetadata(existing.Metadata, copied.Metadata) {
+			continue
+		}
+
+		switch m.opts.Morphisms {
+		case PreferLeft:
+			m.conflict("morphism", copied.ID, "", ns, "kept-left")
+		case Union:
+			if existing.Source != copied.Source || existing.Target != copied.Target || existing.Type != copied.Type {
+				// Arrows with different endpoints cannot be combined
+				m.conflict("morphism", copied.ID, "", ns, "kept-left")
+				continue
+			}
+			m.mergeMetadata(existing.Metadata, copied.Metadata, copied.ID, ns)
+			m.conflict("morphism", copied.ID, "", ns, "merged")
+		case NamespacePrefix:
+			newID := ns + "::" + copied.ID
+			if _, taken := m.morphisms[newID]; taken {
+				return fmt.Errorf("cannot rename morphism %s: %s already exists", copied.ID, newID)
+			}
+			m.conflict("morphism", copied.ID, "", ns, "renamed:"+newID)
+			copied.ID = newID
+			m.morphisms[newID] = copied
+		}
+	}
+
+	return nil
+}
+
+// mergeMetadata merges src into dst, resolving key collisions according to
+// the metadata policy.
+func (m *merger) mergeMetadata(dst, src map[string]interface{}, id, ns string) {
+	keys := make([]string, 0, len(src))
+	for key := range src {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := src[key]
+		existing, exists := dst[key]
+		if !exists {
+			dst[key] = value
+			continue
+		}
+		if sameValue(existing, value) {
+			continue
+		}
+
+		switch m.opts.Metadata {
+		case PreferLeft:
+			m.conflict("metadata", id, key, ns, "kept-left")
+		case Union:
+			dst[key] = unionValues(existing, value)
+			m.conflict("metadata", id, key, ns, "merged")
+		case NamespacePrefix:
+			newKey := ns + "::" + key
+			dst[newKey] = value
+			m.conflict("metadata", id, key, ns, "renamed:"+newKey)
+		}
+	}
+}
+
+// conflict records a resolved collision.
+func (m *merger) conflict(kind, id, key, ns, resolution string) {
+	m.report.Conflicts = append(m.report.Conflicts, &MergeConflict{
+		Kind:       kind,
+		ID:         id,
+		Key:        key,
+		Model:      ns,
+		Resolution: resolution,
+	})
+}
+
+// mergeNamespaces returns one unique namespace per model.
+func mergeNamespaces(given []string, models []*Category) ([]string, error) {
+	if len(given) > 0 && len(given) != len(models) {
+		return nil, fmt.Errorf("got %d namespaces for %d models", len(given), len(models))
+	}
+
+	namespaces := make([]string, len(models))
+	seen := make(map[string]bool)
+	for i, model := range models {
+		ns := model.Name
+		if len(given) > 0 {
+			ns = given[i]
+		}
+		if ns == "" || seen[ns] {
+			if len(given) > 0 {
+				return nil, fmt.Errorf("namespace %q is empty or not unique", ns)
+			}
+			ns = fmt.Sprintf("m%d", i)
+		}
+		seen[ns] = true
+		namespaces[i] = ns
+	}
+	return namespaces, nil
+}
+
+// copyObject returns a copy of obj with its own metadata map.
+func copyObject(obj *Object) *Object {
+	metadata := make(map[string]interface{}, len(obj.Metadata))
+	for k, v := range obj.Metadata {
+		metadata[k] = v
+	}
+	return NewObject(obj.ID, obj.Type, obj.Name, metadata)
+}
+
+// copyMorphism returns a copy of m with its own metadata map.
+func copyMorphism(m *Morphism) *Morphism {
+	metadata := make(map[string]interface{}, len(m.Metadata))
+	for k, v := range m.Metadata {
+		metadata[k] = v
+	}
+	return NewMorphism(m.ID, m.Source, m.Target, m.Type, metadata)
+}
+
+// sameValue compares two metadata values by canonical encoding.
+func sameValue(a, b interface{}) bool {
+	return sameMetadata(map[string]interface{}{"v": a}, map[string]interface{}{"v": b})
+}
+
+// unionValues combines two metadata values into a list of distinct values.
+// List values contribute their elements.
+func unionValues(a, b interface{}) []interface{} {
+	var values []interface{}
+	for _, v := range append(listElements(a), listElements(b)...) {
+		duplicate := false
+		for _, seen := range values {
+			if sameValue(seen, v) {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// listElements returns the elements of a list value, or the value itself.
+func listElements(v interface{}) []interface{} {
+	switch list := v.(type) {
+	case []interface{}:
+		return list
+	case []string:
+		elems := make([]interface{}, len(list))
+		for i, s := range list {
+			elems[i] = s
+		}
+		return elems
+	default:
+		return []interface{}{v}
+	}
+}
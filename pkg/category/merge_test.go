@@ -0,0 +1,121 @@
+package category_test
+
+import (
+	"testing"
+
+	"github.com/manu/catreview/internal/modeltest"
+	"github.com/manu/catreview/pkg/category"
+)
+
+// mergeFixtures returns two models that share an identical object, a
+// conflicting object and a conflicting morphism.
+func mergeFixtures(t *testing.T) (*category.Category, *category.Category) {
+	a := modeltest.Build(t, "svc-a", []*category.Object{
+		category.NewObject("main.go", "file", "main.go", map[string]interface{}{"package": "main", "lines": 10}),
+		category.NewObject("import:fmt", "imported_package", "fmt", nil),
+	}, []*category.Morphism{
+		category.NewMorphism("import:main.go->fmt", "main.go", "import:fmt", "import", nil),
+	})
+
+	b := modeltest.Build(t, "svc-b", []*category.Object{
+		category.NewObject("main.go", "file", "main.go", map[string]interface{}{"package": "main", "lines": 20}),
+		category.NewObject("import:fmt", "imported_package", "fmt", nil),
+	}, []*category.Morphism{
+		category.NewMorphism("import:main.go->fmt", "main.go", "import:fmt", "import", nil),
+	})
+
+	return a, b
+}
+
+func TestMergePreferLeft(t *testing.T) {
+	a, b := mergeFixtures(t)
+
+	merged, report, err := category.Merge(category.MergeOptions{}, a, b)
+	if err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+
+	if report.Objects != 2 || report.Morphisms != 1 {
+		t.Errorf("Expected 2 objects and 1 morphism, got %d and %d", report.Objects, report.Morphisms)
+	}
+	obj, _ := merged.GetObject("main.go")
+	if obj.Metadata["lines"] != 10 {
+		t.Errorf("Expected left metadata to win, got lines=%v", obj.Metadata["lines"])
+	}
+	if len(report.Conflicts) != 1 || report.Conflicts[0].Resolution != "kept-left" {
+		t.Errorf("Expected one kept-left conflict, got %+v", report.Conflicts)
+	}
+	if err := merged.VerifyAxioms(); err != nil {
+		t.Errorf("Merged category violates axioms: %v", err)
+	}
+}
+
+func TestMergeUnion(t *testing.T) {
+	a, b := mergeFixtures(t)
+
+	merged, report, err := category.Merge(category.MergeOptions{Objects: category.Union, Metadata: category.Union}, a, b)
+	if err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+
+	obj, _ := merged.GetObject("main.go")
+	lines, ok := obj.Metadata["lines"].([]interface{})
+	if !ok || len(lines) != 2 {
+		t.Errorf("Expected lines to hold both values, got %v", obj.Metadata["lines"])
+	}
+
+	// Inputs must not be modified
+	left, _ := a.GetObject("main.go")
+	if left.Metadata["lines"] != 10 {
+		t.Errorf("Merge modified its input: lines=%v", left.Metadata["lines"])
+	}
+
+	kinds := map[string]int{}
+	for _, c := range report.Conflicts {
+		kinds[c.Kind]++
+	}
+	if kinds["object"] != 1 || kinds["metadata"] != 1 {
+		t.Errorf("Expected one object and one metadata conflict, got %v", kinds)
+	}
+}
+
+func TestMergeNamespacePrefix(t *testing.T) {
+	a, b := mergeFixtures(t)
+
+	merged, report, err := category.Merge(category.MergeOptions{Objects: category.NamespacePrefix}, a, b)
+	if err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+
+	if _, exists := merged.GetObject("svc-b::main.go"); !exists {
+		t.Fatal("Expected conflicting object to be renamed to svc-b::main.go")
+	}
+	if _, exists := merged.GetObject("svc-b::import:fmt"); exists {
+		t.Error("Identical objects should not be renamed")
+	}
+
+	m, exists := merged.GetMorphism("svc-b::import:main.go->fmt")
+	if !exists {
+		t.Fatal("Expected morphism of renamed object to be renamed")
+	}
+	if m.Source != "svc-b::main.go" || m.Target != "import:fmt" {
+		t.Errorf("Expected svc-b::main.go -> import:fmt, got %s -> %s", m.Source, m.Target)
+	}
+	if report.Objects != 3 || report.Morphisms != 2 {
+		t.Errorf("Expected 3 objects and 2 morphisms, got %d and %d", report.Objects, report.Morphisms)
+	}
+}
+
+func TestMergeInvalidOptions(t *testing.T) {
+	a, b := mergeFixtures(t)
+
+	if _, _, err := category.Merge(category.MergeOptions{}); err == nil {
+		t.Error("Expected error when merging no models")
+	}
+	if _, _, err := category.Merge(category.MergeOptions{Objects: "newest"}, a, b); err == nil {
+		t.Error("Expected error for unknown policy")
+	}
+	if _, _, err := category.Merge(category.MergeOptions{Namespaces: []string{"x", "x"}}, a, b); err == nil {
+		t.Error("Expected error for duplicate namespaces")
+	}
+}
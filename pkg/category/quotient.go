@@ -0,0 +1,178 @@
+package category
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// KeyFunc defines an equivalence relation on objects: objects with the same
+// key are identified in the quotient. An empty key leaves the object in a
+// class of its own.
+type KeyFunc func(obj *Object) string
+
+// Class returns the ID of the equivalence class of obj in the quotient.
+func (k KeyFunc) Class(obj *Object) string {
+	if key := k(obj); key != "" {
+		return key
+	}
+	return obj.ID
+}
+
+// QuotientMorphismID returns the ID of the aggregated morphism between two
+// classes of a quotient.
+func QuotientMorphismID(source, target string) string {
+	return fmt.Sprintf("dep:%s->%s", source, target)
+}
+
+// Quotient returns the quotient category C/~ for the equivalence relation
+// defined by key.
+//
+// Each equivalence class becomes an object whose ID is the class key, with
+// the sorted IDs of its members in the "members" metadata and the number of
+// morphisms inside the class in "internal_morphisms". All morphisms between
+// two classes are aggregated into a single morphism carrying their
+// "multiplicity", the sorted IDs of the underlying "morphisms" and their
+// distinct "types"; it keeps the underlying type when all agree and is a
+// "dependency" otherwise. Morphisms within a class collapse into the
+// class's identity.
+func Quotient(c *Category, key KeyFunc) *Category {
+	quotient := NewCategory(c.Name + "/~")
+
+	classes := make(map[string]string) // Object ID -> class ID
+	members := make(map[string][]string)
+	for _, obj := range c.Objects() {
+		class := key.Class(obj)
+		classes[obj.ID] = class
+		members[class] = append(members[class], obj.ID)
+	}
+
+	internal := make(map[string]int)
+	type aggregate struct {
+		source, target string
+		morphisms      []string
+		types          map[string]bool
+	}
+	aggregates := make(map[string]*aggregate)
+	for _, m := range c.Morphisms() {
+		if m.Type == "identity" {
+			continue
+		}
+		source, target := classes[m.Source], classes[m.Target]
+		if source == target {
+			internal[source]++
+			continue
+		}
+		id := QuotientMorphismID(source, target)
+		agg, exists := aggregates[id]
+		if !exists {
+			agg = &aggregate{source: source, target: target, types: make(map[string]bool)}
+			aggregates[id] = agg
+		}
+		agg.morphisms = append(agg.morphisms, m.ID)
+		agg.types[m.Type] = true
+	}
+
+	classIDs := make([]string, 0, len(members))
+	for class := range members {
+		classIDs = append(classIDs, class)
+	}
+	sort.Strings(classIDs)
+	for _, class := range classIDs {
+		name := class
+		objType := "class"
+		if ids := members[class]; len(ids) == 1 && ids[0] == class {
+			// Singleton classes keep the original object's identity
+			obj, _ := c.GetObject(class)
+			name, objType = obj.Name, obj.Type
+		}
+		quotient.AddObject(NewObject(class, objType, name, map[string]interface{}{
+			"members":            members[class],
+			"internal_morphisms": internal[class],
+		}))
+	}
+
+	for id, agg := range aggregates {
+		types := make([]string, 0, len(agg.types))
+		for t := range agg.types {
+			types = append(types, t)
+		}
+		sort.Strings(types)
+		morphType := "dependency"
+		if len(types) == 1 {
+			morphType = types[0]
+		}
+		quotient.AddMorphism(NewMorphism(id, agg.source, agg.target, morphType, map[string]interface{}{
+			"multiplicity": len(agg.morphisms),
+			"morphisms":    agg.morphisms,
+			"types":        types,
+		}))
+	}
+
+	return quotient
+}
+
+// ByMetadata identifies objects with the same value for a metadata key,
+// e.g. "package" or "owner". Class IDs are "<key>:<value>".
+func ByMetadata(key string) KeyFunc {
+	return func(obj *Object) string {
+		value, ok := obj.Metadata[key]
+		if !ok || value == nil || value == "" {
+			return ""
+		}
+		return fmt.Sprintf("%s:%v", key, value)
+	}
+}
+
+// ByDirectory identifies objects defined in the same directory, truncated to
+// its first depth segments when depth > 0. File objects use their own
+// directory, other objects the directory of their "file" metadata, both
+// relative to the extraction root. Class IDs are "dir:<directory>".
+func ByDirectory(depth int) KeyFunc {
+	return func(obj *Object) string {
+		file := obj.ID
+		if obj.Type != "file" {
+			file, _ = obj.Metadata["file"].(string)
+		}
+		if file == "" {
+			return ""
+		}
+
+		dir := filepath.ToSlash(filepath.Dir(file))
+		if depth > 0 {
+			segments := strings.Split(dir, "/")
+			if len(segments) > depth {
+				dir = strings.Join(segments[:depth], "/")
+			}
+		}
+		return "dir:" + dir
+	}
+}
+
+// ByRegex identifies objects whose IDs produce the same key from a regular
+// expression. The key is template expanded with the match (see
+// regexp.Regexp.Expand); an empty template uses the first capture group, or
+// the whole match if the pattern has no groups. Objects that do not match
+// stay in classes of their own.
+func ByRegex(pattern, template string) (KeyFunc, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key pattern: %v", err)
+	}
+	if template == "" {
+		template = "$0"
+		if re.NumSubexp() > 0 {
+			template = "${1}"
+		}
+	}
+
+	return func(obj *Object) string {
+		match := re.FindStringSubmatchIndex(obj.ID)
+		if match == nil {
+			return ""
+		}
+		return string(re.ExpandString(nil, template, obj.ID, match))
+	}, nil
+}
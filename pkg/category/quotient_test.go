@@ -0,0 +1,110 @@
+package category_test
+
+import (
+	"testing"
+
+	"github.com/manu/catreview/internal/modeltest"
+	"github.com/manu/catreview/pkg/category"
+	"github.com/manu/catreview/pkg/extractor"
+)
+
+// quotientFixture returns files in two directories with imports between them.
+func quotientFixture(t *testing.T) *category.Category {
+	return modeltest.Build(t, "files", []*category.Object{
+		category.NewObject("svc/api/a.go", "file", "a.go", map[string]interface{}{"package": "api"}),
+		category.NewObject("svc/api/b.go", "file", "b.go", map[string]interface{}{"package": "api"}),
+		category.NewObject("svc/store/c.go", "file", "c.go", map[string]interface{}{"package": "store"}),
+		category.NewObject("import:fmt", "imported_package", "fmt", nil),
+	}, []*category.Morphism{
+		category.NewMorphism("m1", "svc/api/a.go", "svc/store/c.go", "import", nil),
+		category.NewMorphism("m2", "svc/api/b.go", "svc/store/c.go", "function_call", nil),
+		category.NewMorphism("m3", "svc/api/a.go", "svc/api/b.go", "function_call", nil),
+		category.NewMorphism("m4", "svc/store/c.go", "import:fmt", "import", nil),
+	})
+}
+
+func TestQuotientByMetadata(t *testing.T) {
+	quotient := category.Quotient(quotientFixture(t), category.ByMetadata("package"))
+
+	stats := quotient.Stats()
+	if stats["objects"] != 3 || stats["morphisms"] != 2 {
+		t.Errorf("Expected 3 objects and 2 morphisms, got %d and %d", stats["objects"], stats["morphisms"])
+	}
+
+	api, exists := quotient.GetObject("package:api")
+	if !exists {
+		t.Fatal("Expected class package:api")
+	}
+	if members := api.Metadata["members"].([]string); len(members) != 2 {
+		t.Errorf("Expected 2 members, got %v", members)
+	}
+	if api.Metadata["internal_morphisms"] != 1 {
+		t.Errorf("Expected 1 internal morphism, got %v", api.Metadata["internal_morphisms"])
+	}
+
+	dep, exists := quotient.GetMorphism(category.QuotientMorphismID("package:api", "package:store"))
+	if !exists {
+		t.Fatal("Expected aggregated morphism package:api -> package:store")
+	}
+	if dep.Metadata["multiplicity"] != 2 {
+		t.Errorf("Expected multiplicity 2, got %v", dep.Metadata["multiplicity"])
+	}
+	if dep.Type != "dependency" {
+		t.Errorf("Expected mixed types to aggregate as 'dependency', got '%s'", dep.Type)
+	}
+
+	// Objects without a key stay distinct and keep their type
+	fmtObj, exists := quotient.GetObject("import:fmt")
+	if !exists || fmtObj.Type != "imported_package" {
+		t.Errorf("Expected singleton import:fmt to keep its type, got %v", fmtObj)
+	}
+
+	if err := quotient.VerifyAxioms(); err != nil {
+		t.Errorf("Quotient violates axioms: %v", err)
+	}
+}
+
+func TestQuotientKeyFuncs(t *testing.T) {
+	obj := category.NewObject("svc/api/handlers/a.go", "file", "a.go", nil)
+
+	if key := category.ByDirectory(0)(obj); key != "dir:svc/api/handlers" {
+		t.Errorf("Expected 'dir:svc/api/handlers', got '%s'", key)
+	}
+	if key := category.ByDirectory(2)(obj); key != "dir:svc/api" {
+		t.Errorf("Expected 'dir:svc/api', got '%s'", key)
+	}
+
+	byService, err := category.ByRegex(`^svc/([^/]+)/`, "service:$1")
+	if err != nil {
+		t.Fatalf("ByRegex failed: %v", err)
+	}
+	if key := byService(obj); key != "service:api" {
+		t.Errorf("Expected 'service:api', got '%s'", key)
+	}
+	if key := byService(category.NewObject("main.go", "file", "main.go", nil)); key != "" {
+		t.Errorf("Expected no key for non-matching ID, got '%s'", key)
+	}
+
+	if _, err := category.ByRegex(`(`, ""); err == nil {
+		t.Error("Expected error for invalid pattern")
+	}
+}
+
+func TestQuotientByDirectoryExtracted(t *testing.T) {
+	// Extracted from an absolute path, depth counts segments below the root
+	cat, err := extractor.NewGoExtractor().ExtractFromPath(modeltest.Shop(t))
+	if err != nil {
+		t.Fatalf("ExtractFromPath failed: %v", err)
+	}
+	quotient := category.Quotient(cat, category.ByDirectory(1))
+
+	for _, id := range []string{"dir:audit", "dir:cmd", "dir:internal", "dir:tools"} {
+		if _, exists := quotient.GetObject(id); !exists {
+			t.Errorf("Expected class %s", id)
+		}
+	}
+	internal, _ := quotient.GetObject("dir:internal")
+	if members, _ := internal.Metadata["members"].([]string); len(members) != 9 {
+		t.Errorf("Expected domain and infra files and declarations in dir:internal, got %v", members)
+	}
+}
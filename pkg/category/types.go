@@ -7,9 +7,9 @@
 package category
 
 import (
-	"crypto/sha256"
-	"encoding/json"
 	"fmt"
+	"sort"
+	"sync"
 )
 
 // Object represents an object in a category.
@@ -40,10 +40,11 @@ func NewObject(id, objType, name string, metadata map[string]interface{}) *Objec
 }
 
 // Hash returns a cryptographic hash of this object for equality checking.
+//
+// The hash is the SHA-256 digest of the object's canonical JSON encoding
+// (see CanonicalJSON), truncated to its first 16 bytes for brevity.
 func (o *Object) Hash() string {
-	data, _ := json.Marshal(o)
-	hash := sha256.Sum256(data)
-	return fmt.Sprintf("%x", hash[:16]) // Use first 16 bytes for brevity
+	return canonicalHash(o)[:32]
 }
 
 // Morphism represents a morphism (arrow) between objects in a category.
@@ -75,6 +76,12 @@ func NewMorphism(id, source, target, morphType string, metadata map[string]inter
 	}
 }
 
+// Hash returns a cryptographic hash of this morphism for equality checking.
+// It uses the same algorithm as Object.Hash.
+func (m *Morphism) Hash() string {
+	return canonicalHash(m)[:32]
+}
+
 // IsComposable checks if this morphism can be composed with another.
 // Morphisms are composable when the target of f equals the source of g.
 func (m *Morphism) IsComposable(other *Morphism) bool {
@@ -92,11 +99,19 @@ func (m *Morphism) IsComposable(other *Morphism) bool {
 // Category axioms:
 // - Associativity: (h ∘ g) ∘ f = h ∘ (g ∘ f)
 // - Identity: f ∘ id_A = f and id_B ∘ f = f for f : A → B
+//
+// Concurrency: all methods are safe for concurrent use, so extractors may add
+// objects and morphisms from several goroutines while analyzers read. The
+// exported maps exist for JSON serialization; accessing them directly bypasses
+// synchronization and is only safe while no other goroutine writes. Objects
+// and morphisms themselves are treated as immutable once added.
 type Category struct {
 	Name       string                `json:"name"`
 	Objects_   map[string]*Object    `json:"objects"`
 	Morphisms_ map[string]*Morphism  `json:"morphisms"`
 	Identities map[string]*Morphism  `json:"identities"` // Identity morphisms for each object
+
+	mu sync.RWMutex
 }
 
 // NewCategory creates a new category with the given name.
@@ -114,6 +129,10 @@ func (c *Category) AddObject(obj *Object) error {
 	if obj == nil {
 		return fmt.Errorf("cannot add nil object")
 	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	if _, exists := c.Objects_[obj.ID]; exists {
 		return fmt.Errorf("object %s already exists", obj.ID)
 	}
@@ -140,6 +159,9 @@ func (c *Category) AddMorphism(m *Morphism) error {
 		return fmt.Errorf("cannot add nil morphism")
 	}
 
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	// Validate source and target exist
 	if _, exists := c.Objects_[m.Source]; !exists {
 		return fmt.Errorf("source object %s does not exist", m.Source)
@@ -158,31 +180,58 @@ func (c *Category) AddMorphism(m *Morphism) error {
 
 // GetObject retrieves an object by ID.
 func (c *Category) GetObject(id string) (*Object, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
 	obj, exists := c.Objects_[id]
 	return obj, exists
 }
 
 // GetMorphism retrieves a morphism by ID.
 func (c *Category) GetMorphism(id string) (*Morphism, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
 	m, exists := c.Morphisms_[id]
 	return m, exists
 }
 
-// Objects returns all objects in the category.
+// Identity retrieves the identity morphism id_A of an object.
+func (c *Category) Identity(objectID string) (*Morphism, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	m, exists := c.Identities[objectID]
+	return m, exists
+}
+
+// Objects returns all objects in the category, sorted by ID.
 func (c *Category) Objects() []*Object {
+	c.mu.RLock()
 	objects := make([]*Object, 0, len(c.Objects_))
 	for _, obj := range c.Objects_ {
 		objects = append(objects, obj)
 	}
+	c.mu.RUnlock()
+
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].ID < objects[j].ID
+	})
 	return objects
 }
 
-// Morphisms returns all morphisms in the category.
+// Morphisms returns all morphisms in the category, sorted by ID.
 func (c *Category) Morphisms() []*Morphism {
+	c.mu.RLock()
 	morphisms := make([]*Morphism, 0, len(c.Morphisms_))
 	for _, m := range c.Morphisms_ {
 		morphisms = append(morphisms, m)
 	}
+	c.mu.RUnlock()
+
+	sort.Slice(morphisms, func(i, j int) bool {
+		return morphisms[i].ID < morphisms[j].ID
+	})
 	return morphisms
 }
 
@@ -212,13 +261,13 @@ func (c *Category) Compose(f, g *Morphism) (*Morphism, error) {
 // 2. Identity: f ∘ id_A = f and id_B ∘ f = f
 func (c *Category) VerifyAxioms() error {
 	// Check identity law for all morphisms
-	for _, m := range c.Morphisms_ {
+	for _, m := range c.Morphisms() {
 		if m.Type == "identity" {
 			continue // Skip identity morphisms themselves
 		}
 
 		// Check left identity: id_B ∘ f = f
-		if idTarget, exists := c.Identities[m.Target]; exists {
+		if idTarget, exists := c.Identity(m.Target); exists {
 			composed, err := c.Compose(m, idTarget)
 			if err != nil {
 				return fmt.Errorf("identity composition failed: %v", err)
@@ -230,7 +279,7 @@ func (c *Category) VerifyAxioms() error {
 		}
 
 		// Check right identity: f ∘ id_A = f
-		if idSource, exists := c.Identities[m.Source]; exists {
+		if idSource, exists := c.Identity(m.Source); exists {
 			composed, err := c.Compose(idSource, m)
 			if err != nil {
 				return fmt.Errorf("identity composition failed: %v", err)
@@ -296,9 +345,46 @@ func (c *Category) VerifyAxioms() error {
 
 // Stats returns statistics about the category.
 func (c *Category) Stats() map[string]int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
 	return map[string]int{
 		"objects":   len(c.Objects_),
 		"morphisms": len(c.Morphisms_) - len(c.Identities), // Exclude identity morphisms
 		"identities": len(c.Identities),
 	}
 }
+
+// Snapshot returns a consistent copy of the category's structure.
+//
+// The copy has its own maps, so later additions to either category are not
+// visible in the other; objects and morphisms are shared. Readers in a
+// long-running process can analyze a snapshot while producers keep updating
+// the original.
+func (c *Category) Snapshot() *Category {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	snap := NewCategory(c.Name)
+	for id, obj := range c.Objects_ {
+		snap.Objects_[id] = obj
+	}
+	for id, m := range c.Morphisms_ {
+		snap.Morphisms_[id] = m
+	}
+	for id, m := range c.Identities {
+		snap.Identities[id] = m
+	}
+	return snap
+}
+
+// categoryJSON has Category's fields without its methods, for encoding.
+type categoryJSON Category
+
+// MarshalJSON encodes the category while holding its read lock.
+func (c *Category) MarshalJSON() ([]byte, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return marshalCanonical((*categoryJSON)(c), "")
+}
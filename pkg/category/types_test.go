@@ -1,6 +1,8 @@
 package category
 
 import (
+	"fmt"
+	"sync"
 	"testing"
 )
 
@@ -293,3 +295,169 @@ func TestIdentityLaw(t *testing.T) {
 		t.Error("Right identity law violated")
 	}
 }
+
+func TestDeterministicOrdering(t *testing.T) {
+	cat := NewCategory("test")
+	for _, id := range []string{"C", "A", "B"} {
+		cat.AddObject(NewObject(id, "module", id, nil))
+	}
+	cat.AddMorphism(NewMorphism("g", "B", "C", "dependency", nil))
+	cat.AddMorphism(NewMorphism("f", "A", "B", "dependency", nil))
+
+	objects := cat.Objects()
+	for i, want := range []string{"A", "B", "C"} {
+		if objects[i].ID != want {
+			t.Errorf("Objects()[%d] = %s, want %s", i, objects[i].ID, want)
+		}
+	}
+
+	morphisms := cat.Morphisms()
+	for i := 1; i < len(morphisms); i++ {
+		if morphisms[i-1].ID > morphisms[i].ID {
+			t.Errorf("Morphisms() not sorted: %s before %s", morphisms[i-1].ID, morphisms[i].ID)
+		}
+	}
+}
+
+func TestCanonicalHash(t *testing.T) {
+	build := func(order []string) *Category {
+		cat := NewCategory("test")
+		for _, id := range order {
+			cat.AddObject(NewObject(id, "module", id, map[string]interface{}{
+				"package": "p",
+				"lines":   10,
+			}))
+		}
+		cat.AddMorphism(NewMorphism("a->b", "A", "B", "dependency", nil))
+		return cat
+	}
+
+	first := build([]string{"A", "B", "C"})
+	second := build([]string{"C", "B", "A"})
+	if first.Hash() != second.Hash() {
+		t.Error("Category hash should not depend on insertion order")
+	}
+
+	objA, _ := first.GetObject("A")
+	objA.Metadata["lines"] = 11
+	if first.Hash() == second.Hash() {
+		t.Error("Category hash should change when metadata changes")
+	}
+
+	// Decoded JSON numbers are float64; the hash must not change
+	obj := NewObject("A", "module", "A", map[string]interface{}{"lines": 10})
+	decoded := NewObject("A", "module", "A", map[string]interface{}{"lines": float64(10)})
+	if obj.Hash() != decoded.Hash() {
+		t.Error("Object hash should be stable across a JSON round trip")
+	}
+
+	data, err := CanonicalJSON(first.Morphisms_["a->b"])
+	if err != nil {
+		t.Fatalf("CanonicalJSON failed: %v", err)
+	}
+	want := `{"id":"a->b","source":"A","target":"B","type":"dependency","metadata":{}}`
+	if string(data) != want {
+		t.Errorf("CanonicalJSON = %s, want %s", data, want)
+	}
+}
+
+func TestConcurrentAddAndRead(t *testing.T) {
+	cat := NewCategory("test")
+	const producers = 8
+	const perProducer = 50
+
+	var wg sync.WaitGroup
+
+	// Producers add objects and chain morphisms within their own range
+	for p := 0; p < producers; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			for i := 0; i < perProducer; i++ {
+				id := fmt.Sprintf("obj_%d_%d", p, i)
+				if err := cat.AddObject(NewObject(id, "module", id, nil)); err != nil {
+					t.Errorf("AddObject(%s) failed: %v", id, err)
+					return
+				}
+				if i > 0 {
+					prev := fmt.Sprintf("obj_%d_%d", p, i-1)
+					m := NewMorphism(fmt.Sprintf("f_%d_%d", p, i), prev, id, "dependency", nil)
+					if err := cat.AddMorphism(m); err != nil {
+						t.Errorf("AddMorphism failed: %v", err)
+						return
+					}
+				}
+			}
+		}(p)
+	}
+
+	// Readers exercise every read path while producers write
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 20; i++ {
+				cat.Objects()
+				cat.Morphisms()
+				cat.Stats()
+				cat.GetObject("obj_0_0")
+				cat.GetMorphism("f_0_1")
+				cat.Identity("obj_0_0")
+				cat.Snapshot()
+				cat.Hash()
+			}
+		}()
+	}
+
+	// Concurrent additions of the same object: exactly one must succeed
+	var dupWG sync.WaitGroup
+	var mu sync.Mutex
+	successes := 0
+	for i := 0; i < 10; i++ {
+		dupWG.Add(1)
+		go func() {
+			defer dupWG.Done()
+			if cat.AddObject(NewObject("shared", "module", "shared", nil)) == nil {
+				mu.Lock()
+				successes++
+				mu.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+	dupWG.Wait()
+
+	if successes != 1 {
+		t.Errorf("Expected exactly one successful duplicate add, got %d", successes)
+	}
+
+	stats := cat.Stats()
+	if stats["objects"] != producers*perProducer+1 {
+		t.Errorf("Expected %d objects, got %d", producers*perProducer+1, stats["objects"])
+	}
+	if stats["morphisms"] != producers*(perProducer-1) {
+		t.Errorf("Expected %d morphisms, got %d", producers*(perProducer-1), stats["morphisms"])
+	}
+	if err := cat.VerifyAxioms(); err != nil {
+		t.Errorf("Axiom verification failed: %v", err)
+	}
+}
+
+func TestSnapshotIsolation(t *testing.T) {
+	cat := NewCategory("test")
+	cat.AddObject(NewObject("A", "module", "A", nil))
+
+	snap := cat.Snapshot()
+	cat.AddObject(NewObject("B", "module", "B", nil))
+
+	if _, exists := snap.GetObject("B"); exists {
+		t.Error("Snapshot should not see objects added after it was taken")
+	}
+	if _, exists := snap.Identity("A"); !exists {
+		t.Error("Snapshot should contain identities of existing objects")
+	}
+	if snap.Hash() == cat.Hash() {
+		t.Error("Snapshot hash should differ after the original changed")
+	}
+}
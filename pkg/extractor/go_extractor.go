@@ -19,9 +19,24 @@ import (
 
 // GoExtractor extracts categorical models from Go source code.
 type GoExtractor struct {
+	// Tests includes _test.go files, marked with "is_test" metadata, so
+	// that dead code analysis can keep what tests use alive
+	Tests bool
+
 	fset       *token.FileSet
 	category   *category.Category
 	packageMap map[string]string // Maps file paths to package names
+	references []reference       // Resolved once all files are read
+	lines      map[reference]int // Line of the first use of each reference
+	modules    moduleFinder
+}
+
+// reference is a use of a name by a function, type or file, recorded while
+// reading files: its target may be declared in a file not read yet.
+type reference struct {
+	source string
+	target string // Qualified name, e.g. "pkg.Name"
+	call   bool
 }
 
 // NewGoExtractor creates a new Go code extractor.
@@ -30,10 +45,18 @@ func NewGoExtractor() *GoExtractor {
 		fset:       token.NewFileSet(),
 		category:   category.NewCategory("go_codebase"),
 		packageMap: make(map[string]string),
+		lines:      make(map[reference]int),
+		modules:    make(moduleFinder),
 	}
 }
 
 // ExtractFromPath extracts categorical model from a Go project path.
+//
+// Files are identified by their slash-separated path relative to root, so
+// models extracted from different checkouts or working directories share
+// IDs, and path selectors match alike. The "path" metadata of a file keeps
+// the path it was read from; its "import_path" metadata, the import path of
+// its package when a go.mod encloses it.
 func (e *GoExtractor) ExtractFromPath(root string) (*category.Category, error) {
 	// Walk the directory tree
 	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
@@ -41,13 +64,19 @@ func (e *GoExtractor) ExtractFromPath(root string) (*category.Category, error) {
 			return err
 		}
 
-		// Skip non-Go files and test files
-		if info.IsDir() || !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+		// Skip non-Go files, and test files unless asked for
+		if info.IsDir() || !strings.HasSuffix(path, ".go") || (!e.Tests && strings.HasSuffix(path, "_test.go")) {
 			return nil
 		}
 
+		fileID, err := filepath.Rel(root, path)
+		if err != nil || fileID == "." {
+			// root is the file itself
+			fileID = filepath.Base(path)
+		}
+
 		// Parse the file
-		if err := e.extractFromFile(path); err != nil {
+		if err := e.extractFromFile(path, filepath.ToSlash(fileID)); err != nil {
 			return fmt.Errorf("failed to extract from %s: %v", path, err)
 		}
 
@@ -57,14 +86,16 @@ func (e *GoExtractor) ExtractFromPath(root string) (*category.Category, error) {
 	if err != nil {
 		return nil, err
 	}
+	e.resolveReferences()
 
 	return e.category, nil
 }
 
-// extractFromFile extracts categorical structures from a single Go file.
-func (e *GoExtractor) extractFromFile(filePath string) error {
+// extractFromFile extracts categorical structures from the Go file at path,
+// identified by filePath.
+func (e *GoExtractor) extractFromFile(path, filePath string) error {
 	// Parse the file
-	f, err := parser.ParseFile(e.fset, filePath, nil, parser.ParseComments)
+	f, err := parser.ParseFile(e.fset, path, nil, parser.ParseComments)
 	if err != nil {
 		return err
 	}
@@ -79,11 +110,17 @@ func (e *GoExtractor) extractFromFile(filePath string) error {
 		filepath.Base(filePath),
 		map[string]interface{}{
 			"package":  pkgName,
-			"path":     filePath,
+			"path":     path,
 			"doc":      getDocComment(f.Doc),
 			"imports":  len(f.Imports),
 		},
 	)
+	if importPath := e.modules.importPath(filepath.Dir(path)); importPath != "" {
+		fileObj.Metadata["import_path"] = importPath
+	}
+	if strings.HasSuffix(filePath, "_test.go") {
+		fileObj.Metadata["is_test"] = true
+	}
 	if err := e.category.AddObject(fileObj); err != nil {
 		return err
 	}
@@ -91,7 +128,7 @@ func (e *GoExtractor) extractFromFile(filePath string) error {
 	// Extract imports as morphisms
 	for _, imp := range f.Imports {
 		importPath := strings.Trim(imp.Path.Value, `"`)
-		if err := e.extractImport(filePath, importPath); err != nil {
+		if err := e.extractImport(filePath, importPath, e.fset.Position(imp.Pos()).Line); err != nil {
 			// Continue on error - some imports might be external
 			continue
 		}
@@ -111,7 +148,7 @@ func (e *GoExtractor) extractFromFile(filePath string) error {
 }
 
 // extractImport creates a dependency morphism for an import.
-func (e *GoExtractor) extractImport(sourceFile, importPath string) error {
+func (e *GoExtractor) extractImport(sourceFile, importPath string, line int) error {
 	// For now, create an object for the imported package
 	// In a full implementation, we'd resolve the import to actual files
 	targetID := fmt.Sprintf("import:%s", importPath)
@@ -141,6 +178,7 @@ func (e *GoExtractor) extractImport(sourceFile, importPath string) error {
 			"import",
 			map[string]interface{}{
 				"import_path": importPath,
+				"line":        line,
 			},
 		)
 		if err := e.category.AddMorphism(morph); err != nil {
@@ -157,6 +195,9 @@ func (e *GoExtractor) extractGenDecl(filePath, pkgName string, decl *ast.GenDecl
 		switch s := spec.(type) {
 		case *ast.TypeSpec:
 			e.extractTypeSpec(filePath, pkgName, s, decl.Doc)
+		case *ast.ValueSpec:
+			// Package-level initializers belong to the file
+			e.extractReferences(filePath, pkgName, s)
 		}
 	}
 }
@@ -184,6 +225,7 @@ func (e *GoExtractor) extractTypeSpec(filePath, pkgName string, spec *ast.TypeSp
 			"package":  pkgName,
 			"file":     filePath,
 			"doc":      getDocComment(doc),
+			"line":     e.fset.Position(spec.Pos()).Line,
 		},
 	)
 	if err := e.category.AddObject(typeObj); err != nil {
@@ -203,59 +245,8 @@ func (e *GoExtractor) extractTypeSpec(filePath, pkgName string, spec *ast.TypeSp
 	)
 	e.category.AddMorphism(morph)
 
-	// Extract struct field types as dependencies
-	if structType, ok := spec.Type.(*ast.StructType); ok {
-		e.extractStructFields(typeID, structType)
-	}
-}
-
-// extractStructFields extracts field type dependencies from a struct.
-func (e *GoExtractor) extractStructFields(structID string, structType *ast.StructType) {
-	for _, field := range structType.Fields.List {
-		// Extract type references
-		e.extractTypeRef(structID, field.Type)
-	}
-}
-
-// extractTypeRef extracts type references (dependencies on other types).
-func (e *GoExtractor) extractTypeRef(sourceID string, expr ast.Expr) {
-	switch t := expr.(type) {
-	case *ast.Ident:
-		// Reference to type in same package
-		if t.Obj != nil {
-			// Local type reference - would need to resolve
-		}
-	case *ast.SelectorExpr:
-		// Reference to type in another package
-		if ident, ok := t.X.(*ast.Ident); ok {
-			targetType := fmt.Sprintf("%s.%s", ident.Name, t.Sel.Name)
-			// Create dependency morphism
-			morphID := fmt.Sprintf("uses:%s->%s", sourceID, targetType)
-			if _, exists := e.category.GetMorphism(morphID); !exists {
-				morph := category.NewMorphism(
-					morphID,
-					sourceID,
-					targetType,
-					"type_dependency",
-					map[string]interface{}{
-						"type": targetType,
-					},
-				)
-				// Note: This might fail if target doesn't exist yet
-				e.category.AddMorphism(morph)
-			}
-		}
-	case *ast.StarExpr:
-		// Pointer type
-		e.extractTypeRef(sourceID, t.X)
-	case *ast.ArrayType:
-		// Array/slice type
-		e.extractTypeRef(sourceID, t.Elt)
-	case *ast.MapType:
-		// Map type
-		e.extractTypeRef(sourceID, t.Key)
-		e.extractTypeRef(sourceID, t.Value)
-	}
+	// Extract field, method and underlying types as dependencies
+	e.extractReferences(typeID, pkgName, spec.Type)
 }
 
 // extractFuncDecl extracts a function declaration.
@@ -282,6 +273,7 @@ func (e *GoExtractor) extractFuncDecl(filePath, pkgName string, decl *ast.FuncDe
 			"package": pkgName,
 			"file":    filePath,
 			"doc":     getDocComment(decl.Doc),
+			"line":    e.fset.Position(decl.Pos()).Line,
 			"is_exported": ast.IsExported(funcName),
 		},
 	)
@@ -302,47 +294,112 @@ func (e *GoExtractor) extractFuncDecl(filePath, pkgName string, decl *ast.FuncDe
 	)
 	e.category.AddMorphism(morph)
 
-	// Extract function calls from body
+	// Extract calls and type references from signature and body
+	e.extractReferences(funcID, pkgName, decl.Type)
 	if decl.Body != nil {
-		ast.Inspect(decl.Body, func(n ast.Node) bool {
-			if call, ok := n.(*ast.CallExpr); ok {
-				e.extractFunctionCall(funcID, call)
-			}
-			return true
-		})
+		e.extractReferences(funcID, pkgName, decl.Body)
 	}
 }
 
-// extractFunctionCall extracts function call relationships.
-func (e *GoExtractor) extractFunctionCall(sourceFunc string, call *ast.CallExpr) {
-	var targetFunc string
+// extractReferences records the names node refers to on behalf of sourceID:
+// calls, functions used as values and types, either declared in the same
+// package or qualified by an imported package name.
+func (e *GoExtractor) extractReferences(sourceID, pkgName string, node ast.Node) {
+	callees := make(map[ast.Expr]bool)
+	var visit func(n ast.Node) bool
+	visit = func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.CallExpr:
+			callees[n.Fun] = true
+		case *ast.Field:
+			// Parameter and field names declare, only the type refers
+			if n.Type != nil {
+				ast.Inspect(n.Type, visit)
+			}
+			return false
+		case *ast.SelectorExpr:
+			if x, ok := n.X.(*ast.Ident); ok {
+				e.refer(sourceID, x.Name+"."+n.Sel.Name, callees[n], e.fset.Position(n.Pos()).Line)
+				return false
+			}
+			// Method calls and fields of expressions can't be resolved
+			// without type information
+			ast.Inspect(n.X, visit)
+			return false
+		case *ast.Ident:
+			e.refer(sourceID, pkgName+"."+n.Name, callees[n], e.fset.Position(n.Pos()).Line)
+		}
+		return true
+	}
+	ast.Inspect(node, visit)
+}
 
-	switch fun := call.Fun.(type) {
-	case *ast.Ident:
-		// Call to function in same package
-		targetFunc = fun.Name
-	case *ast.SelectorExpr:
-		// Call to method or function in another package
-		targetFunc = exprToString(fun)
-	default:
-		return
+// refer records a reference from sourceID to a qualified name, used first
+// at line.
+func (e *GoExtractor) refer(sourceID, target string, call bool, line int) {
+	ref := reference{source: sourceID, target: target, call: call}
+	if _, seen := e.lines[ref]; sourceID != target && !seen {
+		e.lines[ref] = line
+		e.references = append(e.references, ref)
 	}
+}
 
-	// Create call morphism
-	morphID := fmt.Sprintf("calls:%s->%s", sourceFunc, targetFunc)
-	if _, exists := e.category.GetMorphism(morphID); !exists {
-		morph := category.NewMorphism(
-			morphID,
-			sourceFunc,
-			targetFunc,
-			"function_call",
-			map[string]interface{}{
-				"target": targetFunc,
-			},
-		)
-		// Note: Might fail if target doesn't exist
+// resolveReferences adds a morphism for each reference to an object of the
+// model: a function call, a function used as a value, or a type dependency.
+// References to anything else, such as local variables, builtins or
+// packages outside the model, are dropped.
+func (e *GoExtractor) resolveReferences() {
+	for _, ref := range e.references {
+		target, ok := e.category.GetObject(ref.target)
+		if !ok {
+			continue
+		}
+
+		var morph *category.Morphism
+		switch target.Type {
+		case "function":
+			if ref.call {
+				morph = category.NewMorphism(
+					fmt.Sprintf("calls:%s->%s", ref.source, ref.target),
+					ref.source,
+					ref.target,
+					"function_call",
+					map[string]interface{}{
+						"target": ref.target,
+						"line":   e.lines[ref],
+					},
+				)
+			} else {
+				morph = category.NewMorphism(
+					fmt.Sprintf("refers:%s->%s", ref.source, ref.target),
+					ref.source,
+					ref.target,
+					"reference",
+					map[string]interface{}{
+						"target": ref.target,
+						"line":   e.lines[ref],
+					},
+				)
+			}
+		case "struct", "interface", "type":
+			// Conversions call types
+			morph = category.NewMorphism(
+				fmt.Sprintf("uses:%s->%s", ref.source, ref.target),
+				ref.source,
+				ref.target,
+				"type_dependency",
+				map[string]interface{}{
+					"type": ref.target,
+					"line": e.lines[ref],
+				},
+			)
+		default:
+			continue
+		}
 		e.category.AddMorphism(morph)
 	}
+	e.references = nil
+	e.lines = make(map[reference]int)
 }
 
 // Helper functions
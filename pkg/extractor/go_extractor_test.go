@@ -0,0 +1,171 @@
+package extractor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeFiles writes files, keyed by their slash-separated path, below dir.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestGoExtractorReferences(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"store/store.go": `package store
+
+type DB struct{}
+
+func Open() *DB { return &DB{} }
+`,
+		"app/app.go": `package app
+
+import "example.com/m/store"
+
+type Handler struct {
+	db *store.DB
+}
+
+func helper() int { return 1 }
+
+func Run() int {
+	callback := helper
+	_ = callback
+	h := Handler{db: store.Open()}
+	_ = h
+	return helper() + len("x")
+}
+`,
+	}
+	writeFiles(t, dir, files)
+
+	cat, err := NewGoExtractor().ExtractFromPath(dir)
+	if err != nil {
+		t.Fatalf("ExtractFromPath failed: %v", err)
+	}
+
+	// Files are identified relative to the root, and keep where they were read
+	file, ok := cat.GetObject("app/app.go")
+	if !ok || file.Metadata["path"] != filepath.Join(dir, "app", "app.go") {
+		t.Fatalf("Expected app/app.go read from %s, got %v", dir, file)
+	}
+	if fn, _ := cat.GetObject("app.Run"); fn == nil || fn.Metadata["file"] != "app/app.go" {
+		t.Errorf("Expected app.Run in app/app.go, got %v", fn)
+	}
+
+	tests := []struct {
+		id, typ string
+		line    int
+	}{
+		// Same-package call
+		{"calls:app.Run->app.helper", "function_call", 16},
+		// Function used as a value
+		{"refers:app.Run->app.helper", "reference", 12},
+		// Type references, from a composite literal and a field type
+		{"uses:app.Run->app.Handler", "type_dependency", 14},
+		{"uses:app.Handler->store.DB", "type_dependency", 6},
+		// Cross-package selectors
+		{"calls:app.Run->store.Open", "function_call", 14},
+	}
+	for _, tt := range tests {
+		m, ok := cat.GetMorphism(tt.id)
+		if !ok {
+			t.Errorf("Expected morphism %s", tt.id)
+			continue
+		}
+		if m.Type != tt.typ {
+			t.Errorf("Expected %s to be a %s, got %s", tt.id, tt.typ, m.Type)
+		}
+		if line, _ := m.Metadata["line"].(int); line != tt.line {
+			t.Errorf("Expected %s at line %d, got %v", tt.id, tt.line, m.Metadata["line"])
+		}
+	}
+
+	// Local variables and builtins are not objects of the model
+	for _, m := range cat.Morphisms() {
+		if m.Source == "app.Run" {
+			switch m.Target {
+			case "app.Run", "app.helper", "app.Handler", "store.Open":
+			default:
+				t.Errorf("Unexpected morphism %s", m.ID)
+			}
+		}
+	}
+}
+
+func TestGoExtractorImportPaths(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod":           "// The main module\nmodule \"example.com/m\" // quoted\n\ngo 1.21\n",
+		"main.go":          "package main\n",
+		"store/store.go":   "package store\n",
+		"tools/go.mod":     "module example.com/tools\n",
+		"tools/gen/gen.go": "package gen\n",
+	})
+
+	tests := []struct {
+		root string
+		want map[string]string // File ID -> import path
+	}{
+		{dir, map[string]string{
+			"main.go":          "example.com/m",
+			"store/store.go":   "example.com/m/store",
+			"tools/gen/gen.go": "example.com/tools/gen", // A nested module
+		}},
+		// Below the module root, import paths still start at go.mod
+		{filepath.Join(dir, "store"), map[string]string{"store.go": "example.com/m/store"}},
+	}
+	for _, tt := range tests {
+		cat, err := NewGoExtractor().ExtractFromPath(tt.root)
+		if err != nil {
+			t.Fatalf("ExtractFromPath failed: %v", err)
+		}
+		for id, want := range tt.want {
+			if file, ok := cat.GetObject(id); !ok || file.Metadata["import_path"] != want {
+				t.Errorf("Expected %s in package %s, got %v", id, want, file)
+			}
+		}
+	}
+}
+
+func TestGoExtractorTests(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"a.go":      "package a\n\nfunc helper() int { return 1 }\n",
+		"a_test.go": "package a\n\nimport \"testing\"\n\nfunc TestHelper(t *testing.T) { helper() }\n",
+	})
+
+	cat, err := NewGoExtractor().ExtractFromPath(dir)
+	if err != nil {
+		t.Fatalf("ExtractFromPath failed: %v", err)
+	}
+	if _, ok := cat.GetObject("a_test.go"); ok {
+		t.Error("Expected test files to be skipped by default")
+	}
+
+	ext := NewGoExtractor()
+	ext.Tests = true
+	if cat, err = ext.ExtractFromPath(dir); err != nil {
+		t.Fatalf("ExtractFromPath failed: %v", err)
+	}
+	if file, ok := cat.GetObject("a_test.go"); !ok || file.Metadata["is_test"] != true {
+		t.Errorf("Expected a_test.go marked as a test file, got %v", file)
+	}
+	if a, _ := cat.GetObject("a.go"); a == nil || a.Metadata["is_test"] != nil {
+		t.Errorf("Expected a.go not marked as a test file, got %v", a)
+	}
+	if _, ok := cat.GetMorphism("calls:a.TestHelper->a.helper"); !ok {
+		t.Error("Expected the test's call to helper")
+	}
+}
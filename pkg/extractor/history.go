@@ -0,0 +1,456 @@
+package extractor
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/manu/catreview/pkg/analysis"
+	"github.com/manu/catreview/pkg/category"
+)
+
+// HistoryExtractor enriches a categorical model with version-control history.
+//
+// It reads the commit log of a local git repository (via the git binary) and:
+//  1. Attaches churn metadata to file objects (commits, authors, last_modified)
+//  2. Emits "co_changes_with" morphisms between files that change in the same commit,
+//     weighted by the number of shared commits
+//
+// Co-change morphisms describe logical coupling, which can then be compared with
+// the structural coupling derived from the source code.
+type HistoryExtractor struct {
+	repoRoot string
+
+	// MaxCommits limits how many commits are read (0 = unlimited).
+	MaxCommits int
+	// Since restricts history to commits newer than this date (git --since syntax).
+	Since string
+	// MinCoChanges is the minimum number of shared commits for a co-change morphism.
+	MinCoChanges int
+	// MaxFilesPerCommit skips commits touching more files (mass renames, reformatting).
+	MaxFilesPerCommit int
+}
+
+// Commit is a single commit read from the repository log.
+type Commit struct {
+	Hash   string
+	Author string
+	Time   time.Time
+	Files  []string // Paths relative to the repository top level
+}
+
+// FileChurn summarizes the history of a single file.
+type FileChurn struct {
+	Commits      int
+	Authors      map[string]bool
+	LastModified time.Time
+}
+
+// NewHistoryExtractor creates a history extractor for the repository containing repoRoot.
+func NewHistoryExtractor(repoRoot string) *HistoryExtractor {
+	return &HistoryExtractor{
+		repoRoot:          repoRoot,
+		MinCoChanges:      analysis.DefaultMinCoChanges,
+		MaxFilesPerCommit: 50,
+	}
+}
+
+// Commits reads the commit log of the repository, newest first.
+// Merge commits are skipped since they duplicate the changes of their parents.
+func (h *HistoryExtractor) Commits() ([]*Commit, error) {
+	args := []string{"-C", h.repoRoot, "log", "--no-merges", "--name-only",
+		"--format=%x00%H%x1f%an%x1f%ct"}
+	if h.MaxCommits > 0 {
+		args = append(args, fmt.Sprintf("--max-count=%d", h.MaxCommits))
+	}
+	if h.Since != "" {
+		args = append(args, "--since="+h.Since)
+	}
+
+	out, err := h.git(args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseGitLog(out)
+}
+
+// parseGitLog parses the output of git log in the format produced by Commits.
+func parseGitLog(out []byte) ([]*Commit, error) {
+	var commits []*Commit
+	var current *Commit
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "\x00") {
+			fields := strings.Split(line[1:], "\x1f")
+			if len(fields) != 3 {
+				return nil, fmt.Errorf("malformed git log header: %q", line)
+			}
+			seconds, err := strconv.ParseInt(fields[2], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("malformed commit time %q: %v", fields[2], err)
+			}
+			current = &Commit{
+				Hash:   fields[0],
+				Author: fields[1],
+				Time:   time.Unix(seconds, 0).UTC(),
+			}
+			commits = append(commits, current)
+			continue
+		}
+		if line == "" || current == nil {
+			continue
+		}
+		current.Files = append(current.Files, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return commits, nil
+}
+
+// Enrich attaches churn metadata to the file objects of cat and adds
+// "co_changes_with" morphisms between files that frequently change together.
+//
+// File objects are matched to repository paths via their "path" metadata
+// (falling back to the object ID), resolved relative to the working directory.
+func (h *HistoryExtractor) Enrich(cat *category.Category) error {
+	topLevel, err := h.topLevel()
+	if err != nil {
+		return err
+	}
+
+	commits, err := h.Commits()
+	if err != nil {
+		return err
+	}
+
+	return h.enrich(cat, modelFiles(cat, topLevel), commits)
+}
+
+// enrich applies the history of commits to cat, given the file object IDs
+// by repository-relative path.
+func (h *HistoryExtractor) enrich(cat *category.Category, fileIDs map[string]string, commits []*Commit) error {
+	churn := make(map[string]*FileChurn)
+	coChanges := make(map[[2]string]int)
+
+	for _, commit := range commits {
+		// Collect the model files touched by this commit
+		touched := make([]string, 0, len(commit.Files))
+		seen := make(map[string]bool)
+		for _, file := range commit.Files {
+			id, ok := fileIDs[file]
+			if !ok || seen[id] {
+				continue
+			}
+			seen[id] = true
+			touched = append(touched, id)
+
+			c, exists := churn[id]
+			if !exists {
+				c = &FileChurn{Authors: make(map[string]bool)}
+				churn[id] = c
+			}
+			c.Commits++
+			c.Authors[commit.Author] = true
+			if commit.Time.After(c.LastModified) {
+				c.LastModified = commit.Time
+			}
+		}
+
+		if h.MaxFilesPerCommit > 0 && len(touched) > h.MaxFilesPerCommit {
+			continue
+		}
+
+		sort.Strings(touched)
+		for i := 0; i < len(touched); i++ {
+			for j := i + 1; j < len(touched); j++ {
+				coChanges[[2]string{touched[i], touched[j]}]++
+			}
+		}
+	}
+
+	// Attach churn metadata
+	for id, c := range churn {
+		obj, _ := cat.GetObject(id)
+		authors := make([]string, 0, len(c.Authors))
+		for author := range c.Authors {
+			authors = append(authors, author)
+		}
+		sort.Strings(authors)
+
+		obj.Metadata["commits"] = c.Commits
+		obj.Metadata["authors"] = authors
+		obj.Metadata["last_modified"] = c.LastModified.Format(time.RFC3339)
+	}
+
+	// Emit co-change morphisms (one per unordered pair, source < target)
+	for pair, count := range coChanges {
+		if count < h.MinCoChanges {
+			continue
+		}
+		morph := category.NewMorphism(
+			fmt.Sprintf("co_change:%s->%s", pair[0], pair[1]),
+			pair[0],
+			pair[1],
+			"co_changes_with",
+			map[string]interface{}{
+				"weight":    count,
+				"symmetric": true,
+			},
+		)
+		if err := cat.AddMorphism(morph); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ModelFiles maps the repository-relative paths of the file objects of cat
+// to their IDs, resolving paths as Enrich does.
+func (h *HistoryExtractor) ModelFiles(cat *category.Category) (map[string]string, error) {
+	topLevel, err := h.topLevel()
+	if err != nil {
+		return nil, err
+	}
+	return modelFiles(cat, topLevel), nil
+}
+
+// modelFiles maps repository-relative paths to file object IDs.
+func modelFiles(cat *category.Category, topLevel string) map[string]string {
+	fileIDs := make(map[string]string)
+	for _, obj := range cat.Objects() {
+		if obj.Type != "file" {
+			continue
+		}
+		path, ok := obj.Metadata["path"].(string)
+		if !ok {
+			path = obj.ID
+		}
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			continue
+		}
+		if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+			abs = resolved
+		}
+		rel, err := filepath.Rel(topLevel, abs)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		fileIDs[filepath.ToSlash(rel)] = obj.ID
+	}
+	return fileIDs
+}
+
+// ChangedFiles lists the repository-relative paths of the files changed in
+// a revision range, such as "main..HEAD", or since a single revision.
+func (h *HistoryExtractor) ChangedFiles(revisions string) ([]string, error) {
+	out, err := h.git("-C", h.repoRoot, "diff", "--name-only", revisions, "--")
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			files = append(files, line)
+		}
+	}
+	return files, nil
+}
+
+// ParseUnifiedDiff lists the files a unified diff changes, as the paths
+// after its "a/" and "b/" prefixes. Deleted files are listed by their old
+// path, renamed files by their new one. Git sections without "---" and
+// "+++" headers, such as binary files, mode changes and empty new files,
+// are listed by the path of their "diff --git" line. Paths quoted C-style
+// are unquoted. File headers are only read between hunks, whose extent is
+// given by the line counts of their "@@" header, so changed lines looking
+// like "---" or "+++" headers are not mistaken for files.
+func ParseUnifiedDiff(r io.Reader) ([]string, error) {
+	var files []string
+	seen := make(map[string]bool)
+	add := func(path string) {
+		if path != "" && path != "/dev/null" && !seen[path] {
+			seen[path] = true
+			files = append(files, path)
+		}
+	}
+
+	oldPath := ""
+	gitPath := ""              // Path of the current git section until a header lists it
+	oldLines, newLines := 0, 0 // Lines left in the current hunk
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if oldLines > 0 || newLines > 0 {
+			switch {
+			case strings.HasPrefix(line, "-"):
+				oldLines--
+			case strings.HasPrefix(line, "+"):
+				newLines--
+			case strings.HasPrefix(line, `\`):
+				// "\ No newline at end of file"
+			default:
+				// Context line, possibly stripped of its leading space
+				oldLines--
+				newLines--
+			}
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			add(gitPath)
+			oldPath = ""
+			gitPath = gitHeaderPath(strings.TrimPrefix(line, "diff --git "))
+		case strings.HasPrefix(line, "rename to "):
+			// Pure renames have no "---" and "+++" headers
+			add(unquotePath(strings.TrimPrefix(line, "rename to ")))
+			gitPath = ""
+		case strings.HasPrefix(line, "--- "):
+			oldPath = diffPath(strings.TrimPrefix(line, "--- "), "a/")
+		case strings.HasPrefix(line, "+++ "):
+			newPath := diffPath(strings.TrimPrefix(line, "+++ "), "b/")
+			if newPath == "/dev/null" {
+				add(oldPath)
+			} else {
+				add(newPath)
+			}
+			gitPath = ""
+		case strings.HasPrefix(line, "@@ "):
+			var ok bool
+			if oldLines, newLines, ok = hunkLines(line); !ok {
+				return nil, fmt.Errorf("malformed hunk header %q", line)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read diff: %v", err)
+	}
+	add(gitPath)
+	sort.Strings(files)
+	return files, nil
+}
+
+// hunkLines parses the old and new line counts of a "@@ -a,b +c,d @@" hunk
+// header. An omitted count is 1.
+func hunkLines(header string) (int, int, bool) {
+	fields := strings.Fields(header)
+	if len(fields) < 4 || fields[0] != "@@" || fields[3] != "@@" ||
+		!strings.HasPrefix(fields[1], "-") || !strings.HasPrefix(fields[2], "+") {
+		return 0, 0, false
+	}
+	count := func(r string) (int, bool) {
+		start, n := r, "1"
+		if i := strings.IndexByte(r, ','); i >= 0 {
+			start, n = r[:i], r[i+1:]
+		}
+		if _, err := strconv.Atoi(start); err != nil {
+			return 0, false
+		}
+		lines, err := strconv.Atoi(n)
+		return lines, err == nil && lines >= 0
+	}
+	oldLines, okOld := count(fields[1][1:])
+	newLines, okNew := count(fields[2][1:])
+	return oldLines, newLines, okOld && okNew
+}
+
+// diffPath extracts the path of a "---" or "+++" header, dropping the
+// timestamp some tools append and the given prefix.
+func diffPath(header, prefix string) string {
+	if i := strings.IndexByte(header, '\t'); i >= 0 {
+		header = header[:i]
+	}
+	header = unquotePath(strings.TrimSpace(header))
+	if header == "/dev/null" {
+		return header
+	}
+	return strings.TrimPrefix(header, prefix)
+}
+
+// gitHeaderPath extracts the new path of a "diff --git a/<old> b/<new>"
+// line, given without its "diff --git " prefix. Unquoted paths may contain
+// spaces, so the line is split where both paths are equal, as they are
+// unless the file is renamed; renamed files are listed by their "rename
+// to" line instead.
+func gitHeaderPath(paths string) string {
+	if strings.HasPrefix(paths, `"`) {
+		// The old path is quoted; the new one may be too
+		for i := 1; i < len(paths); i++ {
+			if paths[i] == '\\' {
+				i++
+			} else if paths[i] == '"' {
+				return strings.TrimPrefix(unquotePath(strings.TrimSpace(paths[i+1:])), "b/")
+			}
+		}
+		return ""
+	}
+	if strings.HasSuffix(paths, `"`) {
+		if i := strings.Index(paths, ` "`); i >= 0 {
+			return strings.TrimPrefix(unquotePath(paths[i+1:]), "b/")
+		}
+	}
+	if n := len(paths); n%2 == 1 {
+		oldPath, newPath := paths[:n/2], paths[n/2+1:]
+		if paths[n/2] == ' ' && strings.TrimPrefix(oldPath, "a/") == strings.TrimPrefix(newPath, "b/") {
+			return strings.TrimPrefix(newPath, "b/")
+		}
+	}
+	if i := strings.LastIndex(paths, " b/"); i >= 0 {
+		return paths[i+len(" b/"):]
+	}
+	return ""
+}
+
+// unquotePath unquotes a path git quoted C-style, because it contains
+// special characters such as tabs, quotes or non-ASCII bytes.
+func unquotePath(path string) string {
+	if len(path) < 2 || path[0] != '"' || path[len(path)-1] != '"' {
+		return path
+	}
+	if unquoted, err := strconv.Unquote(path); err == nil {
+		return unquoted
+	}
+	return path
+}
+
+// topLevel returns the absolute path of the repository's top-level directory.
+func (h *HistoryExtractor) topLevel() (string, error) {
+	out, err := h.git("-C", h.repoRoot, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", err
+	}
+	topLevel := strings.TrimSpace(string(out))
+	if resolved, err := filepath.EvalSymlinks(topLevel); err == nil {
+		topLevel = resolved
+	}
+	return topLevel, nil
+}
+
+// git runs the git binary and returns its standard output.
+func (h *HistoryExtractor) git(args ...string) ([]byte, error) {
+	cmd := exec.Command("git", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git %s failed: %v: %s",
+			strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
@@ -0,0 +1,267 @@
+package extractor
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/manu/catreview/internal/modeltest"
+	"github.com/manu/catreview/pkg/category"
+)
+
+// gitLogFixture is git log output in the format read by Commits: three
+// commits by two authors, newest first.
+const gitLogFixture = "\x00c3\x1fbob\x1f1700000300\n" +
+	"\n" +
+	"pkg/a.go\n" +
+	"pkg/b.go\n" +
+	"docs/README.md\n" +
+	"\x00c2\x1falice\x1f1700000200\n" +
+	"\n" +
+	"pkg/a.go\n" +
+	"pkg/b.go\n" +
+	"\x00c1\x1falice\x1f1700000100\n" +
+	"\n" +
+	"pkg/a.go\n" +
+	"pkg/c.go\n"
+
+func TestParseGitLog(t *testing.T) {
+	commits, err := parseGitLog([]byte(gitLogFixture))
+	if err != nil {
+		t.Fatalf("parseGitLog failed: %v", err)
+	}
+	if len(commits) != 3 {
+		t.Fatalf("Expected 3 commits, got %d", len(commits))
+	}
+	first := commits[0]
+	if first.Hash != "c3" || first.Author != "bob" || !first.Time.Equal(time.Unix(1700000300, 0)) {
+		t.Errorf("Unexpected commit header %+v", first)
+	}
+	if strings.Join(first.Files, ",") != "pkg/a.go,pkg/b.go,docs/README.md" {
+		t.Errorf("Unexpected files %v", first.Files)
+	}
+
+	if _, err := parseGitLog([]byte("\x00c1\x1falice\n")); err == nil {
+		t.Error("Expected an error for a header without a time")
+	}
+	if _, err := parseGitLog([]byte("\x00c1\x1falice\x1fyesterday\n")); err == nil {
+		t.Error("Expected an error for a malformed time")
+	}
+}
+
+// historyFiles returns a model of the files touched by gitLogFixture.
+func historyFiles(t *testing.T) *category.Category {
+	var files []*category.Object
+	for _, id := range []string{"pkg/a.go", "pkg/b.go", "pkg/c.go"} {
+		files = append(files, category.NewObject(id, "file", id, map[string]interface{}{}))
+	}
+	return modeltest.Build(t, "history", files, nil)
+}
+
+func TestEnrich(t *testing.T) {
+	commits, err := parseGitLog([]byte(gitLogFixture))
+	if err != nil {
+		t.Fatalf("parseGitLog failed: %v", err)
+	}
+	cat := historyFiles(t)
+	fileIDs := map[string]string{"pkg/a.go": "pkg/a.go", "pkg/b.go": "pkg/b.go", "pkg/c.go": "pkg/c.go"}
+
+	h := NewHistoryExtractor(".")
+	if err := h.enrich(cat, fileIDs, commits); err != nil {
+		t.Fatalf("enrich failed: %v", err)
+	}
+
+	a, _ := cat.GetObject("pkg/a.go")
+	if a.Metadata["commits"] != 3 || strings.Join(a.Metadata["authors"].([]string), ",") != "alice,bob" {
+		t.Errorf("Unexpected churn of a.go: %v", a.Metadata)
+	}
+	if a.Metadata["last_modified"] != time.Unix(1700000300, 0).UTC().Format(time.RFC3339) {
+		t.Errorf("Expected the newest commit time, got %v", a.Metadata["last_modified"])
+	}
+
+	// a.go and b.go share two commits; a.go and c.go only one
+	m, ok := cat.GetMorphism("co_change:pkg/a.go->pkg/b.go")
+	if !ok || m.Type != "co_changes_with" || m.Metadata["weight"] != 2 {
+		t.Errorf("Expected a co-change of weight 2 between a.go and b.go, got %v", m)
+	}
+	if _, ok := cat.GetMorphism("co_change:pkg/a.go->pkg/c.go"); ok {
+		t.Error("Expected no co-change below MinCoChanges")
+	}
+
+	// Commits touching too many files do not couple them
+	cat = historyFiles(t)
+	h.MaxFilesPerCommit = 1
+	if err := h.enrich(cat, fileIDs, commits); err != nil {
+		t.Fatalf("enrich failed: %v", err)
+	}
+	if _, ok := cat.GetMorphism("co_change:pkg/a.go->pkg/b.go"); ok {
+		t.Error("Expected commits over MaxFilesPerCommit to be skipped")
+	}
+}
+
+func TestParseUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		diff string
+		want string
+	}{
+		{
+			name: "modified",
+			diff: "diff --git a/pkg/a.go b/pkg/a.go\n" +
+				"index 1111111..2222222 100644\n" +
+				"--- a/pkg/a.go\n" +
+				"+++ b/pkg/a.go\n" +
+				"@@ -1,2 +1,2 @@\n" +
+				" package pkg\n" +
+				"-var x = 1\n" +
+				"+var x = 2\n",
+			want: "pkg/a.go",
+		},
+		{
+			name: "added and deleted",
+			diff: "diff --git a/pkg/new.go b/pkg/new.go\n" +
+				"new file mode 100644\n" +
+				"--- /dev/null\n" +
+				"+++ b/pkg/new.go\n" +
+				"@@ -0,0 +1 @@\n" +
+				"+package pkg\n" +
+				"diff --git a/pkg/old.go b/pkg/old.go\n" +
+				"deleted file mode 100644\n" +
+				"--- a/pkg/old.go\n" +
+				"+++ /dev/null\n" +
+				"@@ -1 +0,0 @@\n" +
+				"-package pkg\n",
+			want: "pkg/new.go,pkg/old.go",
+		},
+		{
+			name: "renamed",
+			diff: "diff --git a/pkg/from.go b/pkg/to.go\n" +
+				"similarity index 100%\n" +
+				"rename from pkg/from.go\n" +
+				"rename to pkg/to.go\n" +
+				"diff --git a/pkg/x.go b/pkg/y.go\n" +
+				"similarity index 90%\n" +
+				"rename from pkg/x.go\n" +
+				"rename to pkg/y.go\n" +
+				"--- a/pkg/x.go\n" +
+				"+++ b/pkg/y.go\n" +
+				"@@ -1 +1 @@\n" +
+				"-package x\n" +
+				"+package y\n",
+			want: "pkg/to.go,pkg/y.go",
+		},
+		{
+			name: "hunk lines looking like headers",
+			diff: "diff --git a/docs/a.md b/docs/a.md\n" +
+				"--- a/docs/a.md\n" +
+				"+++ b/docs/a.md\n" +
+				"@@ -1,3 +1,3 @@\n" +
+				"--- a/removed.go\n" +
+				"+++ b/added.go\n" +
+				"\n" +
+				" context\n" +
+				"\\ No newline at end of file\n" +
+				"--- a/pkg/b.go\t2024-01-01 00:00:00\n" +
+				"+++ b/pkg/b.go\t2024-01-01 00:00:00\n" +
+				"@@ -5 +5,2 @@ func b() {\n" +
+				"-++ x\n" +
+				"+++ y\n" +
+				"+-- z\n",
+			want: "docs/a.md,pkg/b.go",
+		},
+		{
+			name: "without file headers",
+			diff: "diff --git a/img/logo.png b/img/logo.png\n" +
+				"index 1111111..2222222 100644\n" +
+				"Binary files a/img/logo.png and b/img/logo.png differ\n" +
+				"diff --git a/run.sh b/run.sh\n" +
+				"old mode 100644\n" +
+				"new mode 100755\n" +
+				"diff --git a/my file.go b/my file.go\n" +
+				"new file mode 100644\n" +
+				"index 0000000..e69de29\n" +
+				"diff --git a/gone.go b/gone.go\n" +
+				"deleted file mode 100644\n" +
+				"index e69de29..0000000\n",
+			want: "gone.go,img/logo.png,my file.go,run.sh",
+		},
+		{
+			name: "quoted paths",
+			diff: "diff --git \"a/caf\\303\\251.go\" \"b/caf\\303\\251.go\"\n" +
+				"new file mode 100644\n" +
+				"--- /dev/null\n" +
+				"+++ \"b/caf\\303\\251.go\"\n" +
+				"@@ -0,0 +1 @@\n" +
+				"+package caf\n" +
+				"diff --git \"a/tab\\there.go\" \"b/tab\\there.go\"\n" +
+				"old mode 100644\n" +
+				"new mode 100755\n" +
+				"diff --git a/old.go \"b/new \\\"name\\\".go\"\n" +
+				"similarity index 100%\n" +
+				"rename from old.go\n" +
+				"rename to \"new \\\"name\\\".go\"\n",
+			want: "caf\u00e9.go,new \"name\".go,tab\there.go",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := ParseUnifiedDiff(strings.NewReader(tt.diff))
+			if err != nil {
+				t.Fatalf("ParseUnifiedDiff failed: %v", err)
+			}
+			if got := strings.Join(files, ","); got != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+
+	if _, err := ParseUnifiedDiff(strings.NewReader("--- a/x\n+++ b/x\n@@ -1,x +1 @@\n")); err == nil {
+		t.Error("Expected an error for a malformed hunk header")
+	}
+}
+
+// gitRepo creates a repository in a temporary directory with one commit per
+// map of file contents, and returns its path.
+func gitRepo(t *testing.T, commits ...map[string]string) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	dir := t.TempDir()
+	run := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-C", dir,
+			"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s failed: %v: %s", strings.Join(args, " "), err, out)
+		}
+	}
+	run("init", "-q")
+	for i, files := range commits {
+		writeFiles(t, dir, files)
+		run("add", "-A")
+		run("commit", "-q", "-m", fmt.Sprintf("commit %d", i))
+	}
+	return dir
+}
+
+func TestChangedFiles(t *testing.T) {
+	dir := gitRepo(t,
+		map[string]string{"pkg/a.go": "package pkg\n", "pkg/b.go": "package pkg\n"},
+		map[string]string{"pkg/b.go": "package pkg // changed\n", "cmd/main.go": "package main\n"},
+	)
+	h := NewHistoryExtractor(dir)
+
+	files, err := h.ChangedFiles("HEAD~1..HEAD")
+	if err != nil {
+		t.Fatalf("ChangedFiles failed: %v", err)
+	}
+	if got := strings.Join(files, ","); got != "cmd/main.go,pkg/b.go" {
+		t.Errorf("Expected cmd/main.go,pkg/b.go, got %s", got)
+	}
+
+	if _, err := h.ChangedFiles("no-such-revision"); err == nil {
+		t.Error("Expected an error for an unknown revision")
+	}
+}
@@ -0,0 +1,85 @@
+package extractor
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// goModule is a Go module: its path, as declared in go.mod, and the
+// directory holding go.mod.
+type goModule struct {
+	path string
+	dir  string
+}
+
+// moduleFinder locates the module enclosing each directory, caching every
+// directory looked up on the way to its go.mod.
+type moduleFinder map[string]*goModule // Absolute directory -> module, nil if none
+
+// importPath returns the import path of the package in dir, or "" if dir is
+// not in a module.
+func (m moduleFinder) importPath(dir string) string {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	mod := m.find(abs)
+	if mod == nil {
+		return ""
+	}
+	rel, err := filepath.Rel(mod.dir, abs)
+	if err != nil {
+		return ""
+	}
+	if rel == "." {
+		return mod.path
+	}
+	return path.Join(mod.path, filepath.ToSlash(rel))
+}
+
+// find returns the module of the absolute directory dir: the nearest
+// go.mod in dir or above it.
+func (m moduleFinder) find(dir string) *goModule {
+	if mod, ok := m[dir]; ok {
+		return mod
+	}
+	var mod *goModule
+	if modPath := readModulePath(filepath.Join(dir, "go.mod")); modPath != "" {
+		mod = &goModule{path: modPath, dir: dir}
+	} else if parent := filepath.Dir(dir); parent != dir {
+		mod = m.find(parent)
+	}
+	m[dir] = mod
+	return mod
+}
+
+// readModulePath returns the module path declared by a go.mod file, or ""
+// if the file cannot be read or declares none.
+func readModulePath(gomod string) string {
+	f, err := os.Open(gomod)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 || fields[0] != "module" {
+			continue
+		}
+		if unquoted, err := strconv.Unquote(fields[1]); err == nil {
+			return unquoted
+		}
+		return fields[1]
+	}
+	return ""
+}
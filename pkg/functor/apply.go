@@ -0,0 +1,75 @@
+package functor
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/manu/catreview/pkg/category"
+)
+
+// MappingFailure is an object or morphism a functor failed to map.
+type MappingFailure struct {
+	ID  string
+	Err error
+}
+
+// Application is the result of applying a functor to its whole source
+// category.
+type Application struct {
+	Target    *category.Category
+	Objects   int              // Source objects mapped
+	Morphisms int              // Source morphisms mapped, identities included
+	Unmapped  []string         // Objects and morphisms deliberately left out
+	Failures  []MappingFailure // Objects and morphisms that could not be mapped
+	LawError  error            // Nil when the functor laws hold
+}
+
+// Apply maps every object and then every morphism of f's source category
+// into f's target, which should be fresh, and verifies the functor laws
+// against the result.
+//
+// Mapping errors do not stop the application; they are collected in
+// Failures. Law verification must leave the target as mapped: Apply returns
+// an error if it changes the target, as the verified category would then
+// differ from the one returned.
+func Apply(f Functor) (*Application, error) {
+	app := &Application{Target: f.TargetCategory()}
+	record := func(id string, err error) {
+		if errors.Is(err, ErrUnmapped) {
+			app.Unmapped = append(app.Unmapped, id)
+		} else {
+			app.Failures = append(app.Failures, MappingFailure{ID: id, Err: err})
+		}
+	}
+
+	for _, obj := range f.SourceCategory().Objects() {
+		if _, err := f.MapObject(obj); err != nil {
+			record(obj.ID, err)
+			continue
+		}
+		app.Objects++
+	}
+	for _, morph := range f.SourceCategory().Morphisms() {
+		if _, err := f.MapMorphism(morph); err != nil {
+			record(morph.ID, err)
+			continue
+		}
+		app.Morphisms++
+	}
+
+	before, err := category.CanonicalJSON(app.Target)
+	if err != nil {
+		return nil, err
+	}
+	app.LawError = f.VerifyLaws()
+	after, err := category.CanonicalJSON(app.Target)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(before, after) {
+		return nil, fmt.Errorf("verifying %s changed its target category %s", f.Name(), app.Target.Name)
+	}
+
+	return app, nil
+}
@@ -0,0 +1,74 @@
+package functor
+
+import (
+	"testing"
+
+	"github.com/manu/catreview/internal/modeltest"
+	"github.com/manu/catreview/pkg/category"
+)
+
+func TestApplyPackageAbstraction(t *testing.T) {
+	source := ruleFixture(t)
+	// A dependency within the billing package
+	modeltest.Add(t, source, nil, []*category.Morphism{
+		category.NewMorphism("m5", "services/billing/a.go", "services/billing/b.go", "function_call", nil),
+	})
+	f := NewPackageAbstractionFunctor(source, category.NewCategory("package_level"))
+
+	app, err := Apply(f)
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if app.LawError != nil {
+		t.Errorf("Expected functor laws to hold, got %v", app.LawError)
+	}
+	if len(app.Failures) != 0 || len(app.Unmapped) != 0 {
+		t.Errorf("Expected every object and morphism mapped, got failures %v", app.Failures)
+	}
+
+	// import:fmt has no package and maps to the external object
+	fmtObj, _ := source.GetObject("import:fmt")
+	ext, err := f.MapObject(fmtObj)
+	if err != nil || ext.ID != ExternalObjectID {
+		t.Errorf("Expected import:fmt to map to %s, got %v (%v)", ExternalObjectID, ext, err)
+	}
+
+	// Identities and internal dependencies map to the target's own identity
+	identity, _ := app.Target.Identity("pkg:billing")
+	for _, id := range []string{"id_services/billing/a.go", "m5"} {
+		m, _ := source.GetMorphism(id)
+		mapped, err := f.MapMorphism(m)
+		if err != nil || mapped != identity {
+			t.Errorf("Expected %s to map to the identity of pkg:billing, got %v (%v)", id, mapped, err)
+		}
+	}
+
+	// The composite a.go → c.go → fmt must not add pkg:billing → external
+	stats := app.Target.Stats()
+	if stats["objects"] != 3 || stats["morphisms"] != 2 {
+		t.Errorf("Expected 3 objects and 2 dependencies, got %v", stats)
+	}
+	if _, exists := app.Target.GetMorphism("dep:pkg:billing->external"); exists {
+		t.Error("Expected law verification not to add composite dependencies")
+	}
+}
+
+func TestApplyCollectsUnmapped(t *testing.T) {
+	source := ruleFixture(t)
+	f, err := NewRuleFunctor(&RuleSpec{
+		Objects:   []ObjectRule{{Match: ObjectMatch{ID: "services/**"}, Target: ObjectTemplate{ID: "services"}}},
+		Unmatched: "drop",
+	}, source, category.NewCategory("D"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app, err := Apply(f)
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if app.Objects != 2 || len(app.Unmapped) == 0 || len(app.Failures) != 0 {
+		t.Errorf("Expected 2 mapped objects and dropped ones unmapped, got %d, %v and %v",
+			app.Objects, app.Unmapped, app.Failures)
+	}
+}
@@ -0,0 +1,59 @@
+package functor
+
+import (
+	"fmt"
+
+	"github.com/manu/catreview/pkg/category"
+)
+
+// ComposedFunctor is the composite G ∘ F: C → E of functors F: C → D and
+// G: D → E. It maps objects and morphisms through F and then G.
+//
+// G maps F's images lazily, so functors whose source must be complete when
+// they are built (such as QuotientFunctor) should be built after F has been
+// applied to all of C.
+type ComposedFunctor struct {
+	*BaseFunctor
+	first  Functor
+	second Functor
+}
+
+// Compose returns G ∘ F. The target category of f must be the source
+// category of g.
+func Compose(f, g Functor) (*ComposedFunctor, error) {
+	if f.TargetCategory() != g.SourceCategory() {
+		return nil, fmt.Errorf("cannot compose %s after %s: target %s is not source %s",
+			g.Name(), f.Name(), f.TargetCategory().Name, g.SourceCategory().Name)
+	}
+	return &ComposedFunctor{
+		BaseFunctor: NewBaseFunctor(fmt.Sprintf("%s∘%s", g.Name(), f.Name()), f.SourceCategory(), g.TargetCategory()),
+		first:       f,
+		second:      g,
+	}, nil
+}
+
+// MapObject maps an object through F and then G.
+func (c *ComposedFunctor) MapObject(obj *category.Object) (*category.Object, error) {
+	mid, err := c.first.MapObject(obj)
+	if err != nil {
+		return nil, err
+	}
+	return c.second.MapObject(mid)
+}
+
+// MapMorphism maps a morphism through F and then G.
+func (c *ComposedFunctor) MapMorphism(morph *category.Morphism) (*category.Morphism, error) {
+	mid, err := c.first.MapMorphism(morph)
+	if err != nil {
+		return nil, err
+	}
+	return c.second.MapMorphism(mid)
+}
+
+// VerifyLaws verifies the functor laws of the composite itself.
+func (c *ComposedFunctor) VerifyLaws() error {
+	if err := c.VerifyIdentityLaw(c); err != nil {
+		return err
+	}
+	return c.VerifyCompositionLaw(c)
+}
@@ -0,0 +1,53 @@
+package functor
+
+import (
+	"testing"
+
+	"github.com/manu/catreview/pkg/category"
+)
+
+func TestCompose(t *testing.T) {
+	source := ruleFixture(t)
+
+	// files → directories → top-level directories
+	byDir := NewQuotient(source, category.ByDirectory(0))
+	topLevel, err := category.ByRegex(`^dir:([^/]+)`, "top:$1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	byTop := NewQuotient(byDir.TargetCategory(), topLevel)
+
+	composite, err := Compose(byDir, byTop)
+	if err != nil {
+		t.Fatalf("Compose failed: %v", err)
+	}
+	if composite.SourceCategory() != source || composite.TargetCategory() != byTop.TargetCategory() {
+		t.Error("Expected composite to map the first source to the last target")
+	}
+
+	obj, _ := source.GetObject("services/billing/a.go")
+	mapped, err := composite.MapObject(obj)
+	if err != nil {
+		t.Fatalf("MapObject failed: %v", err)
+	}
+	if mapped.ID != "top:services" {
+		t.Errorf("Expected top:services, got %s", mapped.ID)
+	}
+
+	m, _ := source.GetMorphism("m1")
+	dep, err := composite.MapMorphism(m)
+	if err != nil {
+		t.Fatalf("MapMorphism failed: %v", err)
+	}
+	if dep.Source != "top:services" || dep.Target != "top:lib" {
+		t.Errorf("Expected top:services -> top:lib, got %s -> %s", dep.Source, dep.Target)
+	}
+
+	if err := composite.VerifyLaws(); err != nil {
+		t.Errorf("Composite violates functor laws: %v", err)
+	}
+
+	if _, err := Compose(byTop, byDir); err == nil {
+		t.Error("Expected error composing functors with mismatched categories")
+	}
+}
@@ -0,0 +1,255 @@
+package functor
+
+import (
+	"fmt"
+
+	"github.com/manu/catreview/pkg/category"
+)
+
+// OppositeFunctor is the contravariant identity-on-objects functor C → C^op.
+//
+// It maps every object to itself and every morphism f: A → B to the
+// reversed f: B → A of the opposite category. Being contravariant, it
+// reverses composition: F(g ∘ f) = F(f) ∘ F(g).
+type OppositeFunctor struct {
+	*BaseFunctor
+}
+
+// NewOppositeFunctor creates the functor from source to its opposite, which
+// must have been built with source.Op().
+func NewOppositeFunctor(source, opposite *category.Category) *OppositeFunctor {
+	return &OppositeFunctor{
+		BaseFunctor: NewBaseFunctor("Opposite", source, opposite),
+	}
+}
+
+// MapObject maps an object to the same object of the opposite category.
+func (f *OppositeFunctor) MapObject(obj *category.Object) (*category.Object, error) {
+	target, exists := f.target.GetObject(obj.ID)
+	if !exists {
+		return nil, fmt.Errorf("object %s not found in %s", obj.ID, f.target.Name)
+	}
+	return target, nil
+}
+
+// MapMorphism maps a morphism to its reversed counterpart.
+func (f *OppositeFunctor) MapMorphism(morph *category.Morphism) (*category.Morphism, error) {
+	if morph.Type == "identity" {
+		return f.identity(morph.Source)
+	}
+	if reversed, exists := f.target.GetMorphism(morph.ID); exists {
+		return reversed, nil
+	}
+	// Composites are not stored; reverse them directly
+	return category.NewMorphism(morph.ID, morph.Target, morph.Source, morph.Type, morph.Metadata), nil
+}
+
+// VerifyLaws verifies the identity law and the contravariant composition law.
+func (f *OppositeFunctor) VerifyLaws() error {
+	if err := f.VerifyIdentityLaw(f); err != nil {
+		return err
+	}
+
+	checked := 0
+	const maxChecks = 50
+	morphisms := f.source.Morphisms()
+	for _, fm := range morphisms {
+		for _, gm := range morphisms {
+			if checked >= maxChecks {
+				return nil
+			}
+			if !fm.IsComposable(gm) {
+				continue
+			}
+			composed, err := f.source.Compose(fm, gm)
+			if err != nil {
+				continue
+			}
+			fComposed, err := f.MapMorphism(composed)
+			if err != nil {
+				return fmt.Errorf("failed to map composed morphism: %v", err)
+			}
+			ff, err := f.MapMorphism(fm)
+			if err != nil {
+				return fmt.Errorf("failed to map f: %v", err)
+			}
+			fg, err := f.MapMorphism(gm)
+			if err != nil {
+				return fmt.Errorf("failed to map g: %v", err)
+			}
+
+			// F(g ∘ f) = F(f) ∘ F(g)
+			reversed, err := f.target.Compose(fg, ff)
+			if err != nil {
+				return fmt.Errorf("failed to compose in target: %v", err)
+			}
+			if fComposed.Source != reversed.Source || fComposed.Target != reversed.Target {
+				return fmt.Errorf("contravariant composition law violated: F(%s∘%s) != F(%s)∘F(%s)",
+					gm.ID, fm.ID, fm.ID, gm.ID)
+			}
+			checked++
+		}
+	}
+	return nil
+}
+
+// identity returns the identity of an object in the target category.
+func (f *OppositeFunctor) identity(objectID string) (*category.Morphism, error) {
+	identity, exists := f.target.Identity(objectID)
+	if !exists {
+		return nil, fmt.Errorf("object %s has no identity in %s", objectID, f.target.Name)
+	}
+	return identity, nil
+}
+
+// ProjectionFunctor is a projection π: C × D → C or π: C × D → D of a
+// product category onto one of its factors.
+type ProjectionFunctor struct {
+	*BaseFunctor
+	side string // "left" or "right"
+}
+
+// NewLeftProjection creates π₁: C × D → C for product = category.Product(c, d).
+func NewLeftProjection(product, c *category.Category) *ProjectionFunctor {
+	return &ProjectionFunctor{
+		BaseFunctor: NewBaseFunctor("LeftProjection", product, c),
+		side:        "left",
+	}
+}
+
+// NewRightProjection creates π₂: C × D → D for product = category.Product(c, d).
+func NewRightProjection(product, d *category.Category) *ProjectionFunctor {
+	return &ProjectionFunctor{
+		BaseFunctor: NewBaseFunctor("RightProjection", product, d),
+		side:        "right",
+	}
+}
+
+// MapObject maps a pair (a, b) to its component.
+func (f *ProjectionFunctor) MapObject(obj *category.Object) (*category.Object, error) {
+	if cached, exists := f.GetObjectMapping(obj.ID); exists {
+		return cached, nil
+	}
+
+	componentID, ok := obj.Metadata[f.side].(string)
+	if !ok {
+		return nil, fmt.Errorf("object %s is not a product object", obj.ID)
+	}
+	component, exists := f.target.GetObject(componentID)
+	if !exists {
+		return nil, fmt.Errorf("component %s not found in %s", componentID, f.target.Name)
+	}
+
+	f.AddObjectMapping(obj.ID, component)
+	return component, nil
+}
+
+// MapMorphism maps a pair (f, g) to its component morphism.
+func (f *ProjectionFunctor) MapMorphism(morph *category.Morphism) (*category.Morphism, error) {
+	if cached, exists := f.GetMorphismMapping(morph.ID); exists {
+		return cached, nil
+	}
+
+	source, target, err := mapEndpoints(f, morph)
+	if err != nil {
+		return nil, err
+	}
+
+	var mapped *category.Morphism
+	if componentID, ok := morph.Metadata[f.side].(string); ok {
+		mapped, _ = f.target.GetMorphism(componentID)
+	}
+	if mapped == nil {
+		mapped = endpointMorphism(f.target, morph, source, target)
+	}
+
+	f.AddMorphismMapping(morph.ID, mapped)
+	return mapped, nil
+}
+
+// VerifyLaws verifies functor laws for this specific functor.
+func (f *ProjectionFunctor) VerifyLaws() error {
+	if err := f.VerifyIdentityLaw(f); err != nil {
+		return err
+	}
+	return f.VerifyCompositionLaw(f)
+}
+
+// InjectionFunctor is an injection ι: C → C + D or ι: D → C + D of a
+// category into a coproduct.
+type InjectionFunctor struct {
+	*BaseFunctor
+	tag string
+}
+
+// NewInjectionFunctor creates the injection of source into coproduct, where
+// tag is source's tag as returned by category.CoproductTags.
+func NewInjectionFunctor(source, coproduct *category.Category, tag string) *InjectionFunctor {
+	return &InjectionFunctor{
+		BaseFunctor: NewBaseFunctor(fmt.Sprintf("Injection(%s)", tag), source, coproduct),
+		tag:         tag,
+	}
+}
+
+// MapObject maps an object to its tagged copy.
+func (f *InjectionFunctor) MapObject(obj *category.Object) (*category.Object, error) {
+	id := category.CoproductID(f.tag, obj.ID)
+	tagged, exists := f.target.GetObject(id)
+	if !exists {
+		return nil, fmt.Errorf("object %s not found in %s", id, f.target.Name)
+	}
+	return tagged, nil
+}
+
+// MapMorphism maps a morphism to its tagged copy.
+func (f *InjectionFunctor) MapMorphism(morph *category.Morphism) (*category.Morphism, error) {
+	source, target, err := mapEndpoints(f, morph)
+	if err != nil {
+		return nil, err
+	}
+	if tagged, exists := f.target.GetMorphism(category.CoproductID(f.tag, morph.ID)); exists {
+		return tagged, nil
+	}
+	return endpointMorphism(f.target, morph, source, target), nil
+}
+
+// VerifyLaws verifies functor laws for this specific functor.
+func (f *InjectionFunctor) VerifyLaws() error {
+	if err := f.VerifyIdentityLaw(f); err != nil {
+		return err
+	}
+	return f.VerifyCompositionLaw(f)
+}
+
+// mapEndpoints maps the source and target objects of a morphism.
+func mapEndpoints(f Functor, morph *category.Morphism) (*category.Object, *category.Object, error) {
+	sourceObj, exists := f.SourceCategory().GetObject(morph.Source)
+	if !exists {
+		return nil, nil, fmt.Errorf("source object %s not found", morph.Source)
+	}
+	targetObj, exists := f.SourceCategory().GetObject(morph.Target)
+	if !exists {
+		return nil, nil, fmt.Errorf("target object %s not found", morph.Target)
+	}
+
+	source, err := f.MapObject(sourceObj)
+	if err != nil {
+		return nil, nil, err
+	}
+	target, err := f.MapObject(targetObj)
+	if err != nil {
+		return nil, nil, err
+	}
+	return source, target, nil
+}
+
+// endpointMorphism maps a morphism that has no stored counterpart, such as
+// an identity or a composite, by its mapped endpoints.
+func endpointMorphism(target *category.Category, morph *category.Morphism, source, dest *category.Object) *category.Morphism {
+	if source.ID == dest.ID {
+		if identity, exists := target.Identity(source.ID); exists {
+			return identity
+		}
+	}
+	return category.NewMorphism(morph.ID, source.ID, dest.ID, "composed", nil)
+}
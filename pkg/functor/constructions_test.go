@@ -0,0 +1,127 @@
+package functor
+
+import (
+	"testing"
+
+	"github.com/manu/catreview/internal/modeltest"
+	"github.com/manu/catreview/pkg/category"
+)
+
+// chain returns the category A → B → C with the given name.
+func chain(t *testing.T, name string) *category.Category {
+	var objects []*category.Object
+	for _, id := range []string{"A", "B", "C"} {
+		objects = append(objects, category.NewObject(id, "module", id, nil))
+	}
+	return modeltest.Build(t, name, objects, []*category.Morphism{
+		category.NewMorphism("f", "A", "B", "dependency", nil),
+		category.NewMorphism("g", "B", "C", "dependency", nil),
+	})
+}
+
+func TestOppositeFunctor(t *testing.T) {
+	cat := chain(t, "C")
+	op, err := cat.Op()
+	if err != nil {
+		t.Fatalf("Op failed: %v", err)
+	}
+	f := NewOppositeFunctor(cat, op)
+	if err := f.VerifyLaws(); err != nil {
+		t.Fatalf("Expected the contravariant functor laws to hold, got %v", err)
+	}
+
+	fm, _ := cat.GetMorphism("f")
+	mapped, err := f.MapMorphism(fm)
+	if err != nil || mapped.Source != "B" || mapped.Target != "A" {
+		t.Errorf("Expected f reversed to B → A, got %v (%v)", mapped, err)
+	}
+
+	// F(g ∘ f) = F(f) ∘ F(g): C → A
+	gm, _ := cat.GetMorphism("g")
+	composed, err := cat.Compose(fm, gm)
+	if err != nil {
+		t.Fatalf("Compose failed: %v", err)
+	}
+	mapped, err = f.MapMorphism(composed)
+	if err != nil || mapped.Source != "C" || mapped.Target != "A" {
+		t.Errorf("Expected g ∘ f reversed to C → A, got %v (%v)", mapped, err)
+	}
+
+	identity, _ := cat.Identity("B")
+	mapped, err = f.MapMorphism(identity)
+	opIdentity, _ := op.Identity("B")
+	if err != nil || mapped != opIdentity {
+		t.Errorf("Expected the identity of B in the opposite, got %v (%v)", mapped, err)
+	}
+}
+
+func TestProjectionFunctors(t *testing.T) {
+	c := chain(t, "C")
+	d := category.NewCategory("D")
+	modeltest.Add(t, d, []*category.Object{
+		category.NewObject("X", "module", "X", nil),
+		category.NewObject("Y", "module", "Y", nil),
+	}, []*category.Morphism{
+		category.NewMorphism("h", "X", "Y", "dependency", nil),
+	})
+	product, err := category.Product(c, d)
+	if err != nil {
+		t.Fatalf("Product failed: %v", err)
+	}
+
+	left := NewLeftProjection(product, c)
+	right := NewRightProjection(product, d)
+	for _, p := range []*ProjectionFunctor{left, right} {
+		if err := p.VerifyLaws(); err != nil {
+			t.Errorf("Expected %s to satisfy the functor laws, got %v", p.Name(), err)
+		}
+	}
+
+	fh, _ := product.GetMorphism("(f,h)")
+	if m, err := left.MapMorphism(fh); err != nil || m.ID != "f" {
+		t.Errorf("Expected π₁(f,h) = f, got %v (%v)", m, err)
+	}
+	if m, err := right.MapMorphism(fh); err != nil || m.ID != "h" {
+		t.Errorf("Expected π₂(f,h) = h, got %v (%v)", m, err)
+	}
+
+	// (id_A, h) projects to the identity of A
+	ah, _ := product.GetMorphism("(id_A,h)")
+	identity, _ := c.Identity("A")
+	if m, err := left.MapMorphism(ah); err != nil || m != identity {
+		t.Errorf("Expected π₁(id_A,h) = id_A, got %v (%v)", m, err)
+	}
+
+	pair, _ := product.GetObject(category.ProductObjectID("B", "Y"))
+	if obj, err := right.MapObject(pair); err != nil || obj.ID != "Y" {
+		t.Errorf("Expected π₂(B,Y) = Y, got %v (%v)", obj, err)
+	}
+}
+
+func TestInjectionFunctors(t *testing.T) {
+	a := chain(t, "svc-a")
+	b := chain(t, "svc-b")
+	coproduct, err := category.Coproduct(a, b)
+	if err != nil {
+		t.Fatalf("Coproduct failed: %v", err)
+	}
+	leftTag, rightTag := category.CoproductTags(a, b)
+
+	left := NewInjectionFunctor(a, coproduct, leftTag)
+	right := NewInjectionFunctor(b, coproduct, rightTag)
+	for _, inj := range []*InjectionFunctor{left, right} {
+		if err := inj.VerifyLaws(); err != nil {
+			t.Errorf("Expected %s to satisfy the functor laws, got %v", inj.Name(), err)
+		}
+	}
+
+	g, _ := b.GetMorphism("g")
+	m, err := right.MapMorphism(g)
+	if err != nil || m.ID != "svc-b:g" || m.Source != "svc-b:B" || m.Target != "svc-b:C" {
+		t.Errorf("Expected g injected as svc-b:g, got %v (%v)", m, err)
+	}
+	objA, _ := a.GetObject("A")
+	if obj, err := left.MapObject(objA); err != nil || obj.ID != "svc-a:A" {
+		t.Errorf("Expected A injected as svc-a:A, got %v (%v)", obj, err)
+	}
+}
@@ -0,0 +1,133 @@
+package functor
+
+import (
+	"fmt"
+
+	"github.com/manu/catreview/pkg/category"
+)
+
+// groupFunctor maps objects to group objects chosen by a group function and
+// aggregates morphisms between groups like PackageAbstractionFunctor: group
+// objects list their "files", dependencies between groups their
+// "source_files" and "target_files", and dependencies within a group map to
+// its identity.
+type groupFunctor struct {
+	*BaseFunctor
+
+	// group returns the group object for obj; it is added to the target on
+	// first use, with a "files" list appended to its metadata.
+	group func(obj *category.Object) *category.Object
+}
+
+// MapObject maps an object to its group.
+func (f *groupFunctor) MapObject(obj *category.Object) (*category.Object, error) {
+	if cached, exists := f.GetObjectMapping(obj.ID); exists {
+		return cached, nil
+	}
+
+	proto := f.group(obj)
+	groupObj, exists := f.target.GetObject(proto.ID)
+	if !exists {
+		groupObj = proto
+		if groupObj.Metadata == nil {
+			groupObj.Metadata = make(map[string]interface{})
+		}
+		groupObj.Metadata["files"] = []string{obj.ID}
+		if err := f.target.AddObject(groupObj); err != nil {
+			return nil, err
+		}
+	} else if files, ok := groupObj.Metadata["files"].([]string); ok {
+		groupObj.Metadata["files"] = append(files, obj.ID)
+	}
+
+	f.AddObjectMapping(obj.ID, groupObj)
+	return groupObj, nil
+}
+
+// MapMorphism maps a dependency to a dependency between groups.
+func (f *groupFunctor) MapMorphism(morph *category.Morphism) (*category.Morphism, error) {
+	if cached, exists := f.GetMorphismMapping(morph.ID); exists {
+		return cached, nil
+	}
+
+	sourceGroup, targetGroup, err := mapEndpoints(f, morph)
+	if err != nil {
+		return nil, err
+	}
+
+	// Composites built during law verification must not add dependencies
+	if !isStored(f.source, morph) {
+		return endpointMorphism(f.target, morph, sourceGroup, targetGroup), nil
+	}
+
+	if sourceGroup.ID == targetGroup.ID {
+		identity, _ := f.target.Identity(sourceGroup.ID)
+		f.AddMorphismMapping(morph.ID, identity)
+		return identity, nil
+	}
+
+	depID := fmt.Sprintf("dep:%s->%s", sourceGroup.ID, targetGroup.ID)
+	dep, exists := f.target.GetMorphism(depID)
+	if !exists {
+		dep = category.NewMorphism(depID, sourceGroup.ID, targetGroup.ID, "dependency", map[string]interface{}{
+			"source_files": []string{morph.Source},
+			"target_files": []string{morph.Target},
+		})
+		if err := f.target.AddMorphism(dep); err != nil {
+			return nil, err
+		}
+	} else {
+		if sources, ok := dep.Metadata["source_files"].([]string); ok {
+			dep.Metadata["source_files"] = append(sources, morph.Source)
+		}
+		if targets, ok := dep.Metadata["target_files"].([]string); ok {
+			dep.Metadata["target_files"] = append(targets, morph.Target)
+		}
+	}
+
+	f.AddMorphismMapping(morph.ID, dep)
+	return dep, nil
+}
+
+// VerifyLaws verifies functor laws for this specific functor.
+func (f *groupFunctor) VerifyLaws() error {
+	if err := f.VerifyIdentityLaw(f); err != nil {
+		return err
+	}
+	return f.VerifyCompositionLaw(f)
+}
+
+// DirectoryFunctor maps a file-level category to a category of directories.
+//
+// Objects are grouped by the directory of their file (their ID for file
+// objects, their "file" metadata otherwise), truncated to its first depth
+// path segments when depth > 0: depth 1 groups "internal/api/x.go" into
+// "dir:internal", depth 2 into "dir:internal/api". Objects without a file,
+// such as imported packages, map to the external object.
+type DirectoryFunctor struct {
+	*groupFunctor
+	Depth int
+}
+
+// NewDirectoryFunctor creates a functor from file-level to directory-level.
+func NewDirectoryFunctor(source, target *category.Category, depth int) *DirectoryFunctor {
+	key := category.ByDirectory(depth)
+	name := "FileToDirectory"
+	if depth > 0 {
+		name = fmt.Sprintf("FileToDirectory%d", depth)
+	}
+
+	f := &DirectoryFunctor{Depth: depth}
+	f.groupFunctor = &groupFunctor{
+		BaseFunctor: NewBaseFunctor(name, source, target),
+		group: func(obj *category.Object) *category.Object {
+			id := key(obj)
+			if id == "" {
+				return category.NewObject(ExternalObjectID, "external", "external", nil)
+			}
+			dir := id[len("dir:"):]
+			return category.NewObject(id, "directory", dir, map[string]interface{}{"path": dir})
+		},
+	}
+	return f
+}
@@ -0,0 +1,80 @@
+package functor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/manu/catreview/internal/modeltest"
+	"github.com/manu/catreview/pkg/category"
+	"github.com/manu/catreview/pkg/extractor"
+)
+
+func TestDirectoryFunctor(t *testing.T) {
+	source := ruleFixture(t)
+
+	tests := []struct {
+		depth    int
+		expected []string
+	}{
+		{0, []string{"dir:lib/shared", "dir:services/billing", ExternalObjectID}},
+		{1, []string{"dir:lib", "dir:services", ExternalObjectID}},
+	}
+
+	for _, tt := range tests {
+		f := NewDirectoryFunctor(source, category.NewCategory("directories"), tt.depth)
+		app, err := Apply(f)
+		if err != nil {
+			t.Fatalf("Apply failed: %v", err)
+		}
+		if app.LawError != nil || len(app.Failures) != 0 {
+			t.Errorf("depth %d: expected clean application, got %v and %v", tt.depth, app.LawError, app.Failures)
+		}
+
+		objects := app.Target.Objects()
+		if len(objects) != len(tt.expected) {
+			t.Fatalf("depth %d: expected %d directories, got %d", tt.depth, len(tt.expected), len(objects))
+		}
+		for i, obj := range objects {
+			if obj.ID != tt.expected[i] {
+				t.Errorf("depth %d: expected %s, got %s", tt.depth, tt.expected[i], obj.ID)
+			}
+		}
+	}
+
+	f := NewDirectoryFunctor(source, category.NewCategory("directories"), 1)
+	Apply(f)
+	services, _ := f.TargetCategory().GetObject("dir:services")
+	if files, _ := services.Metadata["files"].([]string); len(files) != 2 {
+		t.Errorf("Expected dir:services to list 2 files, got %v", services.Metadata["files"])
+	}
+	dep, exists := f.TargetCategory().GetMorphism("dep:dir:services->dir:lib")
+	if !exists {
+		t.Fatal("Expected dependency dir:services -> dir:lib")
+	}
+	if sources, _ := dep.Metadata["source_files"].([]string); len(sources) != 3 {
+		t.Errorf("Expected 3 source files on the dependency, got %v", dep.Metadata["source_files"])
+	}
+}
+
+func TestDirectoryFunctorExtracted(t *testing.T) {
+	// Extracted from an absolute path, directories start at the module root
+	source, err := extractor.NewGoExtractor().ExtractFromPath(modeltest.Shop(t))
+	if err != nil {
+		t.Fatalf("ExtractFromPath failed: %v", err)
+	}
+	app, err := Apply(NewDirectoryFunctor(source, category.NewCategory("directories"), 1))
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+
+	var ids []string
+	for _, obj := range app.Target.Objects() {
+		ids = append(ids, obj.ID)
+	}
+	if want := "dir:audit,dir:cmd,dir:internal,dir:tools," + ExternalObjectID; strings.Join(ids, ",") != want {
+		t.Errorf("Expected %s, got %v", want, ids)
+	}
+	if _, exists := app.Target.GetMorphism("dep:dir:cmd->dir:internal"); !exists {
+		t.Error("Expected dependency dir:cmd -> dir:internal")
+	}
+}
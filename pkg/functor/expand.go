@@ -0,0 +1,88 @@
+package functor
+
+import (
+	"fmt"
+
+	"github.com/manu/catreview/pkg/category"
+)
+
+// Expand drills down from an abstraction: it returns the sub-diagram of f's
+// source category lying over the target object with the given ID, that is
+// the objects F maps onto it and the morphisms between them F maps onto its
+// identity.
+//
+// Where abstraction functors forget detail, Expand recovers it as the
+// inverse image of F. Together they form a Galois connection between
+// sub-diagrams of the two categories: a sub-diagram S lies within Expand(X)
+// exactly when F maps S into X.
+func Expand(f Functor, objectID string) (*category.Category, error) {
+	target := f.TargetCategory()
+	if _, exists := target.GetObject(objectID); !exists {
+		return nil, fmt.Errorf("object %s not found in %s", objectID, target.Name)
+	}
+	identity, _ := target.Identity(objectID)
+
+	sub := category.NewCategory(fmt.Sprintf("expand(%s)", objectID))
+	for _, obj := range f.SourceCategory().Objects() {
+		if mapped, err := f.MapObject(obj); err == nil && mapped.ID == objectID {
+			if err := sub.AddObject(obj); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := addPreimage(f, sub, identity); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+// ExpandMorphism returns the sub-diagram of f's source category lying over
+// the target morphism with the given ID: the morphisms F maps onto it and
+// their endpoints.
+func ExpandMorphism(f Functor, morphismID string) (*category.Category, error) {
+	target := f.TargetCategory()
+	morph, exists := target.GetMorphism(morphismID)
+	if !exists {
+		return nil, fmt.Errorf("morphism %s not found in %s", morphismID, target.Name)
+	}
+	if morph.Type == "identity" {
+		return Expand(f, morph.Source)
+	}
+
+	sub := category.NewCategory(fmt.Sprintf("expand(%s)", morphismID))
+	if err := addPreimage(f, sub, morph); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+// addPreimage adds the non-identity source morphisms F maps onto morph to
+// sub, together with their endpoints.
+func addPreimage(f Functor, sub *category.Category, morph *category.Morphism) error {
+	if morph == nil {
+		return nil
+	}
+	source := f.SourceCategory()
+	for _, m := range source.Morphisms() {
+		if m.Type == "identity" {
+			continue
+		}
+		mapped, err := f.MapMorphism(m)
+		if err != nil || mapped.ID != morph.ID {
+			continue
+		}
+		for _, id := range []string{m.Source, m.Target} {
+			if _, exists := sub.GetObject(id); exists {
+				continue
+			}
+			obj, _ := source.GetObject(id)
+			if err := sub.AddObject(obj); err != nil {
+				return err
+			}
+		}
+		if err := sub.AddMorphism(m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
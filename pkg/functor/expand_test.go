@@ -0,0 +1,51 @@
+package functor
+
+import (
+	"testing"
+
+	"github.com/manu/catreview/internal/modeltest"
+	"github.com/manu/catreview/pkg/category"
+)
+
+func TestExpand(t *testing.T) {
+	source := ruleFixture(t)
+	modeltest.Add(t, source, nil, []*category.Morphism{
+		category.NewMorphism("m5", "services/billing/a.go", "services/billing/b.go", "function_call", nil),
+	})
+	f := NewPackageAbstractionFunctor(source, category.NewCategory("package_level"))
+	if _, err := Apply(f); err != nil {
+		t.Fatal(err)
+	}
+
+	// Package: its files and the dependencies between them
+	sub, err := Expand(f, "pkg:billing")
+	if err != nil {
+		t.Fatalf("Expand failed: %v", err)
+	}
+	stats := sub.Stats()
+	if stats["objects"] != 2 || stats["morphisms"] != 1 {
+		t.Errorf("Expected 2 files and 1 dependency, got %v", stats)
+	}
+	if _, exists := sub.GetMorphism("m5"); !exists {
+		t.Error("Expected internal dependency m5 in the expansion")
+	}
+
+	// Package dependency: the file dependencies behind it
+	sub, err = ExpandMorphism(f, "dep:pkg:billing->pkg:sharedutil")
+	if err != nil {
+		t.Fatalf("ExpandMorphism failed: %v", err)
+	}
+	for _, id := range []string{"m1", "m2", "m3"} {
+		if _, exists := sub.GetMorphism(id); !exists {
+			t.Errorf("Expected %s in the expansion", id)
+		}
+	}
+	stats = sub.Stats()
+	if stats["objects"] != 3 || stats["morphisms"] != 3 {
+		t.Errorf("Expected 3 files and 3 dependencies, got %v", stats)
+	}
+
+	if _, err := ExpandMorphism(f, "dep:pkg:sharedutil->pkg:billing"); err == nil {
+		t.Error("Expected error for a morphism not in the abstraction")
+	}
+}
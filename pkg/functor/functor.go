@@ -8,11 +8,17 @@
 package functor
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/manu/catreview/pkg/category"
 )
 
+// ErrUnmapped is returned (wrapped) by MapObject and MapMorphism for objects
+// and morphisms a functor deliberately leaves out of its target, such as those
+// dropped by a RuleFunctor. Law verification skips them.
+var ErrUnmapped = errors.New("deliberately unmapped")
+
 // Functor represents a structure-preserving mapping between categories.
 //
 // A functor F: C → D consists of:
@@ -42,6 +48,13 @@ type Functor interface {
 	VerifyLaws() error
 }
 
+// isStored reports whether morph is a morphism of c rather than one built
+// on the fly, such as a composite.
+func isStored(c *category.Category, morph *category.Morphism) bool {
+	stored, exists := c.GetMorphism(morph.ID)
+	return exists && stored == morph
+}
+
 // BaseFunctor provides common functor functionality.
 type BaseFunctor struct {
 	name     string
@@ -123,23 +136,29 @@ func (f *BaseFunctor) VerifyCompositionLaw(mapper Functor) error {
 				continue
 			}
 
-			// Compute F(g ∘ f)
-			fComposed, err1 := mapper.MapMorphism(composed)
-			if err1 != nil {
-				return fmt.Errorf("failed to map composed morphism: %v", err1)
-			}
-
-			// Compute F(f) and F(g)
+			// Compute F(f) and F(g); skip pairs the functor leaves unmapped
 			ff, err2 := mapper.MapMorphism(fMorph)
+			if errors.Is(err2, ErrUnmapped) {
+				continue
+			}
 			if err2 != nil {
 				return fmt.Errorf("failed to map f: %v", err2)
 			}
 
 			fg, err3 := mapper.MapMorphism(gMorph)
+			if errors.Is(err3, ErrUnmapped) {
+				continue
+			}
 			if err3 != nil {
 				return fmt.Errorf("failed to map g: %v", err3)
 			}
 
+			// Compute F(g ∘ f)
+			fComposed, err1 := mapper.MapMorphism(composed)
+			if err1 != nil {
+				return fmt.Errorf("failed to map composed morphism: %v", err1)
+			}
+
 			// Compute F(g) ∘ F(f) in target category
 			fgComposed, err4 := f.target.Compose(ff, fg)
 			if err4 != nil {
@@ -165,15 +184,21 @@ func (f *BaseFunctor) VerifyIdentityLaw(mapper Functor) error {
 
 	for _, obj := range objects {
 		// Get identity morphism for this object
-		idMorph := &category.Morphism{
-			ID:     fmt.Sprintf("id_%s", obj.ID),
-			Source: obj.ID,
-			Target: obj.ID,
-			Type:   "identity",
+		idMorph, exists := f.source.Identity(obj.ID)
+		if !exists {
+			idMorph = &category.Morphism{
+				ID:     fmt.Sprintf("id_%s", obj.ID),
+				Source: obj.ID,
+				Target: obj.ID,
+				Type:   "identity",
+			}
 		}
 
 		// Map the identity
 		fIdMorph, err := mapper.MapMorphism(idMorph)
+		if errors.Is(err, ErrUnmapped) {
+			continue
+		}
 		if err != nil {
 			return fmt.Errorf("failed to map identity for %s: %v", obj.ID, err)
 		}
@@ -184,21 +209,31 @@ func (f *BaseFunctor) VerifyIdentityLaw(mapper Functor) error {
 			return fmt.Errorf("failed to map object %s: %v", obj.ID, err)
 		}
 
-		// Verify F(id_A) is identity for F(A)
+		// Verify F(id_A) is identity for F(A), and the target's own identity
+		// where it has one
 		if fIdMorph.Source != fObj.ID || fIdMorph.Target != fObj.ID {
 			return fmt.Errorf("identity law violated: F(id_%s) is not id_{F(%s)}",
 				obj.ID, obj.ID)
 		}
+		if identity, exists := f.target.Identity(fObj.ID); exists && fIdMorph.ID != identity.ID {
+			return fmt.Errorf("identity law violated: F(id_%s) = %s is not the identity %s of %s",
+				obj.ID, fIdMorph.ID, identity.ID, f.target.Name)
+		}
 	}
 
 	return nil
 }
 
+// ExternalObjectID is the object PackageAbstractionFunctor maps objects
+// without package metadata to, such as imported packages.
+const ExternalObjectID = "external"
+
 // PackageAbstractionFunctor maps file-level category to package-level category.
 //
 // This functor abstracts from individual files to packages:
-// - Files → Packages
+// - Files → Packages (objects without a package → "external")
 // - File dependencies → Package dependencies
+// - Dependencies within a package → The package's identity
 //
 // This is useful for viewing architecture at different granularities.
 type PackageAbstractionFunctor struct {
@@ -219,17 +254,17 @@ func (f *PackageAbstractionFunctor) MapObject(obj *category.Object) (*category.O
 		return cached, nil
 	}
 
-	// Extract package from file metadata
-	packageName, ok := obj.Metadata["package"].(string)
-	if !ok {
-		return nil, fmt.Errorf("object %s has no package metadata", obj.ID)
+	// Extract package from file metadata; objects outside the analyzed code,
+	// such as imported packages, map to the external object
+	pkgID, pkgType, packageName := ExternalObjectID, "external", "external"
+	if name, ok := obj.Metadata["package"].(string); ok && name != "" {
+		pkgID, pkgType, packageName = fmt.Sprintf("pkg:%s", name), "package", name
 	}
 
 	// Create or get package object
-	pkgID := fmt.Sprintf("pkg:%s", packageName)
 	pkgObj, exists := f.target.GetObject(pkgID)
 	if !exists {
-		pkgObj = category.NewObject(pkgID, "package", packageName, map[string]interface{}{
+		pkgObj = category.NewObject(pkgID, pkgType, packageName, map[string]interface{}{
 			"files": []string{obj.ID},
 		})
 		if err := f.target.AddObject(pkgObj); err != nil {
@@ -255,37 +290,23 @@ func (f *PackageAbstractionFunctor) MapMorphism(morph *category.Morphism) (*cate
 		return cached, nil
 	}
 
-	// Get source and target objects from source category
-	sourceObj, exists := f.source.GetObject(morph.Source)
-	if !exists {
-		return nil, fmt.Errorf("source object %s not found", morph.Source)
-	}
-
-	targetObj, exists := f.source.GetObject(morph.Target)
-	if !exists {
-		return nil, fmt.Errorf("target object %s not found", morph.Target)
-	}
-
-	// Map objects to packages
-	sourcePkg, err := f.MapObject(sourceObj)
+	// Map source and target objects to packages
+	sourcePkg, targetPkg, err := mapEndpoints(f, morph)
 	if err != nil {
 		return nil, err
 	}
 
-	targetPkg, err := f.MapObject(targetObj)
-	if err != nil {
-		return nil, err
+	// Composites built during law verification are not part of the source
+	// and must not add dependencies to the target
+	if !isStored(f.source, morph) {
+		return endpointMorphism(f.target, morph, sourcePkg, targetPkg), nil
 	}
 
-	// Don't create self-dependencies
+	// Don't create self-dependencies: map to the package's identity
 	if sourcePkg.ID == targetPkg.ID {
-		// Return identity morphism
-		return &category.Morphism{
-			ID:     fmt.Sprintf("id_%s", sourcePkg.ID),
-			Source: sourcePkg.ID,
-			Target: sourcePkg.ID,
-			Type:   "identity",
-		}, nil
+		identity, _ := f.target.Identity(sourcePkg.ID)
+		f.AddMorphismMapping(morph.ID, identity)
+		return identity, nil
 	}
 
 	// Create package dependency morphism
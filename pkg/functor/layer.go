@@ -0,0 +1,120 @@
+package functor
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/manu/catreview/internal/glob"
+	"github.com/manu/catreview/internal/specfile"
+	"github.com/manu/catreview/pkg/category"
+)
+
+// LayerSpec declares the architectural layers of a codebase, from the top
+// layer down, in YAML or JSON:
+//
+//	name: Layers
+//	layers:
+//	  - name: api
+//	    paths: ["cmd/**", "internal/api/**"]
+//	  - name: domain
+//	    paths: ["internal/domain/**"]
+//	  - name: infrastructure
+//	    paths: ["pkg/**", "regex:^internal/(db|cache)/"]
+//
+// Paths are globs ("*" within a path segment, "**" across segments) or, with
+// a "regex:" prefix, regular expressions, matched against the file of each
+// object, or the path of directory objects. The first matching layer wins.
+type LayerSpec struct {
+	Name   string  `yaml:"name" json:"name"`
+	Layers []Layer `yaml:"layers" json:"layers"`
+}
+
+// Layer is a named layer and the paths it contains.
+type Layer struct {
+	Name  string   `yaml:"name" json:"name"`
+	Paths []string `yaml:"paths" json:"paths"`
+}
+
+// UnassignedLayer is the layer of files no layer of a spec contains.
+const UnassignedLayer = "unassigned"
+
+// LoadLayerSpec reads a layer spec from a YAML or JSON file.
+func LoadLayerSpec(path string) (*LayerSpec, error) {
+	var spec LayerSpec
+	if err := specfile.Load(path, "layer", &spec); err != nil {
+		return nil, err
+	}
+	return &spec, nil
+}
+
+// LayerFunctor maps a file-level category to a category of named layers.
+//
+// Layer objects "layer:<name>" record their "rank" (0 for the top layer)
+// and "files". Files outside every layer map to "layer:unassigned", objects
+// without a file, such as imported packages, to the external object.
+type LayerFunctor struct {
+	*groupFunctor
+	spec  *LayerSpec
+	paths [][]*regexp.Regexp
+}
+
+// NewLayerFunctor creates a functor from file-level to layer-level.
+func NewLayerFunctor(spec *LayerSpec, source, target *category.Category) (*LayerFunctor, error) {
+	f := &LayerFunctor{spec: spec}
+	seen := make(map[string]bool)
+	for i, layer := range spec.Layers {
+		if layer.Name == "" {
+			return nil, fmt.Errorf("layer %d: missing name", i+1)
+		}
+		if seen[layer.Name] || layer.Name == UnassignedLayer {
+			return nil, fmt.Errorf("layer %s: duplicate or reserved name", layer.Name)
+		}
+		seen[layer.Name] = true
+
+		var compiled []*regexp.Regexp
+		for _, pattern := range layer.Paths {
+			re, _, err := glob.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("layer %s: %v", layer.Name, err)
+			}
+			if re != nil {
+				compiled = append(compiled, re)
+			}
+		}
+		f.paths = append(f.paths, compiled)
+	}
+
+	name := spec.Name
+	if name == "" {
+		name = "FileToLayer"
+	}
+	f.groupFunctor = &groupFunctor{
+		BaseFunctor: NewBaseFunctor(name, source, target),
+		group:       f.layerOf,
+	}
+	return f, nil
+}
+
+// layerOf returns the layer object obj belongs to.
+func (f *LayerFunctor) layerOf(obj *category.Object) *category.Object {
+	file := obj.ID
+	if obj.Type != "file" {
+		file, _ = obj.Metadata["file"].(string)
+	}
+	if obj.Type == "directory" {
+		file, _ = obj.Metadata["path"].(string)
+	}
+	if file == "" {
+		return category.NewObject(ExternalObjectID, "external", "external", nil)
+	}
+
+	for i, patterns := range f.paths {
+		for _, re := range patterns {
+			if re.MatchString(file) {
+				name := f.spec.Layers[i].Name
+				return category.NewObject("layer:"+name, "layer", name, map[string]interface{}{"rank": i})
+			}
+		}
+	}
+	return category.NewObject("layer:"+UnassignedLayer, "layer", UnassignedLayer, nil)
+}
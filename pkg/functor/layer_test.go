@@ -0,0 +1,105 @@
+package functor
+
+import (
+	"testing"
+
+	"github.com/manu/catreview/internal/modeltest"
+	"github.com/manu/catreview/pkg/category"
+	"github.com/manu/catreview/pkg/extractor"
+)
+
+func TestLayerFunctor(t *testing.T) {
+	source := ruleFixture(t)
+	modeltest.Add(t, source, []*category.Object{
+		category.NewObject("tools/gen.go", "file", "gen.go", nil),
+	}, nil)
+
+	spec := &LayerSpec{Layers: []Layer{
+		{Name: "domain", Paths: []string{"services/**"}},
+		{Name: "infrastructure", Paths: []string{"regex:^lib/"}},
+	}}
+	f, err := NewLayerFunctor(spec, source, category.NewCategory("layers"))
+	if err != nil {
+		t.Fatalf("NewLayerFunctor failed: %v", err)
+	}
+	app, err := Apply(f)
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if app.LawError != nil {
+		t.Errorf("Expected functor laws to hold, got %v", app.LawError)
+	}
+
+	expected := map[string]string{
+		"services/billing/a.go": "layer:domain",
+		"lib/shared/c.go":       "layer:infrastructure",
+		"tools/gen.go":          "layer:unassigned",
+		"import:fmt":            ExternalObjectID,
+	}
+	for id, layer := range expected {
+		obj, _ := source.GetObject(id)
+		mapped, err := f.MapObject(obj)
+		if err != nil || mapped.ID != layer {
+			t.Errorf("Expected %s in %s, got %v (%v)", id, layer, mapped, err)
+		}
+	}
+
+	infra, _ := app.Target.GetObject("layer:infrastructure")
+	if infra.Metadata["rank"] != 1 {
+		t.Errorf("Expected infrastructure rank 1, got %v", infra.Metadata["rank"])
+	}
+	if _, exists := app.Target.GetMorphism("dep:layer:domain->layer:infrastructure"); !exists {
+		t.Error("Expected dependency from domain to infrastructure")
+	}
+}
+
+func TestLayerSpecValidation(t *testing.T) {
+	source := ruleFixture(t)
+	specs := []*LayerSpec{
+		{Layers: []Layer{{Paths: []string{"**"}}}},
+		{Layers: []Layer{{Name: "a"}, {Name: "a"}}},
+		{Layers: []Layer{{Name: "a", Paths: []string{"regex:("}}}},
+	}
+	for i, spec := range specs {
+		if _, err := NewLayerFunctor(spec, source, category.NewCategory("layers")); err == nil {
+			t.Errorf("spec %d: expected validation error", i)
+		}
+	}
+}
+
+func TestLayerFunctorExtracted(t *testing.T) {
+	source, err := extractor.NewGoExtractor().ExtractFromPath(modeltest.Shop(t))
+	if err != nil {
+		t.Fatalf("ExtractFromPath failed: %v", err)
+	}
+	spec := &LayerSpec{Layers: []Layer{
+		{Name: "app", Paths: []string{"cmd/**", "tools/**"}},
+		{Name: "domain", Paths: []string{"internal/domain/**"}},
+		{Name: "infrastructure", Paths: []string{"internal/infra/**", "audit/**"}},
+	}}
+	f, err := NewLayerFunctor(spec, source, category.NewCategory("layers"))
+	if err != nil {
+		t.Fatalf("NewLayerFunctor failed: %v", err)
+	}
+	app, err := Apply(f)
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+
+	// Files and declarations alike fall in the layer of their path below the root
+	for id, layer := range map[string]string{
+		"cmd/shop/main.go": "layer:app",
+		"main.main":        "layer:app",
+		"domain.Place":     "layer:domain",
+		"infra.Store":      "layer:infrastructure",
+		"audit.Record":     "layer:infrastructure",
+	} {
+		obj, _ := source.GetObject(id)
+		if mapped, err := f.MapObject(obj); err != nil || mapped.ID != layer {
+			t.Errorf("Expected %s in %s, got %v (%v)", id, layer, mapped, err)
+		}
+	}
+	if _, exists := app.Target.GetObject("layer:" + UnassignedLayer); exists {
+		t.Error("Expected every file to be assigned a layer")
+	}
+}
@@ -0,0 +1,271 @@
+package functor
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/manu/catreview/pkg/category"
+)
+
+// NaturalTransformation is a natural transformation η: F ⇒ G between
+// functors F, G: C → D.
+//
+// It consists of a component η_A: F(A) → G(A) in D for every object A of C,
+// such that every naturality square commutes: for each f: A → B in C,
+//
+//	G(f) ∘ η_A = η_B ∘ F(f)
+//
+// Squares are checked against D as it stands: F and G must already have
+// been applied, and F(f) and G(f) must be morphisms of D; nothing is added to
+// D to make a square commute. A path of two non-identity morphisms composes
+// to the morphism D holds between its endpoints, if D holds exactly one.
+// With Thin set, D is treated as a preorder, the usual reading of "may
+// depend on" architecture diagrams: parallel paths are always equal, so a
+// square commutes when its components are well-typed. Otherwise both sides
+// must compose to the same morphism of D, or be the same path.
+type NaturalTransformation struct {
+	Name string
+	Thin bool
+
+	f, g       Functor
+	components map[string]*category.Morphism
+}
+
+// NaturalitySquare is the naturality square of a morphism f: A → B. Failing
+// squares record why they do not commute.
+type NaturalitySquare struct {
+	Morphism        string `json:"morphism"` // f
+	Source          string `json:"source"`   // A
+	Target          string `json:"target"`   // B
+	FMorphism       string `json:"f_morphism,omitempty"`
+	GMorphism       string `json:"g_morphism,omitempty"`
+	SourceComponent string `json:"source_component,omitempty"` // η_A
+	TargetComponent string `json:"target_component,omitempty"` // η_B
+	Reason          string `json:"reason,omitempty"`
+}
+
+// NaturalityReport is the result of VerifyNaturality.
+type NaturalityReport struct {
+	Checked  int                 `json:"checked"`
+	Skipped  int                 `json:"skipped"` // Squares of morphisms F or G leave unmapped
+	Failures []*NaturalitySquare `json:"failures"`
+}
+
+// Natural reports whether every checked square commutes.
+func (r *NaturalityReport) Natural() bool {
+	return len(r.Failures) == 0
+}
+
+// NewNaturalTransformation creates a transformation F ⇒ G without
+// components. F and G must have the same source and target categories.
+func NewNaturalTransformation(name string, f, g Functor) (*NaturalTransformation, error) {
+	if f.SourceCategory() != g.SourceCategory() || f.TargetCategory() != g.TargetCategory() {
+		return nil, fmt.Errorf("functors %s and %s are not parallel", f.Name(), g.Name())
+	}
+	return &NaturalTransformation{
+		Name:       name,
+		f:          f,
+		g:          g,
+		components: make(map[string]*category.Morphism),
+	}, nil
+}
+
+// SetComponent sets η_A for the object A with the given ID.
+func (n *NaturalTransformation) SetComponent(objectID string, m *category.Morphism) {
+	n.components[objectID] = m
+}
+
+// Component returns η_A. Objects without an explicit component whose images
+// under F and G coincide default to the identity of D.
+func (n *NaturalTransformation) Component(obj *category.Object) (*category.Morphism, error) {
+	if m, ok := n.components[obj.ID]; ok {
+		return m, nil
+	}
+	fA, err := n.f.MapObject(obj)
+	if err != nil {
+		return nil, err
+	}
+	gA, err := n.g.MapObject(obj)
+	if err != nil {
+		return nil, err
+	}
+	if fA.ID == gA.ID {
+		if identity, ok := n.f.TargetCategory().Identity(fA.ID); ok {
+			return identity, nil
+		}
+	}
+	return nil, fmt.Errorf("no component for %s: %s → %s", obj.ID, fA.ID, gA.ID)
+}
+
+// SetComponentsFromMap sets components from a mapping of F-images to
+// G-images in D, such as a declared "context → layer" table. For each
+// object A with F(A) mapped to X, η_A is the identity of X when X is F(A),
+// the morphism D holds from F(A) to X if it holds exactly one, and otherwise
+// a declared component "eta:F(A)->X" of type "component". Declared
+// components belong to the transformation and are not added to D.
+// Components whose X is not G(A) are reported as failing squares by
+// VerifyNaturality.
+func (n *NaturalTransformation) SetComponentsFromMap(mapping map[string]string) error {
+	target := n.f.TargetCategory()
+	for _, obj := range n.f.SourceCategory().Objects() {
+		fA, err := n.f.MapObject(obj)
+		if err != nil {
+			continue
+		}
+		x, ok := mapping[fA.ID]
+		if !ok {
+			continue
+		}
+		if _, ok := target.GetObject(x); !ok {
+			return fmt.Errorf("component target %s for %s is not an object of %s", x, fA.ID, target.Name)
+		}
+		if x == fA.ID {
+			identity, _ := target.Identity(x)
+			n.SetComponent(obj.ID, identity)
+			continue
+		}
+
+		component := homMorphism(target, fA.ID, x)
+		if component == nil {
+			component = category.NewMorphism(fmt.Sprintf("eta:%s->%s", fA.ID, x), fA.ID, x, "component", nil)
+		}
+		n.SetComponent(obj.ID, component)
+	}
+	return nil
+}
+
+// VerifyNaturality checks the naturality square of every non-identity
+// morphism of C. F and G must have been applied to C first, e.g. with Apply.
+func (n *NaturalTransformation) VerifyNaturality() *NaturalityReport {
+	report := &NaturalityReport{Failures: []*NaturalitySquare{}}
+	source := n.f.SourceCategory()
+
+	for _, m := range source.Morphisms() {
+		if m.Type == "identity" {
+			continue
+		}
+		square := &NaturalitySquare{Morphism: m.ID, Source: m.Source, Target: m.Target}
+
+		fm, errF := n.f.MapMorphism(m)
+		gm, errG := n.g.MapMorphism(m)
+		if errors.Is(errF, ErrUnmapped) || errors.Is(errG, ErrUnmapped) {
+			report.Skipped++
+			continue
+		}
+		report.Checked++
+
+		if reason := n.checkSquare(square, m, fm, errF, gm, errG); reason != "" {
+			square.Reason = reason
+			report.Failures = append(report.Failures, square)
+		}
+	}
+
+	return report
+}
+
+// checkSquare fills in the morphisms of a square and returns why it fails
+// to commute, or "" if it commutes.
+func (n *NaturalTransformation) checkSquare(square *NaturalitySquare, m, fm *category.Morphism, errF error, gm *category.Morphism, errG error) string {
+	if errF != nil {
+		return fmt.Sprintf("F(%s) undefined: %v", m.ID, errF)
+	}
+	if errG != nil {
+		return fmt.Sprintf("G(%s) undefined: %v", m.ID, errG)
+	}
+	square.FMorphism, square.GMorphism = fm.ID, gm.ID
+	d := n.f.TargetCategory()
+	if !inCategory(d, fm) {
+		return fmt.Sprintf("F(%s) = %s is not a morphism of %s", m.ID, fm.ID, d.Name)
+	}
+	if !inCategory(d, gm) {
+		return fmt.Sprintf("G(%s) = %s is not a morphism of %s", m.ID, gm.ID, d.Name)
+	}
+
+	source, _ := n.f.SourceCategory().GetObject(m.Source)
+	target, _ := n.f.SourceCategory().GetObject(m.Target)
+	etaA, reason := n.checkComponent(source)
+	if reason != "" {
+		return reason
+	}
+	etaB, reason := n.checkComponent(target)
+	if reason != "" {
+		return reason
+	}
+	square.SourceComponent, square.TargetComponent = etaA.ID, etaB.ID
+
+	if n.Thin {
+		return ""
+	}
+
+	// G(f) ∘ η_A and η_B ∘ F(f), composed in D where D holds the composite
+	left := composite(d, etaA, gm)
+	right := composite(d, fm, etaB)
+	if strings.Join(left, "\x00") != strings.Join(right, "\x00") {
+		return fmt.Sprintf("G(f)∘η_A = [%s] differs from η_B∘F(f) = [%s]",
+			strings.Join(left, ", "), strings.Join(right, ", "))
+	}
+	return ""
+}
+
+// checkComponent returns η_A after checking it runs from F(A) to G(A).
+func (n *NaturalTransformation) checkComponent(obj *category.Object) (*category.Morphism, string) {
+	eta, err := n.Component(obj)
+	if err != nil {
+		return nil, err.Error()
+	}
+	fA, errF := n.f.MapObject(obj)
+	gA, errG := n.g.MapObject(obj)
+	if errF != nil || errG != nil {
+		return nil, fmt.Sprintf("object %s is not mapped by both functors", obj.ID)
+	}
+	if eta.Source != fA.ID || eta.Target != gA.ID {
+		return nil, fmt.Sprintf("component η_%s: %s → %s is not F(%s) → G(%s) = %s → %s",
+			obj.ID, eta.Source, eta.Target, obj.ID, obj.ID, fA.ID, gA.ID)
+	}
+	return eta, ""
+}
+
+// composite returns the IDs of the non-identity morphisms of a path, given
+// in order of application, or the ID of the morphism of c the path composes
+// to: the one c holds between the path's endpoints, if it holds exactly one.
+func composite(c *category.Category, path ...*category.Morphism) []string {
+	var ids []string
+	for _, m := range path {
+		if m.Type != "identity" {
+			ids = append(ids, m.ID)
+		}
+	}
+	if len(ids) > 1 {
+		if m := homMorphism(c, path[0].Source, path[len(path)-1].Target); m != nil {
+			return []string{m.ID}
+		}
+	}
+	return ids
+}
+
+// homMorphism returns the morphism c holds from source to target, or nil
+// if it holds none or several.
+func homMorphism(c *category.Category, source, target string) *category.Morphism {
+	var found *category.Morphism
+	for _, m := range c.Morphisms() {
+		if m.Source != source || m.Target != target || m.Type == "identity" {
+			continue
+		}
+		if found != nil {
+			return nil
+		}
+		found = m
+	}
+	return found
+}
+
+// inCategory reports whether m is a morphism of c: stored in c, or one of
+// its identities.
+func inCategory(c *category.Category, m *category.Morphism) bool {
+	if m.Type == "identity" {
+		identity, exists := c.Identity(m.Source)
+		return exists && identity == m
+	}
+	return isStored(c, m)
+}
@@ -0,0 +1,172 @@
+package functor
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/manu/catreview/internal/modeltest"
+	"github.com/manu/catreview/pkg/category"
+)
+
+// naturalFixture builds F (files → contexts) and G (files → layers) into one
+// shared target over ruleFixture, and applies both.
+func naturalFixture(t *testing.T) (Functor, Functor) {
+	t.Helper()
+	return naturalFunctors(t, ruleFixture(t), nil)
+}
+
+// naturalFunctors builds F and G over source, trying the given layer rules
+// before the default ones.
+func naturalFunctors(t *testing.T, source *category.Category, layerRules []ObjectRule) (Functor, Functor) {
+	t.Helper()
+	target := category.NewCategory("D")
+
+	contexts := &RuleSpec{
+		Objects: []ObjectRule{
+			{Match: ObjectMatch{ID: "services/billing/**"}, Target: ObjectTemplate{ID: "context:billing"}},
+			{Match: ObjectMatch{ID: "lib/**"}, Target: ObjectTemplate{ID: "context:shared"}},
+		},
+		Unmatched: "drop",
+		Morphisms: map[string]string{"co_changes_with": "drop", "*": "dependency"},
+	}
+	layers := &RuleSpec{
+		Objects: append(layerRules,
+			ObjectRule{Match: ObjectMatch{ID: "services/**"}, Target: ObjectTemplate{ID: "layer:domain"}},
+			ObjectRule{Match: ObjectMatch{ID: "lib/**"}, Target: ObjectTemplate{ID: "layer:infra"}},
+		),
+		Unmatched: "drop",
+		Morphisms: map[string]string{"co_changes_with": "drop", "*": "dependency"},
+	}
+
+	f, err := NewRuleFunctor(contexts, source, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := NewRuleFunctor(layers, source, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fn := range []Functor{f, g} {
+		for _, obj := range source.Objects() {
+			if _, err := fn.MapObject(obj); err != nil && !errors.Is(err, ErrUnmapped) {
+				t.Fatal(err)
+			}
+		}
+		for _, m := range source.Morphisms() {
+			if _, err := fn.MapMorphism(m); err != nil && !errors.Is(err, ErrUnmapped) {
+				t.Fatal(err)
+			}
+		}
+	}
+	return f, g
+}
+
+func TestNaturalTransformation(t *testing.T) {
+	f, g := naturalFixture(t)
+
+	eta, err := NewNaturalTransformation("eta", f, g)
+	if err != nil {
+		t.Fatalf("NewNaturalTransformation failed: %v", err)
+	}
+	eta.Thin = true
+	before, _ := category.CanonicalJSON(f.TargetCategory())
+	err = eta.SetComponentsFromMap(map[string]string{
+		"context:billing": "layer:domain",
+		"context:shared":  "layer:infra",
+	})
+	if err != nil {
+		t.Fatalf("SetComponentsFromMap failed: %v", err)
+	}
+
+	report := eta.VerifyNaturality()
+	if after, _ := category.CanonicalJSON(f.TargetCategory()); !bytes.Equal(before, after) {
+		t.Error("Expected components and verification to leave D unchanged")
+	}
+	if !report.Natural() {
+		t.Errorf("Expected natural transformation, got failures: %+v", report.Failures[0])
+	}
+	// m1, m2 checked; co-change m3 and m4 into the dropped import skipped
+	if report.Checked != 2 || report.Skipped != 2 {
+		t.Errorf("Expected 2 checked and 2 skipped squares, got %d and %d", report.Checked, report.Skipped)
+	}
+
+	// Strict reading: D holds no composite context:billing → layer:infra,
+	// so the two paths of the m1 and m2 squares differ
+	eta.Thin = false
+	if report := eta.VerifyNaturality(); len(report.Failures) != 2 {
+		t.Errorf("Expected both squares to fail without composites in D, got %d failures", len(report.Failures))
+	}
+
+	// Once D holds the composite, both paths compose to it
+	modeltest.Add(t, f.TargetCategory(), nil, []*category.Morphism{
+		category.NewMorphism("uses:context:billing->layer:infra", "context:billing", "layer:infra", "uses", nil),
+	})
+	if report := eta.VerifyNaturality(); !report.Natural() {
+		t.Errorf("Expected squares to commute through D's composite, got failures: %+v", report.Failures[0])
+	}
+}
+
+func TestNaturalTransformationNotNatural(t *testing.T) {
+	// A shared handler that runtime layering puts in the domain layer, while
+	// the shared context is declared infrastructure
+	source := ruleFixture(t)
+	modeltest.Add(t, source, []*category.Object{
+		category.NewObject("lib/shared/handler.go", "file", "handler.go", map[string]interface{}{"package": "sharedutil"}),
+	}, []*category.Morphism{
+		category.NewMorphism("m5", "services/billing/a.go", "lib/shared/handler.go", "function_call", nil),
+	})
+	f, g := naturalFunctors(t, source, []ObjectRule{
+		{Match: ObjectMatch{ID: "lib/shared/handler.go"}, Target: ObjectTemplate{ID: "layer:domain"}},
+	})
+
+	eta, _ := NewNaturalTransformation("eta", f, g)
+	eta.Thin = true
+	err := eta.SetComponentsFromMap(map[string]string{
+		"context:billing": "layer:domain",
+		"context:shared":  "layer:infra",
+	})
+	if err != nil {
+		t.Fatalf("SetComponentsFromMap failed: %v", err)
+	}
+
+	// m1 and m2 into c.go commute; m5 into the handler does not
+	report := eta.VerifyNaturality()
+	if report.Checked != 3 || len(report.Failures) != 1 {
+		t.Fatalf("Expected 1 of 3 squares to fail, got %d of %d: %+v", len(report.Failures), report.Checked, report.Failures)
+	}
+	if sq := report.Failures[0]; sq.Morphism != "m5" || sq.GMorphism != "id_layer:domain" {
+		t.Errorf("Expected the m5 square to fail within the domain layer, got %+v", sq)
+	}
+}
+
+func TestNaturalTransformationWrongComponent(t *testing.T) {
+	f, g := naturalFixture(t)
+
+	eta, _ := NewNaturalTransformation("eta", f, g)
+	eta.Thin = true
+	// Declares shared code as domain, but it lives in infra
+	eta.SetComponentsFromMap(map[string]string{
+		"context:billing": "layer:domain",
+		"context:shared":  "layer:domain",
+	})
+
+	report := eta.VerifyNaturality()
+	if report.Natural() {
+		t.Fatal("Expected failing squares for a wrong component")
+	}
+	sq := report.Failures[0]
+	if sq.FMorphism == "" || sq.GMorphism == "" || sq.Reason == "" {
+		t.Errorf("Expected failing square to name its morphisms and reason, got %+v", sq)
+	}
+}
+
+func TestNaturalTransformationRequiresParallelFunctors(t *testing.T) {
+	source := ruleFixture(t)
+	f, _ := NewRuleFunctor(&RuleSpec{}, source, category.NewCategory("D1"))
+	g, _ := NewRuleFunctor(&RuleSpec{}, source, category.NewCategory("D2"))
+
+	if _, err := NewNaturalTransformation("eta", f, g); err == nil {
+		t.Error("Expected error for functors with different targets")
+	}
+}
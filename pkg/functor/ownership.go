@@ -0,0 +1,176 @@
+package functor
+
+import (
+	"fmt"
+
+	"github.com/manu/catreview/pkg/analysis"
+	"github.com/manu/catreview/pkg/category"
+)
+
+// OwnershipFunctor maps a file-level category to a team-level category.
+//
+// This functor lifts objects to the team named by their "owner" metadata:
+// - Owned objects → Teams (objects without an owner → "team:unowned")
+// - Dependencies between teams → Team dependencies counting the underlying morphisms
+// - Dependencies within a team → The team's identity
+//
+// Only the morphisms Edges allows are dependencies, as for the team coupling
+// matrix (analysis.ComputeTeamCoupling); others, such as co-changes, are
+// reported with ErrUnmapped.
+type OwnershipFunctor struct {
+	*BaseFunctor
+
+	// Edges selects the morphisms mapped to team dependencies (nil: all
+	// structural morphisms)
+	Edges *analysis.EdgeFilter
+}
+
+// NewOwnershipFunctor creates a functor from file-level to team-level.
+func NewOwnershipFunctor(source, target *category.Category) *OwnershipFunctor {
+	return &OwnershipFunctor{
+		BaseFunctor: NewBaseFunctor("FileToTeam", source, target),
+	}
+}
+
+// MapObject maps an object to its owning team.
+func (f *OwnershipFunctor) MapObject(obj *category.Object) (*category.Object, error) {
+	if cached, exists := f.GetObjectMapping(obj.ID); exists {
+		return cached, nil
+	}
+
+	team, _ := obj.Metadata["owner"].(string)
+	if team == "" {
+		team = "unowned"
+	}
+
+	teamID := fmt.Sprintf("team:%s", team)
+	teamObj, exists := f.target.GetObject(teamID)
+	if !exists {
+		teamObj = category.NewObject(teamID, "team", team, map[string]interface{}{
+			"files": []string{obj.ID},
+		})
+		if err := f.target.AddObject(teamObj); err != nil {
+			return nil, err
+		}
+	} else if files, ok := teamObj.Metadata["files"].([]string); ok {
+		teamObj.Metadata["files"] = append(files, obj.ID)
+	}
+
+	f.AddObjectMapping(obj.ID, teamObj)
+	return teamObj, nil
+}
+
+// MapMorphism maps a dependency to a team dependency.
+// Dependencies within a team map to the team's identity morphism.
+func (f *OwnershipFunctor) MapMorphism(morph *category.Morphism) (*category.Morphism, error) {
+	if cached, exists := f.GetMorphismMapping(morph.ID); exists {
+		return cached, nil
+	}
+
+	stored := isStored(f.source, morph)
+	if stored && morph.Type != "identity" && !f.Edges.Allows(morph) {
+		return nil, fmt.Errorf("morphism %s of type %s: %w", morph.ID, morph.Type, ErrUnmapped)
+	}
+
+	sourceTeam, targetTeam, err := mapEndpoints(f, morph)
+	if err != nil {
+		return nil, err
+	}
+
+	// Composites built during law verification must not add dependencies
+	if !stored {
+		return endpointMorphism(f.target, morph, sourceTeam, targetTeam), nil
+	}
+
+	if sourceTeam.ID == targetTeam.ID {
+		identity, _ := f.target.Identity(sourceTeam.ID)
+		f.AddMorphismMapping(morph.ID, identity)
+		return identity, nil
+	}
+
+	teamMorphID := fmt.Sprintf("dep:%s->%s", sourceTeam.ID, targetTeam.ID)
+	teamMorph, exists := f.target.GetMorphism(teamMorphID)
+	if !exists {
+		teamMorph = category.NewMorphism(
+			teamMorphID,
+			sourceTeam.ID,
+			targetTeam.ID,
+			"team_dependency",
+			map[string]interface{}{
+				"count":        1,
+				"source_files": []string{morph.Source},
+				"target_files": []string{morph.Target},
+			},
+		)
+		if err := f.target.AddMorphism(teamMorph); err != nil {
+			return nil, err
+		}
+	} else {
+		if count, ok := teamMorph.Metadata["count"].(int); ok {
+			teamMorph.Metadata["count"] = count + 1
+		}
+		if sources, ok := teamMorph.Metadata["source_files"].([]string); ok {
+			teamMorph.Metadata["source_files"] = append(sources, morph.Source)
+		}
+		if targets, ok := teamMorph.Metadata["target_files"].([]string); ok {
+			teamMorph.Metadata["target_files"] = append(targets, morph.Target)
+		}
+	}
+
+	f.AddMorphismMapping(morph.ID, teamMorph)
+	return teamMorph, nil
+}
+
+// PropagateOwners sets the "owner" metadata of every object of f's target
+// that has none to the owner shared by most of the objects f maps onto it,
+// the first in order on a tie. Owners thus survive abstraction: after
+// file -> package, the ownership functor maps each package to the team
+// owning most of its files rather than to "team:unowned".
+func PropagateOwners(f Functor) {
+	counts := make(map[string]map[string]int) // Target object -> owner -> source objects
+	for _, obj := range f.SourceCategory().Objects() {
+		owner, _ := obj.Metadata["owner"].(string)
+		if owner == "" {
+			continue
+		}
+		mapped, err := f.MapObject(obj)
+		if err != nil {
+			continue
+		}
+		if counts[mapped.ID] == nil {
+			counts[mapped.ID] = make(map[string]int)
+		}
+		counts[mapped.ID][owner]++
+	}
+
+	for id, owners := range counts {
+		obj, exists := f.TargetCategory().GetObject(id)
+		if !exists {
+			continue
+		}
+		if owner, _ := obj.Metadata["owner"].(string); owner != "" {
+			continue
+		}
+		majority := ""
+		for owner, n := range owners {
+			if majority == "" || n > owners[majority] || n == owners[majority] && owner < majority {
+				majority = owner
+			}
+		}
+		if obj.Metadata == nil {
+			obj.Metadata = make(map[string]interface{})
+		}
+		obj.Metadata["owner"] = majority
+	}
+}
+
+// VerifyLaws verifies functor laws for this specific functor.
+func (f *OwnershipFunctor) VerifyLaws() error {
+	if err := f.VerifyIdentityLaw(f); err != nil {
+		return err
+	}
+	if err := f.VerifyCompositionLaw(f); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package functor
+
+import (
+	"testing"
+
+	"github.com/manu/catreview/internal/modeltest"
+	"github.com/manu/catreview/pkg/category"
+)
+
+func TestOwnershipFunctorAfterPackages(t *testing.T) {
+	source := ruleFixture(t)
+	modeltest.Add(t, source, []*category.Object{
+		category.NewObject("services/billing/d.go", "file", "d.go", map[string]interface{}{"package": "billing"}),
+	}, nil)
+	owners := map[string]string{
+		"services/billing/a.go": "payments",
+		"services/billing/b.go": "payments",
+		"services/billing/d.go": "bob",
+		"lib/shared/c.go":       "search",
+	}
+	for id, owner := range owners {
+		obj, _ := source.GetObject(id)
+		obj.Metadata["owner"] = owner
+	}
+
+	// file -> package
+	packages := NewPackageAbstractionFunctor(source, category.NewCategory("package_level"))
+	if _, err := Apply(packages); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	PropagateOwners(packages)
+	for id, want := range map[string]interface{}{"pkg:billing": "payments", "pkg:sharedutil": "search", ExternalObjectID: nil} {
+		obj, _ := packages.TargetCategory().GetObject(id)
+		if obj == nil || obj.Metadata["owner"] != want {
+			t.Errorf("Expected %s owned by %v, got %v", id, want, obj)
+		}
+	}
+
+	// package -> team
+	teams := NewOwnershipFunctor(packages.TargetCategory(), category.NewCategory("team_level"))
+	app, err := Apply(teams)
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if app.LawError != nil || len(app.Failures) != 0 {
+		t.Fatalf("Expected a lawful functor, got %v %v", app.LawError, app.Failures)
+	}
+	if _, ok := app.Target.GetMorphism("dep:team:payments->team:search"); !ok {
+		t.Errorf("Expected payments to depend on search, got %v", app.Target.Morphisms())
+	}
+	if _, ok := app.Target.GetMorphism("dep:team:search->team:unowned"); !ok {
+		t.Errorf("Expected search to depend on the unowned external object, got %v", app.Target.Morphisms())
+	}
+
+	// The composite maps every file to the team owning most of its package
+	composite, err := Compose(packages, teams)
+	if err != nil {
+		t.Fatalf("Compose failed: %v", err)
+	}
+	d, _ := source.GetObject("services/billing/d.go")
+	if team, err := composite.MapObject(d); err != nil || team.ID != "team:payments" {
+		t.Errorf("Expected d.go to map to team:payments, got %v (%v)", team, err)
+	}
+}
@@ -0,0 +1,170 @@
+package functor
+
+import (
+	"sort"
+)
+
+// Collapse is a target morphism onto which several distinct source
+// morphisms are mapped.
+type Collapse struct {
+	Target  string   `json:"target"`
+	Sources []string `json:"sources"`
+}
+
+// Properties describes how much structure a functor preserves.
+//
+// A functor F: C → D is
+// - injective on objects when no two objects share an image,
+// - faithful when it is injective on every hom-set Hom(A, B),
+// - full when it is surjective onto every Hom(F(A), F(B)).
+//
+// Abstraction functors are typically neither: the fibre sizes and collapses
+// say how much detail an abstraction hides.
+type Properties struct {
+	SourceObjects   int `json:"source_objects"`
+	SourceMorphisms int `json:"source_morphisms"` // Non-identity morphisms
+	TargetObjects   int `json:"target_objects"`
+	TargetMorphisms int `json:"target_morphisms"` // Non-identity morphisms hit by F
+	Unmapped        int `json:"unmapped"`         // Objects and morphisms F does not map
+
+	InjectiveOnObjects bool `json:"injective_on_objects"`
+	Faithful           bool `json:"faithful"`
+	Full               bool `json:"full"`
+
+	// FibreSizes counts the source objects mapped to each target object.
+	FibreSizes map[string]int `json:"fibre_sizes"`
+	// Collapses lists target morphisms hit by more than one source morphism,
+	// including identities absorbing non-identity morphisms, largest first.
+	Collapses []*Collapse `json:"collapses"`
+	// ToIdentity counts non-identity morphisms mapped to identities.
+	ToIdentity int `json:"to_identity"`
+}
+
+// LargestFibre returns the target object with the most preimages and its size.
+func (p *Properties) LargestFibre() (string, int) {
+	id, size := "", 0
+	for target, n := range p.FibreSizes {
+		if n > size || (n == size && target < id) {
+			id, size = target, n
+		}
+	}
+	return id, size
+}
+
+// CollapsedMorphisms returns the number of source morphisms involved in collapses.
+func (p *Properties) CollapsedMorphisms() int {
+	n := 0
+	for _, c := range p.Collapses {
+		n += len(c.Sources)
+	}
+	return n
+}
+
+// AnalyzeProperties maps every object and morphism of f's source category
+// and determines which structure f preserves. Functors cache their
+// mappings, so calling it after the functor has been applied is cheap.
+func AnalyzeProperties(f Functor) *Properties {
+	source := f.SourceCategory()
+	props := &Properties{
+		FibreSizes: make(map[string]int),
+		Collapses:  []*Collapse{},
+	}
+
+	// Objects and fibres
+	fibres := make(map[string][]string)
+	for _, obj := range source.Objects() {
+		props.SourceObjects++
+		mapped, err := f.MapObject(obj)
+		if err != nil {
+			props.Unmapped++
+			continue
+		}
+		fibres[mapped.ID] = append(fibres[mapped.ID], obj.ID)
+	}
+	props.TargetObjects = len(fibres)
+	props.InjectiveOnObjects = true
+	for target, objects := range fibres {
+		props.FibreSizes[target] = len(objects)
+		if len(objects) > 1 {
+			props.InjectiveOnObjects = false
+		}
+	}
+
+	// Morphisms, grouped by target morphism and by hom-set
+	type homKey struct{ source, target, image string }
+	preimages := make(map[string][]string)         // Target morphism ID → non-identity source morphisms
+	covered := make(map[string]map[[2]string]bool) // Target morphism ID → hom-sets (A, B) hitting it
+	homImages := make(map[homKey]bool)
+	targetEnds := make(map[string][2]string)
+	identities := make(map[string]bool) // Target identities absorbing non-identity morphisms
+	props.Faithful = true
+
+	for _, m := range source.Morphisms() {
+		isIdentity := m.Type == "identity"
+		if !isIdentity {
+			props.SourceMorphisms++
+		}
+		mapped, err := f.MapMorphism(m)
+		if err != nil {
+			props.Unmapped++
+			continue
+		}
+
+		// Faithfulness: distinct morphisms of one hom-set need distinct images
+		key := homKey{m.Source, m.Target, mapped.ID}
+		if homImages[key] {
+			props.Faithful = false
+		}
+		homImages[key] = true
+
+		if covered[mapped.ID] == nil {
+			covered[mapped.ID] = make(map[[2]string]bool)
+		}
+		covered[mapped.ID][[2]string{m.Source, m.Target}] = true
+		targetEnds[mapped.ID] = [2]string{mapped.Source, mapped.Target}
+
+		if isIdentity {
+			continue
+		}
+		if mapped.Type == "identity" {
+			props.ToIdentity++
+			identities[mapped.ID] = true
+		}
+		preimages[mapped.ID] = append(preimages[mapped.ID], m.ID)
+	}
+
+	// Fullness: every target morphism X → Y must be hit from every pair of
+	// objects in the fibres of X and Y
+	props.Full = true
+	for id, ends := range targetEnds {
+		if len(covered[id]) < len(fibres[ends[0]])*len(fibres[ends[1]]) {
+			props.Full = false
+		}
+	}
+	for _, m := range f.TargetCategory().Morphisms() {
+		if m.Type == "identity" {
+			continue
+		}
+		if _, hit := targetEnds[m.ID]; !hit {
+			props.Full = false
+		}
+	}
+
+	for target, sources := range preimages {
+		if !identities[target] {
+			props.TargetMorphisms++
+		}
+		if len(sources) > 1 || identities[target] {
+			sort.Strings(sources)
+			props.Collapses = append(props.Collapses, &Collapse{Target: target, Sources: sources})
+		}
+	}
+	sort.Slice(props.Collapses, func(i, j int) bool {
+		if len(props.Collapses[i].Sources) != len(props.Collapses[j].Sources) {
+			return len(props.Collapses[i].Sources) > len(props.Collapses[j].Sources)
+		}
+		return props.Collapses[i].Target < props.Collapses[j].Target
+	})
+
+	return props
+}
@@ -0,0 +1,79 @@
+package functor
+
+import (
+	"testing"
+
+	"github.com/manu/catreview/internal/modeltest"
+	"github.com/manu/catreview/pkg/category"
+)
+
+func TestAnalyzePropertiesQuotient(t *testing.T) {
+	source := ruleFixture(t)
+	f := NewQuotient(source, category.ByDirectory(0))
+
+	props := AnalyzeProperties(f)
+	if props.InjectiveOnObjects {
+		t.Error("Expected directory quotient not to be injective on objects")
+	}
+	if id, size := props.LargestFibre(); id != "dir:services/billing" || size != 2 {
+		t.Errorf("Expected largest fibre dir:services/billing of size 2, got %s of size %d", id, size)
+	}
+	// m1 and m3 are parallel and share their image
+	if props.Faithful {
+		t.Error("Expected quotient not to be faithful")
+	}
+	// a.go and b.go have no morphism between them mapping to the identity
+	if props.Full {
+		t.Error("Expected quotient not to be full")
+	}
+
+	if len(props.Collapses) == 0 {
+		t.Fatal("Expected collapsed morphisms")
+	}
+	top := props.Collapses[0]
+	if len(top.Sources) != 3 || top.Sources[0] != "m1" || top.Sources[2] != "m3" {
+		t.Errorf("Expected m1, m2 and m3 collapsed first, got %v onto %s", top.Sources, top.Target)
+	}
+	if props.SourceMorphisms != 4 || props.Unmapped != 0 {
+		t.Errorf("Expected 4 source morphisms and none unmapped, got %d and %d", props.SourceMorphisms, props.Unmapped)
+	}
+}
+
+func TestAnalyzePropertiesEmbedding(t *testing.T) {
+	source := modeltest.Build(t, "files", []*category.Object{
+		category.NewObject("a.go", "file", "a.go", nil),
+		category.NewObject("b.go", "file", "b.go", nil),
+	}, []*category.Morphism{
+		category.NewMorphism("m1", "a.go", "b.go", "import", nil),
+	})
+
+	// Every file is its own class: an isomorphic copy
+	f := NewQuotient(source, func(obj *category.Object) string { return obj.ID })
+
+	props := AnalyzeProperties(f)
+	if !props.InjectiveOnObjects || !props.Faithful || !props.Full {
+		t.Errorf("Expected injective, faithful and full functor, got %+v", props)
+	}
+	if len(props.Collapses) != 0 || props.ToIdentity != 0 {
+		t.Errorf("Expected no collapses, got %v", props.Collapses)
+	}
+}
+
+func TestAnalyzePropertiesToIdentity(t *testing.T) {
+	source := ruleFixture(t)
+	f := NewQuotient(source, category.ByMetadata("package"))
+
+	props := AnalyzeProperties(f)
+	if props.ToIdentity != 0 {
+		t.Errorf("Expected no morphism within a package, got %d", props.ToIdentity)
+	}
+
+	byTop := NewQuotient(source, func(obj *category.Object) string { return "all" })
+	props = AnalyzeProperties(byTop)
+	if props.ToIdentity != 4 || props.TargetMorphisms != 0 {
+		t.Errorf("Expected all 4 morphisms absorbed into the identity, got %d (%d target morphisms)", props.ToIdentity, props.TargetMorphisms)
+	}
+	if len(props.Collapses) != 1 || props.Collapses[0].Target != "id_all" {
+		t.Errorf("Expected one collapse onto id_all, got %v", props.Collapses)
+	}
+}
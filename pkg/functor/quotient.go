@@ -0,0 +1,72 @@
+package functor
+
+import (
+	"fmt"
+
+	"github.com/manu/catreview/pkg/category"
+)
+
+// QuotientFunctor is the canonical projection π: C → C/~ onto a quotient
+// category built with category.Quotient.
+//
+// It maps every object to its equivalence class, every morphism between
+// classes to their aggregated morphism, and every morphism within a class to
+// the class's identity.
+type QuotientFunctor struct {
+	*BaseFunctor
+	key category.KeyFunc
+}
+
+// NewQuotientFunctor creates the projection from source onto
+// quotient = category.Quotient(source, key).
+func NewQuotientFunctor(source, quotient *category.Category, key category.KeyFunc) *QuotientFunctor {
+	return &QuotientFunctor{
+		BaseFunctor: NewBaseFunctor("Quotient", source, quotient),
+		key:         key,
+	}
+}
+
+// NewQuotient builds the quotient of source by key together with its
+// projection functor.
+func NewQuotient(source *category.Category, key category.KeyFunc) *QuotientFunctor {
+	return NewQuotientFunctor(source, category.Quotient(source, key), key)
+}
+
+// MapObject maps an object to its equivalence class.
+func (f *QuotientFunctor) MapObject(obj *category.Object) (*category.Object, error) {
+	class := f.key.Class(obj)
+	classObj, exists := f.target.GetObject(class)
+	if !exists {
+		return nil, fmt.Errorf("class %s of %s not found in %s", class, obj.ID, f.target.Name)
+	}
+	return classObj, nil
+}
+
+// MapMorphism maps a morphism to the aggregated morphism between the classes
+// of its endpoints, or to the class identity when both lie in one class.
+func (f *QuotientFunctor) MapMorphism(morph *category.Morphism) (*category.Morphism, error) {
+	if cached, exists := f.GetMorphismMapping(morph.ID); exists {
+		return cached, nil
+	}
+
+	source, target, err := mapEndpoints(f, morph)
+	if err != nil {
+		return nil, err
+	}
+
+	mapped, exists := f.target.GetMorphism(category.QuotientMorphismID(source.ID, target.ID))
+	if !exists || source.ID == target.ID {
+		mapped = endpointMorphism(f.target, morph, source, target)
+	}
+
+	f.AddMorphismMapping(morph.ID, mapped)
+	return mapped, nil
+}
+
+// VerifyLaws verifies functor laws for this specific functor.
+func (f *QuotientFunctor) VerifyLaws() error {
+	if err := f.VerifyIdentityLaw(f); err != nil {
+		return err
+	}
+	return f.VerifyCompositionLaw(f)
+}
@@ -0,0 +1,334 @@
+package functor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/manu/catreview/internal/glob"
+	"github.com/manu/catreview/internal/specfile"
+	"github.com/manu/catreview/pkg/category"
+	"gopkg.in/yaml.v3"
+)
+
+// RuleSpec declares a functor as data, so architectural mappings such as
+// "file → bounded context" need no Go code. Specs are written in YAML or
+// JSON:
+//
+//	name: FileToContext
+//	objects:
+//	  - match:
+//	      id: "regex:^services/([^/]+)/"
+//	    target:
+//	      id: "context:$1"
+//	      type: bounded_context
+//	  - match:
+//	      type: file
+//	      metadata:
+//	        package: "billing*"
+//	    target:
+//	      id: "context:billing"
+//	unmatched: drop
+//	morphisms:
+//	  co_changes_with: drop
+//	  "*": dependency
+//
+// Object rules are tried in order and the first match wins. Match fields are
+// globs ("*" within a path segment, "**" across segments) unless prefixed
+// with "regex:". Target fields are templates: "$1" and "${name}" expand to
+// the capture groups of a regex ID match, and "{id}", "{name}", "{type}" and
+// "{metadata.<key>}" to properties of the source object.
+type RuleSpec struct {
+	Name      string            `yaml:"name" json:"name"`
+	Objects   []ObjectRule      `yaml:"objects" json:"objects"`
+	Unmatched string            `yaml:"unmatched,omitempty" json:"unmatched,omitempty"` // "keep" (default) or "drop"
+	Morphisms map[string]string `yaml:"morphisms,omitempty" json:"morphisms,omitempty"` // Source type ("*" = any) → target type or "drop"
+}
+
+// ObjectRule maps the objects it matches to a target object.
+type ObjectRule struct {
+	Match  ObjectMatch    `yaml:"match" json:"match"`
+	Target ObjectTemplate `yaml:"target" json:"target"`
+}
+
+// ObjectMatch selects objects. All given fields must match.
+type ObjectMatch struct {
+	ID       string            `yaml:"id,omitempty" json:"id,omitempty"`
+	Type     string            `yaml:"type,omitempty" json:"type,omitempty"`
+	Metadata map[string]string `yaml:"metadata,omitempty" json:"metadata,omitempty"`
+}
+
+// ObjectTemplate describes the target object of a rule.
+type ObjectTemplate struct {
+	ID       string                 `yaml:"id" json:"id"`
+	Type     string                 `yaml:"type,omitempty" json:"type,omitempty"` // Default "group"
+	Name     string                 `yaml:"name,omitempty" json:"name,omitempty"` // Default: the expanded ID
+	Metadata map[string]interface{} `yaml:"metadata,omitempty" json:"metadata,omitempty"`
+}
+
+// dropValue drops unmatched objects or morphisms of a type.
+const dropValue = "drop"
+
+// LoadRuleSpec reads a functor spec from a YAML or JSON file.
+func LoadRuleSpec(path string) (*RuleSpec, error) {
+	var spec RuleSpec
+	if err := specfile.Load(path, "functor", &spec); err != nil {
+		return nil, err
+	}
+	return &spec, nil
+}
+
+// SaveRuleSpec writes a functor spec to a file, as JSON if the path ends in
+// ".json" and as YAML otherwise.
+func SaveRuleSpec(spec *RuleSpec, path string) error {
+	var data []byte
+	var err error
+	if strings.HasSuffix(path, ".json") {
+		data, err = json.MarshalIndent(spec, "", "  ")
+		data = append(data, '\n')
+	} else {
+		var buf bytes.Buffer
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		err = enc.Encode(spec)
+		data = buf.Bytes()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to encode functor spec: %v", err)
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// RuleFunctor is a functor defined by a RuleSpec.
+//
+// Objects mapped to the same target object are recorded in its "members"
+// metadata. Morphisms between different target objects are aggregated per
+// target type into a morphism "<type>:<source>-><target>" with their
+// "multiplicity" and underlying "morphisms"; morphisms within one target
+// object map to its identity. Dropped objects and morphisms, and morphisms
+// touching dropped objects, are reported with ErrUnmapped.
+type RuleFunctor struct {
+	*BaseFunctor
+	spec  *RuleSpec
+	rules []*compiledRule
+}
+
+// compiledRule is an ObjectRule with its patterns compiled.
+type compiledRule struct {
+	rule     ObjectRule
+	id       *regexp.Regexp
+	idRegex  bool
+	typ      *regexp.Regexp
+	metadata map[string]*regexp.Regexp
+}
+
+// NewRuleFunctor compiles spec into a functor from source to target.
+func NewRuleFunctor(spec *RuleSpec, source, target *category.Category) (*RuleFunctor, error) {
+	switch spec.Unmatched {
+	case "":
+		spec.Unmatched = "keep"
+	case "keep", dropValue:
+	default:
+		return nil, fmt.Errorf("invalid unmatched policy %q (want keep or drop)", spec.Unmatched)
+	}
+
+	name := spec.Name
+	if name == "" {
+		name = "Rules"
+	}
+	f := &RuleFunctor{
+		BaseFunctor: NewBaseFunctor(name, source, target),
+		spec:        spec,
+	}
+
+	for i, rule := range spec.Objects {
+		if rule.Target.ID == "" {
+			return nil, fmt.Errorf("object rule %d: target id is required", i+1)
+		}
+		compiled := &compiledRule{rule: rule, metadata: make(map[string]*regexp.Regexp)}
+		var err error
+		if compiled.id, compiled.idRegex, err = glob.Compile(rule.Match.ID); err != nil {
+			return nil, fmt.Errorf("object rule %d: %v", i+1, err)
+		}
+		if compiled.typ, _, err = glob.Compile(rule.Match.Type); err != nil {
+			return nil, fmt.Errorf("object rule %d: %v", i+1, err)
+		}
+		for key, pattern := range rule.Match.Metadata {
+			if compiled.metadata[key], _, err = glob.Compile(pattern); err != nil {
+				return nil, fmt.Errorf("object rule %d: metadata %s: %v", i+1, key, err)
+			}
+		}
+		f.rules = append(f.rules, compiled)
+	}
+
+	return f, nil
+}
+
+// MapObject maps an object through the first matching rule.
+func (f *RuleFunctor) MapObject(obj *category.Object) (*category.Object, error) {
+	if cached, exists := f.GetObjectMapping(obj.ID); exists {
+		return cached, nil
+	}
+
+	var template ObjectTemplate
+	var expand func(string) string
+	for _, rule := range f.rules {
+		if expand = rule.match(obj); expand != nil {
+			template = rule.rule.Target
+			break
+		}
+	}
+	if expand == nil {
+		if f.spec.Unmatched == dropValue {
+			return nil, fmt.Errorf("object %s: %w", obj.ID, ErrUnmapped)
+		}
+		template = ObjectTemplate{ID: obj.ID, Type: obj.Type, Name: obj.Name, Metadata: obj.Metadata}
+		expand = func(s string) string { return s }
+	}
+
+	targetID := expand(template.ID)
+	targetObj, exists := f.target.GetObject(targetID)
+	if !exists {
+		metadata := make(map[string]interface{}, len(template.Metadata)+1)
+		for key, value := range template.Metadata {
+			if s, ok := value.(string); ok {
+				value = expand(s)
+			}
+			metadata[key] = value
+		}
+		metadata["members"] = []string{}
+
+		objType := expand(template.Type)
+		if objType == "" {
+			objType = "group"
+		}
+		name := expand(template.Name)
+		if name == "" {
+			name = targetID
+		}
+
+		targetObj = category.NewObject(targetID, objType, name, metadata)
+		if err := f.target.AddObject(targetObj); err != nil {
+			return nil, err
+		}
+	}
+	if members, ok := targetObj.Metadata["members"].([]string); ok {
+		targetObj.Metadata["members"] = append(members, obj.ID)
+	}
+
+	f.AddObjectMapping(obj.ID, targetObj)
+	return targetObj, nil
+}
+
+// MapMorphism maps a morphism according to its type and its endpoints' targets.
+func (f *RuleFunctor) MapMorphism(morph *category.Morphism) (*category.Morphism, error) {
+	if cached, exists := f.GetMorphismMapping(morph.ID); exists {
+		return cached, nil
+	}
+
+	source, target, err := mapEndpoints(f, morph)
+	if err != nil {
+		return nil, err
+	}
+	if morph.Type == "identity" || morph.Type == "composed" || source.ID == target.ID {
+		return endpointMorphism(f.target, morph, source, target), nil
+	}
+
+	morphType := f.morphismType(morph.Type)
+	if morphType == dropValue {
+		return nil, fmt.Errorf("morphism %s of type %s: %w", morph.ID, morph.Type, ErrUnmapped)
+	}
+
+	id := fmt.Sprintf("%s:%s->%s", morphType, source.ID, target.ID)
+	mapped, exists := f.target.GetMorphism(id)
+	if !exists {
+		mapped = category.NewMorphism(id, source.ID, target.ID, morphType, map[string]interface{}{
+			"multiplicity": 0,
+			"morphisms":    []string{},
+		})
+		if err := f.target.AddMorphism(mapped); err != nil {
+			return nil, err
+		}
+	}
+	if count, ok := mapped.Metadata["multiplicity"].(int); ok {
+		mapped.Metadata["multiplicity"] = count + 1
+	}
+	if ids, ok := mapped.Metadata["morphisms"].([]string); ok {
+		mapped.Metadata["morphisms"] = append(ids, morph.ID)
+	}
+
+	f.AddMorphismMapping(morph.ID, mapped)
+	return mapped, nil
+}
+
+// VerifyLaws verifies functor laws on the mapped part of the source category.
+func (f *RuleFunctor) VerifyLaws() error {
+	if err := f.VerifyIdentityLaw(f); err != nil {
+		return err
+	}
+	return f.VerifyCompositionLaw(f)
+}
+
+// morphismType returns the target type for a source morphism type.
+func (f *RuleFunctor) morphismType(sourceType string) string {
+	if t, ok := f.spec.Morphisms[sourceType]; ok {
+		return t
+	}
+	if t, ok := f.spec.Morphisms["*"]; ok {
+		return t
+	}
+	return sourceType
+}
+
+// match reports whether obj satisfies the rule. On a match it returns the
+// template expansion function for the rule's target; otherwise nil.
+func (r *compiledRule) match(obj *category.Object) func(string) string {
+	if r.typ != nil && !r.typ.MatchString(obj.Type) {
+		return nil
+	}
+	for key, re := range r.metadata {
+		value, ok := obj.Metadata[key]
+		if !ok || !re.MatchString(fmt.Sprintf("%v", value)) {
+			return nil
+		}
+	}
+
+	var idMatch []int
+	if r.id != nil {
+		if idMatch = r.id.FindStringSubmatchIndex(obj.ID); idMatch == nil {
+			return nil
+		}
+	}
+
+	return func(template string) string {
+		if r.idRegex && strings.Contains(template, "$") {
+			template = string(r.id.ExpandString(nil, template, obj.ID, idMatch))
+		}
+		return expandPlaceholders(template, obj)
+	}
+}
+
+// placeholderPattern matches "{id}", "{name}", "{type}" and "{metadata.<key>}".
+var placeholderPattern = regexp.MustCompile(`\{(id|name|type|metadata\.[^}]+)\}`)
+
+// expandPlaceholders substitutes object properties into a template.
+func expandPlaceholders(template string, obj *category.Object) string {
+	return placeholderPattern.ReplaceAllStringFunc(template, func(p string) string {
+		field := p[1 : len(p)-1]
+		switch field {
+		case "id":
+			return obj.ID
+		case "name":
+			return obj.Name
+		case "type":
+			return obj.Type
+		}
+		if value, ok := obj.Metadata[strings.TrimPrefix(field, "metadata.")]; ok {
+			return fmt.Sprintf("%v", value)
+		}
+		return ""
+	})
+}
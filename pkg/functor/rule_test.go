@@ -0,0 +1,155 @@
+package functor
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/manu/catreview/internal/modeltest"
+	"github.com/manu/catreview/pkg/category"
+)
+
+const contextSpec = `
+name: FileToContext
+objects:
+  - match:
+      id: "regex:^services/(?P<svc>[^/]+)/"
+    target:
+      id: "context:${svc}"
+      type: bounded_context
+      metadata:
+        package: "{metadata.package}"
+  - match:
+      type: file
+      metadata:
+        package: "shared*"
+    target:
+      id: "context:shared"
+unmatched: drop
+morphisms:
+  co_changes_with: drop
+  "*": dependency
+`
+
+func ruleFixture(t *testing.T) *category.Category {
+	return modeltest.Build(t, "files", []*category.Object{
+		category.NewObject("services/billing/a.go", "file", "a.go", map[string]interface{}{"package": "billing"}),
+		category.NewObject("services/billing/b.go", "file", "b.go", map[string]interface{}{"package": "billing"}),
+		category.NewObject("lib/shared/c.go", "file", "c.go", map[string]interface{}{"package": "sharedutil"}),
+		category.NewObject("import:fmt", "imported_package", "fmt", nil),
+	}, []*category.Morphism{
+		category.NewMorphism("m1", "services/billing/a.go", "lib/shared/c.go", "import", nil),
+		category.NewMorphism("m2", "services/billing/b.go", "lib/shared/c.go", "function_call", nil),
+		category.NewMorphism("m3", "services/billing/a.go", "lib/shared/c.go", "co_changes_with", nil),
+		category.NewMorphism("m4", "lib/shared/c.go", "import:fmt", "import", nil),
+	})
+}
+
+func loadSpec(t *testing.T, content string) *RuleSpec {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "spec.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	spec, err := LoadRuleSpec(path)
+	if err != nil {
+		t.Fatalf("LoadRuleSpec failed: %v", err)
+	}
+	return spec
+}
+
+func TestRuleFunctor(t *testing.T) {
+	source := ruleFixture(t)
+	f, err := NewRuleFunctor(loadSpec(t, contextSpec), source, category.NewCategory("contexts"))
+	if err != nil {
+		t.Fatalf("NewRuleFunctor failed: %v", err)
+	}
+
+	for _, obj := range source.Objects() {
+		_, err := f.MapObject(obj)
+		if obj.ID == "import:fmt" {
+			if !errors.Is(err, ErrUnmapped) {
+				t.Errorf("Expected unmatched object to be dropped, got %v", err)
+			}
+		} else if err != nil {
+			t.Errorf("Failed to map %s: %v", obj.ID, err)
+		}
+	}
+	for _, m := range source.Morphisms() {
+		f.MapMorphism(m)
+	}
+
+	target := f.TargetCategory()
+	billing, exists := target.GetObject("context:billing")
+	if !exists {
+		t.Fatal("Expected object context:billing")
+	}
+	if billing.Type != "bounded_context" || billing.Metadata["package"] != "billing" {
+		t.Errorf("Expected expanded template, got type=%s metadata=%v", billing.Type, billing.Metadata)
+	}
+	if members := billing.Metadata["members"].([]string); len(members) != 2 {
+		t.Errorf("Expected 2 members, got %v", members)
+	}
+
+	dep, exists := target.GetMorphism("dependency:context:billing->context:shared")
+	if !exists {
+		t.Fatal("Expected aggregated dependency morphism")
+	}
+	if dep.Metadata["multiplicity"] != 2 {
+		t.Errorf("Expected multiplicity 2 (co-change dropped), got %v", dep.Metadata["multiplicity"])
+	}
+	if stats := target.Stats(); stats["morphisms"] != 1 {
+		t.Errorf("Expected 1 morphism, got %d", stats["morphisms"])
+	}
+
+	if err := f.VerifyLaws(); err != nil {
+		t.Errorf("Functor laws violated: %v", err)
+	}
+}
+
+func TestRuleSpecValidation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spec.yaml")
+	os.WriteFile(path, []byte("objects:\n  - match: {id: a}\n    taget: {id: b}\n"), 0644)
+	if _, err := LoadRuleSpec(path); err == nil {
+		t.Error("Expected error for unknown field")
+	}
+
+	source := ruleFixture(t)
+	bad := []*RuleSpec{
+		{Objects: []ObjectRule{{Match: ObjectMatch{ID: "a"}}}},
+		{Objects: []ObjectRule{{Match: ObjectMatch{ID: "regex:("}, Target: ObjectTemplate{ID: "x"}}}},
+		{Unmatched: "ignore"},
+	}
+	for i, spec := range bad {
+		if _, err := NewRuleFunctor(spec, source, category.NewCategory("t")); err == nil {
+			t.Errorf("Spec %d: expected error", i)
+		}
+	}
+}
+
+func TestSaveRuleSpec(t *testing.T) {
+	spec := &RuleSpec{
+		Name: "FileToModule",
+		Objects: []ObjectRule{{
+			Match:  ObjectMatch{Metadata: map[string]string{"file": "regex:^services/billing/"}},
+			Target: ObjectTemplate{ID: "module:billing", Type: "module"},
+		}},
+	}
+
+	for _, name := range []string{"spec.yaml", "spec.json"} {
+		path := filepath.Join(t.TempDir(), name)
+		if err := SaveRuleSpec(spec, path); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		loaded, err := LoadRuleSpec(path)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if loaded.Name != spec.Name || len(loaded.Objects) != 1 ||
+			loaded.Objects[0].Match.Metadata["file"] != "regex:^services/billing/" ||
+			loaded.Objects[0].Target.ID != "module:billing" {
+			t.Errorf("%s: spec changed in round trip: %+v", name, loaded)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+// Package ownership maps codebase objects to the teams that own them.
+//
+// Ownership is read from CODEOWNERS files (GitHub and GitLab syntax) and an
+// optional ownership map that groups individual owners into teams. Owned
+// objects carry an "owner" metadata attribute, which the ownership functor
+// uses to lift a file-level category to a team-level category.
+package ownership
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/manu/catreview/internal/glob"
+)
+
+// Rule is a single CODEOWNERS entry.
+type Rule struct {
+	Pattern string   `json:"pattern"`
+	Owners  []string `json:"owners"`
+	Section string   `json:"section,omitempty"` // GitLab section name, empty for GitHub
+	Line    int      `json:"line"`
+
+	regex *regexp.Regexp
+}
+
+// Codeowners is a parsed CODEOWNERS file.
+type Codeowners struct {
+	// Root is the directory CODEOWNERS patterns are relative to.
+	Root  string
+	Rules []*Rule
+}
+
+// StandardLocations lists where CODEOWNERS files are looked up, relative to
+// the repository root, in order of precedence.
+var StandardLocations = []string{
+	".github/CODEOWNERS",
+	".gitlab/CODEOWNERS",
+	"CODEOWNERS",
+	"docs/CODEOWNERS",
+}
+
+// sectionHeader matches GitLab section headers such as "[Docs]", "^[Docs][2] @docs".
+var sectionHeader = regexp.MustCompile(`^\^?\[([^\]]+)\](?:\[\d+\])?\s*(.*)$`)
+
+// Parse parses CODEOWNERS content in GitHub or GitLab syntax.
+//
+// GitLab sections are supported: each section is evaluated independently and
+// entries without owners inherit the section's default owners.
+func Parse(r io.Reader) (*Codeowners, error) {
+	co := &Codeowners{}
+	section := ""
+	var sectionOwners []string
+
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if m := sectionHeader.FindStringSubmatch(line); m != nil {
+			section = m[1]
+			sectionOwners = strings.Fields(stripComment(m[2]))
+			continue
+		}
+
+		fields := strings.Fields(stripComment(line))
+		if len(fields) == 0 {
+			continue
+		}
+		pattern := strings.ReplaceAll(fields[0], `\#`, "#")
+		owners := fields[1:]
+		if len(owners) == 0 {
+			owners = sectionOwners
+		}
+
+		regex, err := compilePattern(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid pattern %q: %v", lineNum, pattern, err)
+		}
+
+		co.Rules = append(co.Rules, &Rule{
+			Pattern: pattern,
+			Owners:  owners,
+			Section: section,
+			Line:    lineNum,
+			regex:   regex,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return co, nil
+}
+
+// LoadFile parses a CODEOWNERS file. Patterns are resolved relative to the
+// repository root, which is the file's directory or, for files inside
+// .github, .gitlab or docs, its parent.
+func LoadFile(path string) (*Codeowners, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	co, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	root := filepath.Dir(path)
+	switch filepath.Base(root) {
+	case ".github", ".gitlab", "docs":
+		root = filepath.Dir(root)
+	}
+	co.Root = root
+
+	return co, nil
+}
+
+// Find looks up a CODEOWNERS file in the standard locations under root.
+// Returns nil without error if none exists.
+func Find(root string) (*Codeowners, error) {
+	for _, loc := range StandardLocations {
+		path := filepath.Join(root, loc)
+		if _, err := os.Stat(path); err == nil {
+			return LoadFile(path)
+		}
+	}
+	return nil, nil
+}
+
+// Owners returns the owners of a slash-separated path relative to Root.
+//
+// Within a section the last matching rule wins, as in GitHub. Owners from all
+// GitLab sections with a matching rule are combined in file order.
+func (co *Codeowners) Owners(path string) []string {
+	path = strings.TrimPrefix(filepath.ToSlash(path), "./")
+
+	matches := make(map[string]*Rule)
+	var order []string
+	for _, rule := range co.Rules {
+		if !rule.regex.MatchString(path) {
+			continue
+		}
+		if _, seen := matches[rule.Section]; !seen {
+			order = append(order, rule.Section)
+		}
+		matches[rule.Section] = rule
+	}
+
+	var owners []string
+	seen := make(map[string]bool)
+	for _, section := range order {
+		for _, owner := range matches[section].Owners {
+			if !seen[owner] {
+				seen[owner] = true
+				owners = append(owners, owner)
+			}
+		}
+	}
+	return owners
+}
+
+// compilePattern translates a gitignore-style CODEOWNERS pattern to a regex.
+//
+// Patterns starting with "/" or containing an inner "/" are anchored to the
+// root; other patterns match at any depth. A pattern matches a path itself
+// and everything below it, a trailing "/" matches directory contents only, and
+// a trailing "/*" matches only the files directly inside a directory.
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	dirOnly := strings.HasSuffix(pattern, "/")
+	p := strings.TrimSuffix(pattern, "/")
+	anchored := strings.HasPrefix(p, "/") || strings.Contains(p, "/")
+	p = strings.TrimPrefix(p, "/")
+
+	var sb strings.Builder
+	sb.WriteString("^")
+	if !anchored {
+		sb.WriteString("(?:.*/)?")
+	}
+
+	sb.WriteString(glob.Expr(p))
+
+	switch {
+	case dirOnly:
+		sb.WriteString("/.*$")
+	case strings.HasSuffix(p, "/*") && !strings.HasSuffix(p, "/**"):
+		// "docs/*" matches files directly in docs, not in its subdirectories
+		sb.WriteString("$")
+	default:
+		sb.WriteString("(?:/.*)?$")
+	}
+
+	return regexp.Compile(sb.String())
+}
+
+// stripComment removes a trailing " #" comment from a CODEOWNERS line.
+func stripComment(line string) string {
+	if idx := strings.Index(line, " #"); idx >= 0 {
+		return strings.TrimSpace(line[:idx])
+	}
+	return line
+}
@@ -0,0 +1,78 @@
+package ownership
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCodeownersLastMatchWins(t *testing.T) {
+	co, err := Parse(strings.NewReader(`
+# Default owners
+*           @org/core
+*.md        @docs      # trailing comment
+/pkg/api/   @api-team
+docs/*      @writers
+**/testdata @qa
+`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{"main.go", []string{"@org/core"}},
+		{"README.md", []string{"@docs"}},
+		{"pkg/api/handler.go", []string{"@api-team"}},
+		{"pkg/api/v1/handler.go", []string{"@api-team"}},
+		{"internal/pkg/api/handler.go", []string{"@org/core"}},
+		{"docs/guide.txt", []string{"@writers"}},
+		{"docs/deep/guide.txt", []string{"@org/core"}},
+		{"pkg/extractor/testdata/x.go", []string{"@qa"}},
+	}
+	for _, tt := range tests {
+		if got := co.Owners(tt.path); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Owners(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestCodeownersGitLabSections(t *testing.T) {
+	co, err := Parse(strings.NewReader(`
+[Backend] @backend
+pkg/
+^[Docs][2] @docs
+*.md
+pkg/README.md @pkg-docs
+`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if got := co.Owners("pkg/a.go"); !reflect.DeepEqual(got, []string{"@backend"}) {
+		t.Errorf("Expected section default owners, got %v", got)
+	}
+	want := []string{"@backend", "@pkg-docs"}
+	if got := co.Owners("pkg/README.md"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected owners combined across sections %v, got %v", want, got)
+	}
+	if got := co.Owners("cmd/main.go"); len(got) != 0 {
+		t.Errorf("Expected no owners, got %v", got)
+	}
+}
+
+func TestTeamMap(t *testing.T) {
+	teams := TeamMap{"@alice": "payments"}
+
+	if got := teams.Team([]string{"@alice", "@bob"}); got != "payments" {
+		t.Errorf("Expected mapped team 'payments', got %q", got)
+	}
+	if got := teams.Team([]string{"@org/search"}); got != "org/search" {
+		t.Errorf("Expected unmapped owner as team, got %q", got)
+	}
+	if got := teams.Team(nil); got != "" {
+		t.Errorf("Expected empty team for no owners, got %q", got)
+	}
+}
@@ -0,0 +1,140 @@
+package ownership
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/manu/catreview/pkg/analysis"
+	"github.com/manu/catreview/pkg/category"
+)
+
+// TeamMap groups individual CODEOWNERS owners (users, groups, emails) into teams.
+// Owners without an entry are their own team.
+type TeamMap map[string]string
+
+// LoadTeamMap reads an ownership map from a JSON object of owner -> team.
+func LoadTeamMap(path string) (TeamMap, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var teams TeamMap
+	if err := json.Unmarshal(data, &teams); err != nil {
+		return nil, err
+	}
+	return teams, nil
+}
+
+// Team returns the team of the first owner in owners, or "" if owners is empty.
+func (t TeamMap) Team(owners []string) string {
+	if len(owners) == 0 {
+		return ""
+	}
+	if team, ok := t[owners[0]]; ok {
+		return team
+	}
+	return strings.TrimPrefix(owners[0], "@")
+}
+
+// Annotate sets "owners" and "owner" metadata on every object of cat that can
+// be attributed to a file. The "owner" attribute is the owning team.
+//
+// Imported packages that resolve to packages inside the model are owned by
+// the team owning most of that package's files. Returns the number of objects
+// that received an owner.
+func Annotate(cat *category.Category, co *Codeowners, teams TeamMap) (int, error) {
+	root, err := filepath.Abs(co.Root)
+	if err != nil {
+		return 0, err
+	}
+
+	annotated := 0
+	for _, obj := range cat.Objects() {
+		path := objectPath(cat, obj)
+		if path == "" {
+			continue
+		}
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(root, abs)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+
+		owners := co.Owners(rel)
+		if len(owners) == 0 {
+			continue
+		}
+		obj.Metadata["owners"] = owners
+		obj.Metadata["owner"] = teams.Team(owners)
+		annotated++
+	}
+
+	// Attribute imported packages to the team owning the package
+	resolver := analysis.NewImportResolver(cat)
+	for _, obj := range cat.Objects() {
+		if obj.Type != "imported_package" {
+			continue
+		}
+		importPath, _ := obj.Metadata["import_path"].(string)
+		if owner := majorityOwner(cat, resolver.Resolve(importPath)); owner != "" {
+			obj.Metadata["owner"] = owner
+			annotated++
+		}
+	}
+
+	return annotated, nil
+}
+
+// objectPath returns the source file path an object belongs to: the "path"
+// of its file object, where the file was read from, or the file ID.
+func objectPath(cat *category.Category, obj *category.Object) string {
+	if path, ok := obj.Metadata["path"].(string); ok {
+		return path
+	}
+	if file, ok := obj.Metadata["file"].(string); ok {
+		if fileObj, ok := cat.GetObject(file); ok && fileObj.Type == "file" {
+			return objectPath(cat, fileObj)
+		}
+		return file
+	}
+	if obj.Type == "file" {
+		return obj.ID
+	}
+	return ""
+}
+
+// majorityOwner returns the most common owner among the given objects.
+// Ties are broken alphabetically.
+func majorityOwner(cat *category.Category, ids []string) string {
+	counts := make(map[string]int)
+	for _, id := range ids {
+		if obj, ok := cat.GetObject(id); ok {
+			if owner, ok := obj.Metadata["owner"].(string); ok && owner != "" {
+				counts[owner]++
+			}
+		}
+	}
+
+	owners := make([]string, 0, len(counts))
+	for owner := range counts {
+		owners = append(owners, owner)
+	}
+	sort.Slice(owners, func(i, j int) bool {
+		if counts[owners[i]] != counts[owners[j]] {
+			return counts[owners[i]] > counts[owners[j]]
+		}
+		return owners[i] < owners[j]
+	})
+
+	if len(owners) == 0 {
+		return ""
+	}
+	return owners[0]
+}
@@ -0,0 +1,175 @@
+package ownership
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/manu/catreview/internal/modeltest"
+	"github.com/manu/catreview/pkg/analysis"
+	"github.com/manu/catreview/pkg/category"
+	"github.com/manu/catreview/pkg/functor"
+)
+
+// ownedFixture models a billing service and a shared library, owned by:
+//
+//	services/billing/a.go       @alice (team payments)
+//	services/billing/legacy.go  @bob, overriding the billing directory
+//	lib/shared/c.go             @search
+//	lib/shared/gen.go           nobody
+func ownedFixture(t *testing.T) (*category.Category, *Codeowners) {
+	t.Helper()
+	co, err := Parse(strings.NewReader(`
+*                           @org/core
+/services/billing/          @alice
+/services/billing/legacy.go @bob
+/lib/                       @search
+/lib/shared/gen.go
+`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	co.Root = "."
+
+	var objects []*category.Object
+	for _, file := range []string{"services/billing/a.go", "services/billing/legacy.go", "lib/shared/c.go", "lib/shared/gen.go"} {
+		objects = append(objects, category.NewObject(file, "file", file, nil))
+	}
+	objects = append(objects,
+		category.NewObject("billing.Pay", "function", "Pay", map[string]interface{}{"file": "services/billing/a.go"}),
+		category.NewObject("import:example.com/app/services/billing", "imported_package", "example.com/app/services/billing",
+			map[string]interface{}{"import_path": "example.com/app/services/billing"}))
+
+	calls := [][3]string{
+		{"m1", "services/billing/a.go", "lib/shared/c.go"},
+		{"m2", "services/billing/legacy.go", "lib/shared/c.go"},
+		{"m3", "billing.Pay", "lib/shared/c.go"},
+		{"m4", "lib/shared/c.go", "import:example.com/app/services/billing"},
+		{"m5", "lib/shared/gen.go", "lib/shared/c.go"},
+		{"m6", "services/billing/a.go", "services/billing/legacy.go"},
+		{"m7", "services/billing/a.go", "billing.Pay"},
+	}
+	var morphisms []*category.Morphism
+	for _, m := range calls {
+		morphisms = append(morphisms, category.NewMorphism(m[0], m[1], m[2], "function_call", nil))
+	}
+	return modeltest.Build(t, "owned", objects, morphisms), co
+}
+
+func TestAnnotate(t *testing.T) {
+	cat, co := ownedFixture(t)
+	annotated, err := Annotate(cat, co, TeamMap{"@alice": "payments"})
+	if err != nil {
+		t.Fatalf("Annotate failed: %v", err)
+	}
+	if annotated != 5 {
+		t.Errorf("Expected 5 owned objects, got %d", annotated)
+	}
+
+	want := map[string]string{
+		"services/billing/a.go":      "payments",
+		"services/billing/legacy.go": "bob", // Last match wins
+		"lib/shared/c.go":            "search",
+		"lib/shared/gen.go":          "", // Matched by a rule without owners
+		"billing.Pay":                "payments",
+		// billing has one file of payments and one of bob; ties break alphabetically
+		"import:example.com/app/services/billing": "bob",
+	}
+	for id, team := range want {
+		obj, _ := cat.GetObject(id)
+		if owner, _ := obj.Metadata["owner"].(string); owner != team {
+			t.Errorf("Expected %s owned by %q, got %q", id, team, owner)
+		}
+	}
+	a, _ := cat.GetObject("services/billing/a.go")
+	if !reflect.DeepEqual(a.Metadata["owners"], []string{"@alice"}) {
+		t.Errorf("Expected the CODEOWNERS owners recorded, got %v", a.Metadata["owners"])
+	}
+}
+
+func TestTeamCoupling(t *testing.T) {
+	cat, co := ownedFixture(t)
+	if _, err := Annotate(cat, co, TeamMap{"@alice": "payments"}); err != nil {
+		t.Fatalf("Annotate failed: %v", err)
+	}
+
+	matrix := analysis.ComputeTeamCoupling(cat, nil)
+	if !reflect.DeepEqual(matrix.Teams, []string{"bob", "payments", "search"}) {
+		t.Fatalf("Unexpected teams %v", matrix.Teams)
+	}
+	// Rows depend on columns; m5 from the unowned gen.go is not counted
+	want := [][]int{
+		{0, 0, 1}, // bob: m2
+		{1, 1, 2}, // payments: m6, m7, m1 and m3
+		{1, 0, 0}, // search: m4
+	}
+	if !reflect.DeepEqual(matrix.Counts, want) {
+		t.Errorf("Expected counts %v, got %v", want, matrix.Counts)
+	}
+	if matrix.CrossTeam() != 5 {
+		t.Errorf("Expected 5 cross-team dependencies, got %d", matrix.CrossTeam())
+	}
+
+	if analysis.ComputeTeamCoupling(category.NewCategory("empty"), nil) != nil {
+		t.Error("Expected no matrix without owners")
+	}
+}
+
+func TestOwnershipFunctor(t *testing.T) {
+	cat, co := ownedFixture(t)
+	if _, err := Annotate(cat, co, TeamMap{"@alice": "payments"}); err != nil {
+		t.Fatalf("Annotate failed: %v", err)
+	}
+
+	// A co-change is no dependency, for the functor as for the matrix
+	modeltest.Add(t, cat, nil, []*category.Morphism{
+		category.NewMorphism("m8", "services/billing/a.go", "lib/shared/c.go", "co_changes_with", nil),
+	})
+
+	f := functor.NewOwnershipFunctor(cat, category.NewCategory("teams"))
+	app, err := functor.Apply(f)
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if app.LawError != nil || len(app.Failures) != 0 {
+		t.Fatalf("Expected a lawful functor, got %v %v", app.LawError, app.Failures)
+	}
+
+	for _, team := range []string{"team:payments", "team:bob", "team:search", "team:unowned"} {
+		if _, ok := app.Target.GetObject(team); !ok {
+			t.Errorf("Expected team object %s", team)
+		}
+	}
+	dep, ok := app.Target.GetMorphism("dep:team:payments->team:search")
+	if !ok || dep.Metadata["count"] != 2 {
+		t.Errorf("Expected payments to depend on search twice, got %v", dep)
+	}
+	if len(app.Unmapped) != 1 || app.Unmapped[0] != "m8" {
+		t.Errorf("Expected the co-change m8 unmapped, got %v", app.Unmapped)
+	}
+	matrix := analysis.ComputeTeamCoupling(cat, nil)
+	if want := matrix.Counts[1][2]; want != 2 {
+		t.Errorf("Expected the matrix to count payments -> search twice too, got %d", want)
+	}
+
+	// An edge filter selects the dependencies, as for the matrix
+	calls := functor.NewOwnershipFunctor(cat, category.NewCategory("teams"))
+	calls.Edges = &analysis.EdgeFilter{Exclude: []string{"function_call"}}
+	filtered, err := functor.Apply(calls)
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	for _, m := range filtered.Target.Morphisms() {
+		if m.Type != "identity" {
+			t.Errorf("Expected no team dependencies without function calls, got %s", m.ID)
+		}
+	}
+
+	// Dependencies within a team map to its identity
+	m7, _ := cat.GetMorphism("m7")
+	mapped, err := f.MapMorphism(m7)
+	identity, _ := app.Target.Identity("team:payments")
+	if err != nil || mapped != identity {
+		t.Errorf("Expected m7 to map to the identity of team:payments, got %v (%v)", mapped, err)
+	}
+}
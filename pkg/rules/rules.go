@@ -0,0 +1,551 @@
+// Package rules checks architecture fitness rules against a categorical
+// model: which parts of a codebase may depend on which, and how coupled its
+// packages may be.
+package rules
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/manu/catreview/internal/glob"
+	"github.com/manu/catreview/internal/specfile"
+	"github.com/manu/catreview/pkg/analysis"
+	"github.com/manu/catreview/pkg/category"
+	"github.com/manu/catreview/pkg/functor"
+)
+
+// Spec declares architecture rules in YAML or JSON:
+//
+//	name: Architecture
+//	layers:
+//	  - name: domain
+//	    paths: ["internal/domain/**"]
+//	  - name: infra
+//	    paths: ["internal/db/**", "internal/queue/**"]
+//	rules:
+//	  - name: domain-is-pure
+//	    description: The domain layer must not depend on infrastructure
+//	    forbid:
+//	      from: [domain]
+//	      to: [infra]
+//	  - name: http-at-the-edge
+//	    only:
+//	      from: ["pkg/api/**"]
+//	      to: ["net/http"]
+//	  - name: x-is-private
+//	    forbid:
+//	      from: ["**"]
+//	      to: ["internal/x/**"]
+//	      except: ["cmd/*/**"]
+//	  - name: small-packages
+//	    severity: warning
+//	    max_efferent:
+//	      packages: ["pkg/**"]
+//	      limit: 20
+//
+// Selectors name a layer of the spec, or are path patterns as in layer
+// specs: globs ("*" within a path segment, "**" across segments) or regular
+// expressions with a "regex:" prefix. They match the file of an object, and
+// imported packages by their import path or its trailing elements, as their
+// directory would: "internal/x/**" matches an import of
+// "example.com/app/internal/x".
+type Spec struct {
+	Name   string          `yaml:"name" json:"name"`
+	Layers []functor.Layer `yaml:"layers,omitempty" json:"layers,omitempty"`
+	Rules  []Rule          `yaml:"rules" json:"rules"`
+}
+
+// Rule is a named constraint. Exactly one of Forbid, Only and MaxEfferent
+// is set.
+type Rule struct {
+	Name        string      `yaml:"name" json:"name"`
+	Description string      `yaml:"description,omitempty" json:"description,omitempty"`
+	Severity    string      `yaml:"severity,omitempty" json:"severity,omitempty"` // "error" (default) or "warning"
+	Forbid      *Dependency `yaml:"forbid,omitempty" json:"forbid,omitempty"`
+	Only        *Dependency `yaml:"only,omitempty" json:"only,omitempty"`
+	MaxEfferent *Coupling   `yaml:"max_efferent,omitempty" json:"max_efferent,omitempty"`
+}
+
+// Dependency selects dependencies from one part of a codebase to another.
+//
+// As a forbid rule, objects matching From, unless they match Except, must
+// not depend on objects matching To. As an only rule, no objects but those
+// matching From or Except may depend on objects matching To. Dependencies
+// within To are always allowed.
+type Dependency struct {
+	From   []string `yaml:"from" json:"from"`
+	To     []string `yaml:"to" json:"to"`
+	Except []string `yaml:"except,omitempty" json:"except,omitempty"`
+}
+
+// Coupling caps the efferent coupling Ce, the number of other analyzed
+// packages a package depends on, of the packages with files matching
+// Packages. Packages outside the analyzed code do not count.
+type Coupling struct {
+	Packages []string `yaml:"packages" json:"packages"`
+	Limit    int      `yaml:"limit" json:"limit"`
+}
+
+// Severities of rule violations.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Kinds of violations.
+const (
+	KindDependency = "dependency" // A forbidden dependency (forbid and only rules)
+	KindCoupling   = "coupling"   // A package over its coupling limit (max_efferent rules)
+)
+
+// Violation is a breach of a rule, with the morphisms causing it.
+type Violation struct {
+	Rule      string             `json:"rule"`
+	Kind      string             `json:"kind"`
+	Severity  string             `json:"severity"`
+	Message   string             `json:"message"`
+	Source    string             `json:"source"`             // Dependent file or package
+	Target    string             `json:"target,omitempty"`   // Dependency file or imported package
+	Efferent  int                `json:"efferent,omitempty"` // Efferent coupling of a coupling violation's package
+	Location  *analysis.Location `json:"location,omitempty"`
+	Morphisms []*Evidence        `json:"morphisms"`
+}
+
+// Fingerprint identifies the violation by its rule, source and target, so
+// it survives changes to line numbers and to the morphisms causing it. The
+// efferent coupling of a coupling violation is not part of it; baselines
+// record it separately.
+func (v *Violation) Fingerprint() string {
+	return analysis.Fingerprint(v.Kind, v.Rule, v.Source, v.Target)
+}
+
+// Evidence is a morphism a violation is made of, with its source position.
+type Evidence struct {
+	MorphismID string             `json:"morphism_id"`
+	Type       string             `json:"type"`
+	Source     string             `json:"source"`
+	Target     string             `json:"target"`
+	Location   *analysis.Location `json:"location,omitempty"`
+}
+
+// LoadSpec reads a rule spec from a YAML or JSON file.
+func LoadSpec(path string) (*Spec, error) {
+	var spec Spec
+	if err := specfile.Load(path, "rule", &spec); err != nil {
+		return nil, err
+	}
+	return &spec, nil
+}
+
+// Engine evaluates the rules of a spec.
+type Engine struct {
+	spec   *Spec
+	layers []*selector
+	rules  []*compiledRule
+}
+
+type compiledRule struct {
+	*Rule
+	from, to, except *selector
+}
+
+// selector matches objects by layer or path.
+type selector struct {
+	layers   []string
+	patterns []*regexp.Regexp
+}
+
+// NewEngine validates and compiles a rule spec. The engine defaults the
+// severity of its own copy of the rules, leaving spec unchanged.
+func NewEngine(spec *Spec) (*Engine, error) {
+	e := &Engine{spec: &Spec{
+		Layers: spec.Layers,
+		Rules:  append([]Rule(nil), spec.Rules...),
+	}}
+	layerNames := make(map[string]bool)
+	for i, layer := range spec.Layers {
+		if layer.Name == "" {
+			return nil, fmt.Errorf("layer %d: missing name", i+1)
+		}
+		if layerNames[layer.Name] {
+			return nil, fmt.Errorf("layer %s: duplicate name", layer.Name)
+		}
+		layerNames[layer.Name] = true
+		s, err := e.compile(layer.Paths, false)
+		if err != nil {
+			return nil, fmt.Errorf("layer %s: %v", layer.Name, err)
+		}
+		e.layers = append(e.layers, s)
+	}
+
+	ruleNames := make(map[string]bool)
+	for i := range e.spec.Rules {
+		rule := &e.spec.Rules[i]
+		if rule.Name == "" {
+			return nil, fmt.Errorf("rule %d: missing name", i+1)
+		}
+		if ruleNames[rule.Name] {
+			return nil, fmt.Errorf("rule %s: duplicate name", rule.Name)
+		}
+		ruleNames[rule.Name] = true
+		if rule.Severity == "" {
+			rule.Severity = SeverityError
+		}
+		if rule.Severity != SeverityError && rule.Severity != SeverityWarning {
+			return nil, fmt.Errorf("rule %s: invalid severity %q (want error or warning)", rule.Name, rule.Severity)
+		}
+
+		kinds := 0
+		for _, set := range []bool{rule.Forbid != nil, rule.Only != nil, rule.MaxEfferent != nil} {
+			if set {
+				kinds++
+			}
+		}
+		if kinds != 1 {
+			return nil, fmt.Errorf("rule %s: exactly one of forbid, only and max_efferent is required", rule.Name)
+		}
+
+		compiled := &compiledRule{Rule: rule}
+		var err error
+		switch {
+		case rule.MaxEfferent != nil:
+			if rule.MaxEfferent.Limit < 0 {
+				return nil, fmt.Errorf("rule %s: negative limit", rule.Name)
+			}
+			compiled.from, err = e.compile(rule.MaxEfferent.Packages, true)
+		default:
+			dep := rule.Forbid
+			if dep == nil {
+				dep = rule.Only
+			}
+			if len(dep.From) == 0 || len(dep.To) == 0 {
+				return nil, fmt.Errorf("rule %s: from and to are required", rule.Name)
+			}
+			if compiled.from, err = e.compile(dep.From, true); err == nil {
+				if compiled.to, err = e.compile(dep.To, true); err == nil {
+					compiled.except, err = e.compile(dep.Except, true)
+				}
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %v", rule.Name, err)
+		}
+		e.rules = append(e.rules, compiled)
+	}
+	return e, nil
+}
+
+// compile compiles selectors; with layers, names of layers select layers.
+func (e *Engine) compile(selectors []string, layers bool) (*selector, error) {
+	s := &selector{}
+	for _, sel := range selectors {
+		if layers && e.isLayer(sel) {
+			s.layers = append(s.layers, sel)
+			continue
+		}
+		if sel == "" {
+			return nil, fmt.Errorf("empty selector")
+		}
+		re, _, err := glob.Compile(sel)
+		if err != nil {
+			return nil, err
+		}
+		s.patterns = append(s.patterns, re)
+	}
+	return s, nil
+}
+
+func (e *Engine) isLayer(name string) bool {
+	for _, layer := range e.spec.Layers {
+		if layer.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Rules returns the rules of the engine's spec, in order.
+func (e *Engine) Rules() []Rule {
+	return e.spec.Rules
+}
+
+// Unmatched lists the path patterns of the spec's layers and rules that
+// select no object of cat, as "<layer or rule>: <pattern>". A rule whose
+// selectors match nothing is satisfied vacuously, e.g. when the patterns
+// were written for another extraction root.
+func (e *Engine) Unmatched(cat *category.Category) []string {
+	var paths []string
+	for _, obj := range cat.Objects() {
+		paths = append(paths, objectPaths(obj)...)
+	}
+
+	var unmatched []string
+	check := func(owner string, selectors []string, layers bool) {
+		for _, sel := range selectors {
+			if layers && e.isLayer(sel) {
+				continue
+			}
+			re, _, err := glob.Compile(sel)
+			if err == nil && !matchesAny([]*regexp.Regexp{re}, paths) {
+				unmatched = append(unmatched, owner+": "+sel)
+			}
+		}
+	}
+	for _, layer := range e.spec.Layers {
+		check("layer "+layer.Name, layer.Paths, false)
+	}
+	for _, rule := range e.spec.Rules {
+		owner := "rule " + rule.Name
+		if rule.MaxEfferent != nil {
+			check(owner, rule.MaxEfferent.Packages, true)
+		}
+		for _, dep := range []*Dependency{rule.Forbid, rule.Only} {
+			if dep != nil {
+				check(owner, dep.From, true)
+				check(owner, dep.To, true)
+				check(owner, dep.Except, true)
+			}
+		}
+	}
+	return unmatched
+}
+
+// Check evaluates every rule against cat, considering the morphisms
+// selected by edges as dependencies. Violations are listed by rule, then by
+// source and target.
+func (e *Engine) Check(cat *category.Category, edges *analysis.EdgeFilter) []*Violation {
+	morphisms := cat.Morphisms()
+	sort.Slice(morphisms, func(i, j int) bool { return morphisms[i].ID < morphisms[j].ID })
+
+	var metrics map[string]*analysis.PackageMetrics
+	violations := []*Violation{}
+	for _, rule := range e.rules {
+		var found []*Violation
+		if rule.MaxEfferent != nil {
+			if metrics == nil {
+				metrics = make(map[string]*analysis.PackageMetrics)
+				for _, p := range analysis.ComputePackageMetrics(cat, edges) {
+					metrics[p.Package] = p
+				}
+			}
+			found = e.checkCoupling(cat, rule, morphisms, edges, metrics)
+		} else {
+			found = e.checkDependencies(cat, rule, morphisms, edges)
+		}
+		sort.Slice(found, func(i, j int) bool {
+			if found[i].Source != found[j].Source {
+				return found[i].Source < found[j].Source
+			}
+			return found[i].Target < found[j].Target
+		})
+		violations = append(violations, found...)
+	}
+	return violations
+}
+
+// checkDependencies reports the forbidden dependencies of a forbid or only
+// rule, one violation per pair of dependent and dependency.
+func (e *Engine) checkDependencies(cat *category.Category, rule *compiledRule, morphisms []*category.Morphism, edges *analysis.EdgeFilter) []*Violation {
+	byPair := make(map[[2]string]*Violation)
+	var found []*Violation
+	for _, morph := range morphisms {
+		if !edges.Allows(morph) || morph.Type == "defines" {
+			continue
+		}
+		source, okSource := cat.GetObject(morph.Source)
+		target, okTarget := cat.GetObject(morph.Target)
+		if !okSource || !okTarget || len(objectPaths(source)) == 0 {
+			continue
+		}
+		if !e.matches(rule.to, target) || e.matches(rule.to, source) || e.matches(rule.except, source) {
+			continue
+		}
+		if fromMatches := e.matches(rule.from, source); fromMatches != (rule.Forbid != nil) {
+			continue
+		}
+
+		pair := [2]string{unitOf(source), unitOf(target)}
+		v, ok := byPair[pair]
+		if !ok {
+			message := fmt.Sprintf("%s must not depend on %s", pair[0], pair[1])
+			if rule.Only != nil {
+				message = fmt.Sprintf("%s depends on %s, which only %s may depend on",
+					pair[0], pair[1], strings.Join(rule.Only.From, ", "))
+			}
+			v = &Violation{
+				Rule:      rule.Name,
+				Kind:      KindDependency,
+				Severity:  rule.Severity,
+				Message:   message,
+				Source:    pair[0],
+				Target:    pair[1],
+				Morphisms: []*Evidence{},
+			}
+			byPair[pair] = v
+			found = append(found, v)
+		}
+		v.Morphisms = append(v.Morphisms, evidence(cat, morph))
+		if v.Location == nil {
+			v.Location = v.Morphisms[len(v.Morphisms)-1].Location
+		}
+	}
+	return found
+}
+
+// checkCoupling reports the packages of a max_efferent rule depending on
+// more packages than its limit, with one morphism per dependency.
+func (e *Engine) checkCoupling(cat *category.Category, rule *compiledRule, morphisms []*category.Morphism, edges *analysis.EdgeFilter, metrics map[string]*analysis.PackageMetrics) []*Violation {
+	// Packages in scope: those with a matching file
+	index := analysis.NewPackageIndex(cat)
+	scope := make(map[string]bool)
+	for _, obj := range cat.Objects() {
+		if pkg, internal := index.Package(obj); internal && e.matches(rule.from, obj) {
+			scope[pkg] = true
+		}
+	}
+
+	var found []*Violation
+	byPackage := make(map[string]*Violation)
+	seen := make(map[[2]string]bool)
+	for _, morph := range morphisms {
+		if !edges.Allows(morph) {
+			continue
+		}
+		source, okSource := cat.GetObject(morph.Source)
+		target, okTarget := cat.GetObject(morph.Target)
+		if !okSource || !okTarget {
+			continue
+		}
+		from, _ := index.Package(source)
+		to, internal := index.Package(target)
+		p := metrics[from]
+		if !scope[from] || p == nil || p.Efferent <= rule.MaxEfferent.Limit || !internal || to == from {
+			continue
+		}
+
+		v, ok := byPackage[from]
+		if !ok {
+			v = &Violation{
+				Rule:      rule.Name,
+				Kind:      KindCoupling,
+				Severity:  rule.Severity,
+				Message:   fmt.Sprintf("package %s depends on %d packages (limit %d)", from, p.Efferent, rule.MaxEfferent.Limit),
+				Source:    from,
+				Efferent:  p.Efferent,
+				Morphisms: []*Evidence{},
+			}
+			byPackage[from] = v
+			found = append(found, v)
+		}
+		if pair := [2]string{from, to}; !seen[pair] {
+			seen[pair] = true
+			v.Morphisms = append(v.Morphisms, evidence(cat, morph))
+			if v.Location == nil {
+				v.Location = v.Morphisms[len(v.Morphisms)-1].Location
+			}
+		}
+	}
+	return found
+}
+
+// matches reports whether a selector selects an object.
+func (e *Engine) matches(s *selector, obj *category.Object) bool {
+	paths := objectPaths(obj)
+	if len(s.layers) > 0 {
+		if layer := e.layerOf(paths); layer != "" {
+			for _, name := range s.layers {
+				if name == layer {
+					return true
+				}
+			}
+		}
+	}
+	return matchesAny(s.patterns, paths)
+}
+
+// layerOf returns the first layer containing one of paths, or "".
+func (e *Engine) layerOf(paths []string) string {
+	for i, layer := range e.layers {
+		if matchesAny(layer.patterns, paths) {
+			return e.spec.Layers[i].Name
+		}
+	}
+	return ""
+}
+
+func matchesAny(patterns []*regexp.Regexp, paths []string) bool {
+	for _, re := range patterns {
+		for _, p := range paths {
+			if re.MatchString(p) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// objectPaths returns the paths selectors match an object by: the file it
+// is declared in, the path of a directory, or the import path of an
+// imported package and its trailing elements, each also as a directory.
+func objectPaths(obj *category.Object) []string {
+	switch obj.Type {
+	case "file":
+		return []string{obj.ID}
+	case "directory":
+		if p, ok := obj.Metadata["path"].(string); ok && p != "" {
+			return []string{p}
+		}
+		return nil
+	case "imported_package":
+		importPath, _ := obj.Metadata["import_path"].(string)
+		if importPath == "" {
+			return nil
+		}
+		var paths []string
+		elems := strings.Split(importPath, "/")
+		for i := range elems {
+			p := strings.Join(elems[i:], "/")
+			paths = append(paths, p, p+"/")
+		}
+		return paths
+	}
+	if file, ok := obj.Metadata["file"].(string); ok && file != "" {
+		return []string{file}
+	}
+	return nil
+}
+
+// unitOf returns the file an object is declared in, or its ID.
+func unitOf(obj *category.Object) string {
+	if loc, ok := analysis.ObjectLocation(obj); ok {
+		return loc.File
+	}
+	return obj.ID
+}
+
+// evidence describes a morphism with its source position.
+func evidence(cat *category.Category, morph *category.Morphism) *Evidence {
+	ev := &Evidence{
+		MorphismID: morph.ID,
+		Type:       morph.Type,
+		Source:     morph.Source,
+		Target:     morph.Target,
+	}
+	if loc, ok := analysis.MorphismLocation(cat, morph); ok {
+		ev.Location = &loc
+	}
+	return ev
+}
+
+// Errors counts the violations of error severity.
+func Errors(violations []*Violation) int {
+	n := 0
+	for _, v := range violations {
+		if v.Severity == SeverityError {
+			n++
+		}
+	}
+	return n
+}
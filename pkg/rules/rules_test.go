@@ -0,0 +1,224 @@
+package rules
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/manu/catreview/internal/modeltest"
+	"github.com/manu/catreview/pkg/analysis"
+	"github.com/manu/catreview/pkg/category"
+	"github.com/manu/catreview/pkg/extractor"
+	"github.com/manu/catreview/pkg/functor"
+)
+
+// rulesFixture models a small layered service:
+//
+//	cmd/app/main.go --import--> net/http, example.com/app/internal/infra
+//	internal/domain/order.go: domain.Place --function_call--> infra.Save (line 12)
+//	internal/infra/db.go --import--> net/http
+func rulesFixture(t *testing.T) *category.Category {
+	objects := []*category.Object{
+		category.NewObject("cmd/app/main.go", "file", "main.go", map[string]interface{}{"package": "main"}),
+		category.NewObject("internal/domain/order.go", "file", "order.go", map[string]interface{}{"package": "domain"}),
+		category.NewObject("domain.Place", "function", "Place", map[string]interface{}{"package": "domain", "file": "internal/domain/order.go", "line": 10}),
+		category.NewObject("internal/infra/db.go", "file", "db.go", map[string]interface{}{"package": "infra"}),
+		category.NewObject("infra.Save", "function", "Save", map[string]interface{}{"package": "infra", "file": "internal/infra/db.go", "line": 5}),
+		category.NewObject("import:net/http", "imported_package", "net/http", map[string]interface{}{"import_path": "net/http"}),
+		category.NewObject("import:example.com/app/internal/infra", "imported_package", "example.com/app/internal/infra", map[string]interface{}{"import_path": "example.com/app/internal/infra"}),
+	}
+
+	morphisms := []*category.Morphism{
+		category.NewMorphism("i1", "cmd/app/main.go", "import:net/http", "import", map[string]interface{}{"line": 4}),
+		category.NewMorphism("i2", "cmd/app/main.go", "import:example.com/app/internal/infra", "import", map[string]interface{}{"line": 5}),
+		category.NewMorphism("i3", "internal/infra/db.go", "import:net/http", "import", map[string]interface{}{"line": 3}),
+		category.NewMorphism("c1", "domain.Place", "infra.Save", "function_call", map[string]interface{}{"line": 12}),
+		category.NewMorphism("d1", "internal/domain/order.go", "domain.Place", "defines", nil),
+	}
+	return modeltest.Build(t, "rules", objects, morphisms)
+}
+
+func check(t *testing.T, spec *Spec) []*Violation {
+	t.Helper()
+	engine, err := NewEngine(spec)
+	if err != nil {
+		t.Fatalf("NewEngine failed: %v", err)
+	}
+	return engine.Check(rulesFixture(t), nil)
+}
+
+func TestForbidLayers(t *testing.T) {
+	violations := check(t, &Spec{
+		Layers: []functor.Layer{
+			{Name: "domain", Paths: []string{"internal/domain/**"}},
+			{Name: "infra", Paths: []string{"internal/infra/**"}},
+		},
+		Rules: []Rule{{Name: "pure-domain", Forbid: &Dependency{From: []string{"domain"}, To: []string{"infra"}}}},
+	})
+
+	if len(violations) != 1 {
+		t.Fatalf("Expected 1 violation, got %d", len(violations))
+	}
+	v := violations[0]
+	if v.Rule != "pure-domain" || v.Severity != SeverityError {
+		t.Errorf("Expected error of pure-domain, got %s of %s", v.Severity, v.Rule)
+	}
+	if v.Source != "internal/domain/order.go" || v.Target != "internal/infra/db.go" {
+		t.Errorf("Expected order.go -> db.go, got %s -> %s", v.Source, v.Target)
+	}
+	if len(v.Morphisms) != 1 || v.Morphisms[0].MorphismID != "c1" {
+		t.Fatalf("Expected the call as evidence, got %v", v.Morphisms)
+	}
+	if loc := v.Morphisms[0].Location; loc == nil || loc.String() != "internal/domain/order.go:12" {
+		t.Errorf("Expected the call at internal/domain/order.go:12, got %v", loc)
+	}
+	if v.Location == nil || *v.Location != (analysis.Location{File: "internal/domain/order.go", Line: 12}) {
+		t.Errorf("Expected the violation located at its first morphism, got %v", v.Location)
+	}
+}
+
+func TestNewEngineKeepsSpec(t *testing.T) {
+	spec := &Spec{Rules: []Rule{{Name: "r", Forbid: &Dependency{From: []string{"internal/**"}, To: []string{"cmd/**"}}}}}
+	engine, err := NewEngine(spec)
+	if err != nil {
+		t.Fatalf("NewEngine failed: %v", err)
+	}
+	if spec.Rules[0].Severity != "" {
+		t.Errorf("Expected the spec to be unchanged, got severity %q", spec.Rules[0].Severity)
+	}
+	if got := engine.Rules()[0].Severity; got != SeverityError {
+		t.Errorf("Expected the engine to default to error, got %q", got)
+	}
+}
+
+func TestOnlyAndExcept(t *testing.T) {
+	// Only infrastructure may use net/http; the import of the main package
+	// is a violation
+	violations := check(t, &Spec{
+		Rules: []Rule{{Name: "http-in-infra", Only: &Dependency{From: []string{"internal/infra/**"}, To: []string{"net/http"}}}},
+	})
+	if len(violations) != 1 || violations[0].Source != "cmd/app/main.go" || violations[0].Target != "import:net/http" {
+		t.Fatalf("Expected main.go -> net/http, got %v", violations)
+	}
+
+	// Nothing but cmd may depend on infra, matched through its import path
+	violations = check(t, &Spec{
+		Rules: []Rule{{Name: "infra-private", Forbid: &Dependency{
+			From:   []string{"**"},
+			To:     []string{"internal/infra/**"},
+			Except: []string{"cmd/**"},
+		}}},
+	})
+	if len(violations) != 1 || violations[0].Source != "internal/domain/order.go" {
+		t.Fatalf("Expected only order.go to violate infra-private, got %v", violations)
+	}
+}
+
+func TestMaxEfferent(t *testing.T) {
+	violations := check(t, &Spec{
+		Rules: []Rule{
+			{Name: "tiny", Severity: SeverityWarning, MaxEfferent: &Coupling{Packages: []string{"internal/**"}, Limit: 0}},
+			{Name: "small", MaxEfferent: &Coupling{Packages: []string{"cmd/**"}, Limit: 0}},
+		},
+	})
+
+	// domain -> infra and main -> infra exceed 0; net/http is external and
+	// does not count, so infra is within the limit
+	var got []string
+	for _, v := range violations {
+		got = append(got, v.Rule+":"+v.Source)
+	}
+	want := "tiny:internal/domain small:cmd/app"
+	if strings.Join(got, " ") != want {
+		t.Errorf("Expected %s, got %v", want, got)
+	}
+	if Errors(violations) != 1 {
+		t.Errorf("Expected 1 error, got %d", Errors(violations))
+	}
+	for _, v := range violations {
+		if v.Source == "cmd/app" && (len(v.Morphisms) != 1 || v.Morphisms[0].MorphismID != "i2") {
+			t.Errorf("Expected only the import of infra as evidence for main, got %v", v.Morphisms)
+		}
+		if v.Efferent != 1 {
+			t.Errorf("Expected %s to record its efferent coupling 1, got %d", v.Source, v.Efferent)
+		}
+	}
+}
+
+func TestNewEngineErrors(t *testing.T) {
+	dep := &Dependency{From: []string{"a/**"}, To: []string{"b/**"}}
+	tests := map[string]*Spec{
+		"missing name":   {Rules: []Rule{{Forbid: dep}}},
+		"duplicate name": {Rules: []Rule{{Name: "r", Forbid: dep}, {Name: "r", Forbid: dep}}},
+		"no kind":        {Rules: []Rule{{Name: "r"}}},
+		"two kinds":      {Rules: []Rule{{Name: "r", Forbid: dep, Only: dep}}},
+		"severity":       {Rules: []Rule{{Name: "r", Severity: "fatal", Forbid: dep}}},
+		"missing to":     {Rules: []Rule{{Name: "r", Forbid: &Dependency{From: []string{"a"}}}}},
+		"bad pattern":    {Rules: []Rule{{Name: "r", Forbid: &Dependency{From: []string{"regex:("}, To: []string{"b"}}}}},
+	}
+	for name, spec := range tests {
+		if _, err := NewEngine(spec); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestLoadSpec(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "rules.yaml")
+	content := `name: Test
+rules:
+  - name: pure
+    forbid:
+      from: ["internal/domain/**"]
+      to: ["internal/infra/**"]
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	spec, err := LoadSpec(path)
+	if err != nil {
+		t.Fatalf("LoadSpec failed: %v", err)
+	}
+	if len(spec.Rules) != 1 || spec.Rules[0].Forbid == nil || spec.Rules[0].Forbid.To[0] != "internal/infra/**" {
+		t.Errorf("Unexpected spec: %+v", spec)
+	}
+
+	// Typos are rejected
+	if err := os.WriteFile(path, []byte(strings.Replace(content, "forbid", "forbidden", 1)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadSpec(path); err == nil {
+		t.Error("Expected an error for an unknown field")
+	}
+}
+
+func TestCheckExtractedModel(t *testing.T) {
+	// Extracted from an absolute root, files are identified relative to it
+	cat, err := extractor.NewGoExtractor().ExtractFromPath(modeltest.Shop(t))
+	if err != nil {
+		t.Fatalf("ExtractFromPath failed: %v", err)
+	}
+
+	engine, err := NewEngine(&Spec{Rules: []Rule{
+		{Name: "pure-domain", Forbid: &Dependency{From: []string{"internal/domain/**"}, To: []string{"internal/infra/**"}}},
+		{Name: "typo", Forbid: &Dependency{From: []string{"internal/domian/**"}, To: []string{"internal/infra/**"}}},
+	}})
+	if err != nil {
+		t.Fatalf("NewEngine failed: %v", err)
+	}
+	var got []string
+	for _, v := range engine.Check(cat, nil) {
+		got = append(got, v.Rule+":"+v.Source+"->"+v.Target)
+	}
+	// The import and the use of infra.Store
+	if want := "pure-domain:internal/domain/order.go->import:example.com/shop/internal/infra," +
+		"pure-domain:internal/domain/order.go->internal/infra/db.go"; strings.Join(got, ",") != want {
+		t.Errorf("Expected %s, got %v", want, got)
+	}
+
+	if unmatched := engine.Unmatched(cat); strings.Join(unmatched, ",") != "rule typo: internal/domian/**" {
+		t.Errorf("Expected the misspelled selector to match nothing, got %v", unmatched)
+	}
+}
@@ -0,0 +1,398 @@
+// Package sarif reports catreview findings in SARIF 2.1.0, the Static
+// Analysis Results Interchange Format read by code-scanning services and
+// IDEs: dependency cycles, architecture rule violations, dead code and
+// packages far from the main sequence.
+package sarif
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/manu/catreview/pkg/analysis"
+	"github.com/manu/catreview/pkg/category"
+	"github.com/manu/catreview/pkg/rules"
+)
+
+// Schema and Version identify the SARIF format written.
+const (
+	Schema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	Version = "2.1.0"
+)
+
+// Result levels.
+const (
+	LevelError   = "error"
+	LevelWarning = "warning"
+	LevelNote    = "note"
+)
+
+// Rule IDs of the built-in findings. Architecture rules are reported as
+// "architecture/<name>".
+const (
+	RuleCycle          = "dependency-cycle"
+	RuleUnusedFunction = "unused-function"
+	RuleUnusedType     = "unused-type"
+	RuleOrphanedFile   = "orphaned-file"
+	RulePackageZone    = "package-zone"
+)
+
+// FingerprintKey names catreview's fingerprints in partialFingerprints. They
+// match the fingerprints of verify baselines.
+const FingerprintKey = "catreview/v1"
+
+// srcRoot is the base of relative file paths, resolved by the consumer.
+const srcRoot = "%SRCROOT%"
+
+// Log is a SARIF log file.
+type Log struct {
+	Schema  string `json:"$schema"`
+	Version string `json:"version"`
+	Runs    []*Run `json:"runs"`
+}
+
+// Run is the output of one invocation of catreview.
+type Run struct {
+	Tool    Tool      `json:"tool"`
+	Results []*Result `json:"results"`
+}
+
+// Tool describes catreview and the rules it checks.
+type Tool struct {
+	Driver Driver `json:"driver"`
+}
+
+// Driver is the analysis tool.
+type Driver struct {
+	Name           string                 `json:"name"`
+	InformationURI string                 `json:"informationUri,omitempty"`
+	Rules          []*ReportingDescriptor `json:"rules"`
+}
+
+// ReportingDescriptor is the metadata of a rule.
+type ReportingDescriptor struct {
+	ID                   string                  `json:"id"`
+	Name                 string                  `json:"name,omitempty"`
+	ShortDescription     *Message                `json:"shortDescription,omitempty"`
+	FullDescription      *Message                `json:"fullDescription,omitempty"`
+	DefaultConfiguration *ReportingConfiguration `json:"defaultConfiguration,omitempty"`
+	Properties           map[string]interface{}  `json:"properties,omitempty"`
+}
+
+// ReportingConfiguration holds the default level of a rule.
+type ReportingConfiguration struct {
+	Level string `json:"level"`
+}
+
+// Message is a plain text message.
+type Message struct {
+	Text string `json:"text"`
+}
+
+// Result is a finding.
+type Result struct {
+	RuleID              string            `json:"ruleId"`
+	RuleIndex           int               `json:"ruleIndex"`
+	Level               string            `json:"level"`
+	Message             Message           `json:"message"`
+	Locations           []*Location       `json:"locations,omitempty"`
+	RelatedLocations    []*Location       `json:"relatedLocations,omitempty"`
+	PartialFingerprints map[string]string `json:"partialFingerprints,omitempty"`
+	BaselineState       string            `json:"baselineState,omitempty"` // "new" or "unchanged"
+}
+
+// Location is a position in an artifact, with an optional message.
+type Location struct {
+	ID               int               `json:"id,omitempty"`
+	PhysicalLocation *PhysicalLocation `json:"physicalLocation,omitempty"`
+	Message          *Message          `json:"message,omitempty"`
+}
+
+// PhysicalLocation is a file and an optional region of it.
+type PhysicalLocation struct {
+	ArtifactLocation ArtifactLocation `json:"artifactLocation"`
+	Region           *Region          `json:"region,omitempty"`
+}
+
+// ArtifactLocation is a file, relative to %SRCROOT% unless absolute.
+type ArtifactLocation struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+// Region is a line of a file.
+type Region struct {
+	StartLine int `json:"startLine"`
+}
+
+// Builder collects findings into a SARIF log.
+type Builder struct {
+	run   *Run
+	rules map[string]int
+
+	// Known reports whether a finding, by fingerprint, is in the baseline.
+	// If set, results record their baselineState.
+	Known func(fingerprint string) bool
+}
+
+// NewBuilder creates a builder for a log with a single run of catreview.
+func NewBuilder() *Builder {
+	return &Builder{
+		run: &Run{
+			Tool: Tool{Driver: Driver{
+				Name:           "catreview",
+				InformationURI: "https://github.com/manutej/catreview-go",
+				Rules:          []*ReportingDescriptor{},
+			}},
+			Results: []*Result{},
+		},
+		rules: make(map[string]int),
+	}
+}
+
+// Log returns the SARIF log of the findings added so far.
+func (b *Builder) Log() *Log {
+	return &Log{Schema: Schema, Version: Version, Runs: []*Run{b.run}}
+}
+
+// Save writes the log to a file.
+func (b *Builder) Save(path string) error {
+	data, err := category.CanonicalJSONIndent(b.Log())
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// rule registers a rule on first use and returns its index.
+func (b *Builder) rule(id, name, description, level string) int {
+	if i, ok := b.rules[id]; ok {
+		return i
+	}
+	b.rules[id] = len(b.run.Tool.Driver.Rules)
+	b.run.Tool.Driver.Rules = append(b.run.Tool.Driver.Rules, &ReportingDescriptor{
+		ID:                   id,
+		Name:                 name,
+		ShortDescription:     &Message{Text: description},
+		DefaultConfiguration: &ReportingConfiguration{Level: level},
+	})
+	return b.rules[id]
+}
+
+// add appends a result of a registered rule.
+func (b *Builder) add(ruleID, level, message, fingerprint string, loc *analysis.Location, related []*Location) {
+	result := &Result{
+		RuleID:              ruleID,
+		RuleIndex:           b.rules[ruleID],
+		Level:               level,
+		Message:             Message{Text: message},
+		RelatedLocations:    related,
+		PartialFingerprints: map[string]string{FingerprintKey: fingerprint},
+	}
+	if loc != nil {
+		result.Locations = []*Location{{PhysicalLocation: physicalLocation(*loc)}}
+	}
+	if b.Known != nil {
+		result.BaselineState = "new"
+		if b.Known(fingerprint) {
+			result.BaselineState = "unchanged"
+		}
+	}
+	b.run.Results = append(b.run.Results, result)
+}
+
+// AddCycles reports dependency cycles, each at the level returned by level.
+// Each morphism closing the cycle, one of those selected by edges, is a
+// related location, and the first one locates the result.
+func (b *Builder) AddCycles(cat *category.Category, cycles []*analysis.Cycle, edges *analysis.EdgeFilter, level func(*analysis.Cycle) string) {
+	b.rule(RuleCycle, "DependencyCycle", "Objects depend on each other in a cycle", LevelWarning)
+
+	// The first morphism, by ID, between each pair of objects
+	between := make(map[[2]string]*category.Morphism)
+	for _, morph := range cat.Morphisms() {
+		if !edges.Allows(morph) {
+			continue
+		}
+		pair := [2]string{morph.Source, morph.Target}
+		if m, ok := between[pair]; !ok || morph.ID < m.ID {
+			between[pair] = morph
+		}
+	}
+
+	for _, c := range cycles {
+		var related []*Location
+		var first *analysis.Location
+		for i, from := range c.Objects {
+			to := c.Objects[(i+1)%len(c.Objects)]
+			morph, ok := between[[2]string{from, to}]
+			if !ok {
+				continue
+			}
+			loc, ok := analysis.MorphismLocation(cat, morph)
+			if !ok {
+				continue
+			}
+			if first == nil {
+				first = &loc
+			}
+			related = append(related, &Location{
+				ID:               len(related) + 1,
+				PhysicalLocation: physicalLocation(loc),
+				Message:          &Message{Text: fmt.Sprintf("%s -> %s (%s)", from, to, morph.Type)},
+			})
+		}
+
+		path := append(append([]string{}, c.Objects...), c.Objects[0])
+		message := fmt.Sprintf("Dependency cycle of length %d: %s", c.Length, strings.Join(path, " -> "))
+		b.add(RuleCycle, level(c), message, c.Fingerprint(), first, related)
+	}
+}
+
+// AddViolations reports architecture rule violations, with the morphisms
+// causing them as related locations. Rules are described from specs.
+func (b *Builder) AddViolations(specs []rules.Rule, violations []*rules.Violation) {
+	for _, rule := range specs {
+		description := rule.Description
+		if description == "" {
+			description = describeRule(rule)
+		}
+		i := b.rule("architecture/"+rule.Name, rule.Name, description, rule.Severity)
+		b.run.Tool.Driver.Rules[i].Properties = map[string]interface{}{"tags": []string{"architecture"}}
+	}
+
+	for _, v := range violations {
+		var related []*Location
+		for _, ev := range v.Morphisms {
+			if ev.Location == nil {
+				continue
+			}
+			related = append(related, &Location{
+				ID:               len(related) + 1,
+				PhysicalLocation: physicalLocation(*ev.Location),
+				Message:          &Message{Text: fmt.Sprintf("%s -> %s (%s)", ev.Source, ev.Target, ev.Type)},
+			})
+		}
+		b.add("architecture/"+v.Rule, v.Severity, v.Message, v.Fingerprint(), v.Location, related)
+	}
+}
+
+// describeRule summarizes a rule without a description.
+func describeRule(rule rules.Rule) string {
+	switch {
+	case rule.Forbid != nil:
+		description := fmt.Sprintf("%s must not depend on %s",
+			strings.Join(rule.Forbid.From, ", "), strings.Join(rule.Forbid.To, ", "))
+		if len(rule.Forbid.Except) > 0 {
+			description += ", except " + strings.Join(rule.Forbid.Except, ", ")
+		}
+		return description
+	case rule.Only != nil:
+		return fmt.Sprintf("Only %s may depend on %s",
+			strings.Join(append(append([]string{}, rule.Only.From...), rule.Only.Except...), ", "),
+			strings.Join(rule.Only.To, ", "))
+	case rule.MaxEfferent != nil:
+		return fmt.Sprintf("Packages in %s depend on at most %d packages",
+			strings.Join(rule.MaxEfferent.Packages, ", "), rule.MaxEfferent.Limit)
+	}
+	return rule.Name
+}
+
+// AddDeadCode reports unused functions and types, and orphaned files.
+func (b *Builder) AddDeadCode(cat *category.Category, dead *analysis.DeadCode) {
+	for _, group := range []struct {
+		rule, name, description, kind string
+		objects                       []*analysis.DeadObject
+	}{
+		{RuleUnusedFunction, "UnusedFunction", "Function unreachable from main, exported API, init and tests", "function", dead.Functions},
+		{RuleUnusedType, "UnusedType", "Type unreachable from main, exported API, init and tests", "type", dead.Types},
+	} {
+		if len(group.objects) == 0 {
+			continue
+		}
+		b.rule(group.rule, group.name, group.description, LevelWarning)
+		for _, d := range group.objects {
+			var loc *analysis.Location
+			if obj, ok := cat.GetObject(d.ObjectID); ok {
+				if l, ok := analysis.ObjectLocation(obj); ok {
+					loc = &l
+				}
+			}
+			message := fmt.Sprintf("Unused %s %s", group.kind, d.ObjectID)
+			if d.Referenced {
+				message += " (used only by dead code)"
+			}
+			b.add(group.rule, LevelWarning, message, analysis.Fingerprint("dead", d.ObjectID), loc, nil)
+		}
+	}
+
+	if len(dead.Files) > 0 {
+		b.rule(RuleOrphanedFile, "OrphanedFile", "File whose functions and types are all unused", LevelWarning)
+	}
+	for _, file := range dead.Files {
+		b.add(RuleOrphanedFile, LevelWarning, fmt.Sprintf("Orphaned file %s: nothing it declares is used", file),
+			analysis.Fingerprint("dead", file), &analysis.Location{File: file}, nil)
+	}
+}
+
+// AddPackageZones reports the packages in the zone of pain or the zone of
+// uselessness, located at their first file.
+func (b *Builder) AddPackageZones(cat *category.Category, packages []*analysis.PackageMetrics) {
+	index := analysis.NewPackageIndex(cat)
+	files := make(map[string]string)
+	for _, obj := range cat.Objects() {
+		if obj.Type != "file" {
+			continue
+		}
+		if pkg, internal := index.Package(obj); internal && (files[pkg] == "" || obj.ID < files[pkg]) {
+			files[pkg] = obj.ID
+		}
+	}
+
+	var breaches []*analysis.PackageMetrics
+	for _, p := range packages {
+		if p.Zone == analysis.ZonePain || p.Zone == analysis.ZoneUselessness {
+			breaches = append(breaches, p)
+		}
+	}
+	if len(breaches) == 0 {
+		return
+	}
+	sort.SliceStable(breaches, func(i, j int) bool { return breaches[i].Package < breaches[j].Package })
+
+	b.rule(RulePackageZone, "MainSequenceDistance",
+		fmt.Sprintf("Package farther than %.1f from the main sequence", analysis.ZoneDistance), LevelNote)
+	for _, p := range breaches {
+		zone := "zone of pain (stable and concrete)"
+		if p.Zone == analysis.ZoneUselessness {
+			zone = "zone of uselessness (unstable and abstract)"
+		}
+		var loc *analysis.Location
+		if file := files[p.Package]; file != "" {
+			loc = &analysis.Location{File: file}
+		}
+		message := fmt.Sprintf("Package %s is in the %s: D=%.2f (A=%.2f, I=%.2f)",
+			p.Package, zone, p.Distance, p.Abstractness, p.Instability)
+		b.add(RulePackageZone, LevelNote, message, analysis.Fingerprint("zone", p.Package), loc, nil)
+	}
+}
+
+// physicalLocation converts a location to SARIF. Relative paths are
+// resolved against %SRCROOT%, absolute paths become file URIs.
+func physicalLocation(loc analysis.Location) *PhysicalLocation {
+	artifact := ArtifactLocation{URI: filepath.ToSlash(loc.File), URIBaseID: srcRoot}
+	if filepath.IsAbs(loc.File) {
+		artifact = ArtifactLocation{URI: "file://" + filepath.ToSlash(loc.File)}
+	}
+	p := &PhysicalLocation{ArtifactLocation: artifact}
+	if loc.Line > 0 {
+		p.Region = &Region{StartLine: loc.Line}
+	}
+	return p
+}
+
+// Len returns the number of results added so far.
+func (b *Builder) Len() int {
+	return len(b.run.Results)
+}
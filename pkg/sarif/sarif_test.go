@@ -0,0 +1,186 @@
+package sarif
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/manu/catreview/internal/modeltest"
+	"github.com/manu/catreview/pkg/analysis"
+	"github.com/manu/catreview/pkg/category"
+	"github.com/manu/catreview/pkg/extractor"
+	"github.com/manu/catreview/pkg/rules"
+)
+
+// cycleFixture models a.go and b.go importing each other's functions:
+//
+//	a.Run --function_call--> b.Help (a.go:7) --function_call--> a.Run (/abs/b.go:3)
+func cycleFixture(t *testing.T) *category.Category {
+	return modeltest.Build(t, "sarif", []*category.Object{
+		category.NewObject("a.Run", "function", "Run", map[string]interface{}{"package": "a", "file": "a.go", "line": 5}),
+		category.NewObject("b.Help", "function", "Help", map[string]interface{}{"package": "b", "file": "/abs/b.go", "line": 2}),
+	}, []*category.Morphism{
+		category.NewMorphism("calls:a.Run->b.Help", "a.Run", "b.Help", "function_call", map[string]interface{}{"line": 7}),
+		category.NewMorphism("calls:b.Help->a.Run", "b.Help", "a.Run", "function_call", map[string]interface{}{"line": 3}),
+	})
+}
+
+func TestCycleResults(t *testing.T) {
+	cat := cycleFixture(t)
+	cycle := &analysis.Cycle{Objects: []string{"a.Run", "b.Help"}, Length: 2}
+
+	b := NewBuilder()
+	b.Known = func(fingerprint string) bool { return fingerprint == cycle.Fingerprint() }
+	b.AddCycles(cat, []*analysis.Cycle{cycle}, nil, func(*analysis.Cycle) string { return LevelError })
+
+	log := b.Log()
+	if log.Version != Version || len(log.Runs) != 1 {
+		t.Fatalf("Expected a single SARIF %s run, got %v", Version, log)
+	}
+	run := log.Runs[0]
+	if len(run.Tool.Driver.Rules) != 1 || run.Tool.Driver.Rules[0].ID != RuleCycle {
+		t.Fatalf("Expected the cycle rule, got %v", run.Tool.Driver.Rules)
+	}
+	if len(run.Results) != 1 {
+		t.Fatalf("Expected 1 result, got %d", len(run.Results))
+	}
+	result := run.Results[0]
+	if result.Level != LevelError || result.BaselineState != "unchanged" {
+		t.Errorf("Expected an unchanged error, got %s %s", result.Level, result.BaselineState)
+	}
+	if result.PartialFingerprints[FingerprintKey] != cycle.Fingerprint() {
+		t.Errorf("Expected the baseline fingerprint, got %v", result.PartialFingerprints)
+	}
+
+	loc := result.Locations[0].PhysicalLocation
+	if loc.ArtifactLocation.URI != "a.go" || loc.ArtifactLocation.URIBaseID != srcRoot || loc.Region.StartLine != 7 {
+		t.Errorf("Expected a.go:7 under %s, got %+v %+v", srcRoot, loc.ArtifactLocation, loc.Region)
+	}
+	if len(result.RelatedLocations) != 2 {
+		t.Fatalf("Expected a related location per morphism, got %d", len(result.RelatedLocations))
+	}
+	back := result.RelatedLocations[1]
+	if back.ID != 2 || back.PhysicalLocation.ArtifactLocation.URI != "file:///abs/b.go" ||
+		back.PhysicalLocation.Region.StartLine != 3 || back.Message.Text != "b.Help -> a.Run (function_call)" {
+		t.Errorf("Unexpected related location %+v %+v", back, back.PhysicalLocation)
+	}
+}
+
+func TestViolationAndDeadCodeResults(t *testing.T) {
+	cat := cycleFixture(t)
+	specs := []rules.Rule{
+		{Name: "pure", Severity: rules.SeverityError, Forbid: &rules.Dependency{From: []string{"a"}, To: []string{"b"}}},
+		{Name: "unused", Severity: rules.SeverityWarning, Description: "Never violated", Forbid: &rules.Dependency{From: []string{"b"}, To: []string{"c"}}},
+	}
+	at := &analysis.Location{File: "a.go", Line: 7}
+	violations := []*rules.Violation{{
+		Rule: "pure", Kind: rules.KindDependency, Severity: rules.SeverityError,
+		Message: "a.go must not depend on /abs/b.go", Source: "a.go", Target: "/abs/b.go", Location: at,
+		Morphisms: []*rules.Evidence{{MorphismID: "calls:a.Run->b.Help", Type: "function_call", Source: "a.Run", Target: "b.Help", Location: at}},
+	}}
+	dead := &analysis.DeadCode{
+		Functions: []*analysis.DeadObject{{ObjectID: "b.Help", Type: "function", File: "/abs/b.go", Referenced: true}},
+		Files:     []string{"/abs/b.go"},
+	}
+
+	b := NewBuilder()
+	b.AddViolations(specs, violations)
+	b.AddDeadCode(cat, dead)
+	run := b.Log().Runs[0]
+
+	var ids []string
+	for _, rule := range run.Tool.Driver.Rules {
+		ids = append(ids, rule.ID)
+	}
+	want := []string{"architecture/pure", "architecture/unused", RuleUnusedFunction, RuleOrphanedFile}
+	if len(ids) != len(want) {
+		t.Fatalf("Expected rules %v, got %v", want, ids)
+	}
+	for i := range want {
+		if ids[i] != want[i] {
+			t.Fatalf("Expected rules %v, got %v", want, ids)
+		}
+	}
+	if run.Tool.Driver.Rules[0].ShortDescription.Text != "a must not depend on b" ||
+		run.Tool.Driver.Rules[1].DefaultConfiguration.Level != LevelWarning {
+		t.Errorf("Unexpected rule metadata %+v %+v", run.Tool.Driver.Rules[0], run.Tool.Driver.Rules[1])
+	}
+
+	if len(run.Results) != 3 {
+		t.Fatalf("Expected 3 results, got %d", len(run.Results))
+	}
+	violation := run.Results[0]
+	if violation.RuleIndex != 0 || violation.Level != LevelError || len(violation.RelatedLocations) != 1 || violation.BaselineState != "" {
+		t.Errorf("Unexpected violation result %+v", violation)
+	}
+	unused := run.Results[1]
+	if unused.RuleIndex != 2 || unused.Message.Text != "Unused function b.Help (used only by dead code)" ||
+		unused.Locations[0].PhysicalLocation.Region.StartLine != 2 {
+		t.Errorf("Unexpected dead code result %+v", unused)
+	}
+	if orphan := run.Results[2]; orphan.RuleIndex != 3 || orphan.Locations[0].PhysicalLocation.Region != nil {
+		t.Errorf("Expected a whole-file orphan result, got %+v", orphan)
+	}
+
+	path := filepath.Join(t.TempDir(), "catreview.sarif")
+	if err := b.Save(path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved map[string]interface{}
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatalf("Saved log is not JSON: %v", err)
+	}
+	if saved["$schema"] != Schema || saved["version"] != "2.1.0" {
+		t.Errorf("Expected the SARIF schema and version, got %v %v", saved["$schema"], saved["version"])
+	}
+}
+
+func TestPackageZoneResults(t *testing.T) {
+	cat := cycleFixture(t)
+	modeltest.Add(t, cat, []*category.Object{
+		category.NewObject("b/z.go", "file", "z.go", map[string]interface{}{"package": "b"}),
+		category.NewObject("b/y.go", "file", "y.go", map[string]interface{}{"package": "b"}),
+	}, nil)
+	packages := []*analysis.PackageMetrics{
+		{Package: "a", Zone: analysis.ZoneMainSequence},
+		{Package: "b", Zone: analysis.ZonePain, Distance: 0.9, Instability: 0.1},
+	}
+
+	b := NewBuilder()
+	b.AddPackageZones(cat, packages)
+	run := b.Log().Runs[0]
+	if len(run.Results) != 1 || run.Results[0].Level != LevelNote {
+		t.Fatalf("Expected a note for package b, got %v", run.Results)
+	}
+	if uri := run.Results[0].Locations[0].PhysicalLocation.ArtifactLocation.URI; uri != "b/y.go" {
+		t.Errorf("Expected the first file of b, got %s", uri)
+	}
+}
+
+func TestExtractedDeadCodeResults(t *testing.T) {
+	cat, err := extractor.NewGoExtractor().ExtractFromPath(modeltest.Shop(t))
+	if err != nil {
+		t.Fatalf("ExtractFromPath failed: %v", err)
+	}
+	dead, err := analysis.FindDeadCode(cat, analysis.DeadCodeOptions{})
+	if err != nil {
+		t.Fatalf("FindDeadCode failed: %v", err)
+	}
+
+	b := NewBuilder()
+	b.AddDeadCode(cat, dead)
+	results := b.Log().Runs[0].Results
+	if len(results) != 1 {
+		t.Fatalf("Expected 1 result, got %d", len(results))
+	}
+	// Files are relative to the extraction root, so the result resolves against the checkout
+	loc := results[0].Locations[0].PhysicalLocation
+	if loc.ArtifactLocation.URI != "internal/domain/order.go" || loc.ArtifactLocation.URIBaseID == "" || loc.Region.StartLine != 28 {
+		t.Errorf("Expected domain.legacy at internal/domain/order.go:28, got %+v %+v", loc.ArtifactLocation, loc.Region)
+	}
+}
@@ -2,7 +2,6 @@
 package viz
 
 import (
-	"encoding/json"
 	"sort"
 
 	"github.com/manu/catreview/pkg/analysis"
@@ -71,6 +70,9 @@ type FilterOptions struct {
 type GraphBuilder struct {
 	category *category.Category
 	coupling map[string]*analysis.CouplingMetrics
+
+	// Edges selects the morphisms drawn as edges (nil: all structural morphisms)
+	Edges *analysis.EdgeFilter
 }
 
 // NewGraphBuilder creates a new graph builder from a category.
@@ -124,8 +126,8 @@ func (b *GraphBuilder) Build() *Graph {
 
 	// Build edges from morphisms
 	for _, morph := range b.category.Morphisms() {
-		if morph.Source == morph.Target {
-			continue // Skip identity morphisms
+		if morph.Source == morph.Target || !b.Edges.Allows(morph) {
+			continue // Skip identity and co-change morphisms
 		}
 
 		edge := &Edge{
@@ -157,7 +159,10 @@ func (b *GraphBuilder) Build() *Graph {
 			}
 			ti := ni.Afferent + ni.Efferent
 			tj := nj.Afferent + nj.Efferent
-			return ti > tj
+			if ti != tj {
+				return ti > tj
+			}
+			return ni.ID < nj.ID
 		})
 	}
 
@@ -190,7 +195,7 @@ func (b *GraphBuilder) calculateCoupling() {
 	efferent := make(map[string]int)
 
 	for _, morph := range b.category.Morphisms() {
-		if morph.Source == morph.Target {
+		if morph.Source == morph.Target || !b.Edges.Allows(morph) {
 			continue
 		}
 		efferent[morph.Source]++
@@ -308,7 +313,13 @@ func (b *GraphBuilder) checkDAG(graph *Graph) bool {
 		return false
 	}
 
+	nodeIDs := make([]string, 0, len(graph.Nodes))
 	for nodeID := range graph.Nodes {
+		nodeIDs = append(nodeIDs, nodeID)
+	}
+	sort.Strings(nodeIDs)
+
+	for _, nodeID := range nodeIDs {
 		if !visited[nodeID] {
 			if hasCycle(nodeID) {
 				return false
@@ -392,9 +403,12 @@ func (g *Graph) Filter(opts *FilterOptions) *Graph {
 		candidates = append(candidates, nodeCoupling{node: node, coupling: totalCoupling})
 	}
 
-	// Sort by coupling (highest first)
+	// Sort by coupling (highest first), ties broken by ID
 	sort.Slice(candidates, func(i, j int) bool {
-		return candidates[i].coupling > candidates[j].coupling
+		if candidates[i].coupling != candidates[j].coupling {
+			return candidates[i].coupling > candidates[j].coupling
+		}
+		return candidates[i].node.ID < candidates[j].node.ID
 	})
 
 	// Apply max nodes limit
@@ -514,7 +528,33 @@ func (g *Graph) ToAdjacency() map[string]map[string]map[string]interface{} {
 	return adj
 }
 
-// ToJSON serializes the graph to JSON.
+// NewCrossLayerEdges returns the cross-layer edges of after that are not
+// cross-layer edges of before, sorted by source and target.
+func NewCrossLayerEdges(before, after *Graph) []*Edge {
+	known := make(map[string]bool)
+	for _, edge := range before.Edges {
+		if edge.Type == "cross_layer" {
+			known[edge.Source+"\x00"+edge.Target] = true
+		}
+	}
+
+	edges := []*Edge{}
+	for _, edge := range after.Edges {
+		if edge.Type == "cross_layer" && !known[edge.Source+"\x00"+edge.Target] {
+			edges = append(edges, edge)
+		}
+	}
+
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].Source != edges[j].Source {
+			return edges[i].Source < edges[j].Source
+		}
+		return edges[i].Target < edges[j].Target
+	})
+	return edges
+}
+
+// ToJSON serializes the graph to canonical, indented JSON.
 func (g *Graph) ToJSON() ([]byte, error) {
-	return json.MarshalIndent(g, "", "  ")
+	return category.CanonicalJSONIndent(g)
 }
@@ -0,0 +1,126 @@
+// mainsequence.go plots packages on Robert Martin's abstractness-instability plane.
+package viz
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/manu/catreview/pkg/analysis"
+)
+
+// plotMarkers label the packages of an ASCII plot, in legend order.
+const plotMarkers = "123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+// GenerateMainSequenceASCII plots packages on the A-I plane as ASCII art.
+// The dotted diagonal is the main sequence A + I = 1; the zone of pain lies
+// towards the bottom left, the zone of uselessness towards the top right.
+// Packages without cross-package dependencies are listed but not plotted.
+func GenerateMainSequenceASCII(packages []*analysis.PackageMetrics) string {
+	const width, height = 41, 21
+
+	grid := make([][]rune, height)
+	for row := range grid {
+		grid[row] = []rune(strings.Repeat(" ", width))
+		// Row 0 is A = 1; the main sequence runs from (0,1) to (1,0)
+		grid[row][(width-1)*row/(height-1)] = '.'
+	}
+
+	plotted := packages
+	if len(plotted) > len(plotMarkers) {
+		plotted = plotted[:len(plotMarkers)]
+	}
+	for i, p := range plotted {
+		if p.Zone == analysis.ZoneIsolated {
+			continue
+		}
+		col := int(p.Instability*float64(width-1) + 0.5)
+		row := height - 1 - int(p.Abstractness*float64(height-1)+0.5)
+		if grid[row][col] != ' ' && grid[row][col] != '.' {
+			grid[row][col] = '*' // Several packages
+		} else {
+			grid[row][col] = rune(plotMarkers[i])
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString("MAIN SEQUENCE (A = abstractness, I = instability, D = |A + I - 1|)\n")
+	sb.WriteString("═══════════════════════════════════════════════════════════\n")
+	for row, line := range grid {
+		label := "     "
+		switch row {
+		case 0:
+			label = "A=1 "
+		case height - 1:
+			label = "A=0 "
+		}
+		sb.WriteString(fmt.Sprintf("%-5s│%s│\n", label, string(line)))
+	}
+	sb.WriteString("     └" + strings.Repeat("─", width) + "┘\n")
+	sb.WriteString(fmt.Sprintf("      I=0%sI=1\n", strings.Repeat(" ", width-6)))
+	sb.WriteString("  bottom left: zone of pain, top right: zone of uselessness, * = several\n")
+	sb.WriteString("───────────────────────────────────────────────────────────\n")
+
+	for i, p := range packages {
+		marker := "-"
+		if i < len(plotMarkers) && p.Zone != analysis.ZoneIsolated {
+			marker = string(plotMarkers[i])
+		}
+		sb.WriteString(fmt.Sprintf("  %s  %-28s A=%.2f I=%.2f D=%.2f  %s\n",
+			marker, truncate(p.Package, 28), p.Abstractness, p.Instability, p.Distance, p.Zone))
+	}
+
+	return sb.String()
+}
+
+// GenerateMainSequenceSVG plots packages on the A-I plane as an SVG image,
+// shading the zones of pain and uselessness.
+func GenerateMainSequenceSVG(packages []*analysis.PackageMetrics) string {
+	const size, margin = 400.0, 50.0
+	x := func(i float64) float64 { return margin + i*size }
+	y := func(a float64) float64 { return margin + (1-a)*size }
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%g" height="%g" font-family="sans-serif" font-size="11">`+"\n",
+		size+2*margin, size+2*margin))
+	sb.WriteString(`<rect width="100%" height="100%" fill="white"/>` + "\n")
+
+	// Zones: beyond analysis.ZoneDistance from the main sequence
+	d := analysis.ZoneDistance
+	sb.WriteString(fmt.Sprintf(`<polygon points="%g,%g %g,%g %g,%g" fill="#f8d7da"/>`+"\n",
+		x(0), y(0), x(1-d), y(0), x(0), y(1-d)))
+	sb.WriteString(fmt.Sprintf(`<polygon points="%g,%g %g,%g %g,%g" fill="#fff3cd"/>`+"\n",
+		x(1), y(1), x(d), y(1), x(1), y(d)))
+	sb.WriteString(fmt.Sprintf(`<text x="%g" y="%g" fill="#842029">zone of pain</text>`+"\n", x(0.02), y(0.03)))
+	sb.WriteString(fmt.Sprintf(`<text x="%g" y="%g" fill="#664d03" text-anchor="end">zone of uselessness</text>`+"\n", x(0.98), y(0.95)))
+
+	// Axes and the main sequence
+	sb.WriteString(fmt.Sprintf(`<rect x="%g" y="%g" width="%g" height="%g" fill="none" stroke="black"/>`+"\n",
+		margin, margin, size, size))
+	sb.WriteString(fmt.Sprintf(`<line x1="%g" y1="%g" x2="%g" y2="%g" stroke="gray" stroke-dasharray="4"/>`+"\n",
+		x(0), y(1), x(1), y(0)))
+	sb.WriteString(fmt.Sprintf(`<text x="%g" y="%g" text-anchor="middle">Instability (I)</text>`+"\n", x(0.5), y(0)+35))
+	sb.WriteString(fmt.Sprintf(`<text x="%g" y="%g" text-anchor="middle" transform="rotate(-90 %g %g)">Abstractness (A)</text>`+"\n",
+		x(0)-30, y(0.5), x(0)-30, y(0.5)))
+	for _, tick := range []float64{0, 0.5, 1} {
+		sb.WriteString(fmt.Sprintf(`<text x="%g" y="%g" text-anchor="middle">%g</text>`+"\n", x(tick), y(0)+15, tick))
+		sb.WriteString(fmt.Sprintf(`<text x="%g" y="%g" text-anchor="end">%g</text>`+"\n", x(0)-5, y(tick)+4, tick))
+	}
+
+	colors := map[string]string{
+		analysis.ZoneMainSequence: "#198754",
+		analysis.ZonePain:         "#dc3545",
+		analysis.ZoneUselessness:  "#fd7e14",
+	}
+	for _, p := range packages {
+		if p.Zone == analysis.ZoneIsolated {
+			continue
+		}
+		cx, cy := x(p.Instability), y(p.Abstractness)
+		sb.WriteString(fmt.Sprintf(`<circle cx="%.1f" cy="%.1f" r="5" fill="%s"><title>%s: A=%.2f I=%.2f D=%.2f (%s)</title></circle>`+"\n",
+			cx, cy, colors[p.Zone], escapeHTML(p.Package), p.Abstractness, p.Instability, p.Distance, p.Zone))
+		sb.WriteString(fmt.Sprintf(`<text x="%.1f" y="%.1f">%s</text>`+"\n", cx+7, cy+4, escapeHTML(p.Package)))
+	}
+
+	sb.WriteString("</svg>\n")
+	return sb.String()
+}
@@ -413,7 +413,12 @@ func GenerateLayeredASCII(graph *Graph) string {
 			}
 		}
 		sort.Slice(nodes, func(a, b int) bool {
-			return (nodes[a].Afferent + nodes[a].Efferent) > (nodes[b].Afferent + nodes[b].Efferent)
+			ta := nodes[a].Afferent + nodes[a].Efferent
+			tb := nodes[b].Afferent + nodes[b].Efferent
+			if ta != tb {
+				return ta > tb
+			}
+			return nodes[a].ID < nodes[b].ID
 		})
 
 		// Display top nodes
@@ -462,7 +467,10 @@ func GenerateHeatmap(graph *Graph) string {
 	sort.Slice(nodes, func(i, j int) bool {
 		ti := nodes[i].Afferent + nodes[i].Efferent
 		tj := nodes[j].Afferent + nodes[j].Efferent
-		return ti > tj
+		if ti != tj {
+			return ti > tj
+		}
+		return nodes[i].ID < nodes[j].ID
 	})
 
 	// Find max coupling for bar scaling